@@ -0,0 +1,119 @@
+package backend
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// defaultSystemPrompt 默认系统提示词，目录（含上级目录）设置了提示词模板时使用模板
+const defaultSystemPrompt = "你是一个有用的 AI 助手。"
+
+// toolSystemPrompt 工具模式下追加的系统提示词
+const toolSystemPrompt = "\n\n你可以调用工具搜索和读取用户的笔记、查看目录，需要时可以创建笔记草稿（创建前会征得用户同意）。回答时请注明参考的笔记标题和 ID。"
+
+// ChatOptions 对话选项
+type ChatOptions struct {
+	Provider  string `json:"provider"`  // AI 服务商名称，为空时使用默认服务商
+	Retrieval bool   `json:"retrieval"` // 自动检索与提问最相似的笔记片段作为上下文
+	Tools     bool   `json:"tools"`     // 允许 AI 调用笔记库工具
+	// CategoryID 对话所在的目录，决定使用的系统提示词模板；为 0 时按关联内容所在的目录
+	CategoryID uint `json:"categoryId"`
+	// Images 用户选择随提问发送的图片，为图片存储目录中的相对路径或 local://images/ 引用
+	// 提问和关联内容中引用的图片在模型支持图片输入时自动附带
+	Images []string `json:"images"`
+}
+
+// chatMessage 与服务商无关的对话消息，由适配器转换为各自的请求格式
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+
+	ToolCalls  []toolCall  `json:"-"` // 助手消息发起的工具调用，由支持工具的适配器转换
+	ToolCallID string      `json:"-"` // role 为 tool 时对应的调用 ID
+	Images     []chatImage `json:"-"` // 随用户消息发送的图片，由适配器转换为各自的图片格式
+}
+
+// buildSystemPrompt 在基础系统提示词后附带用户关联的上下文内容
+func buildSystemPrompt(base string, contextTexts []string) string {
+	systemPrompt := base
+	if len(contextTexts) > 0 {
+		systemPrompt += "\n\n以下是用户提供的上下文内容：\n\n"
+		systemPrompt += strings.Join(contextTexts, "\n\n---\n\n")
+		systemPrompt += "\n\n请基于以上上下文内容回答用户的问题。"
+	}
+	return systemPrompt
+}
+
+// buildChatMessages 构建单轮对话的消息列表
+func buildChatMessages(systemPrompt string, prompt string, contextTexts []string) []chatMessage {
+	return []chatMessage{
+		{Role: "system", Content: buildSystemPrompt(systemPrompt, contextTexts)},
+		{Role: "user", Content: prompt},
+	}
+}
+
+// fitChatMessages 构建单轮对话的消息列表，关联内容超出模型上下文窗口时按配置的策略裁剪
+// retrieval 为 true 时按提问检索最相似的笔记片段，使用关联内容剩余的预算，并返回实际引用的片段
+// images 为用户选择的图片，与提问和上下文中引用的图片一起在剩余预算内附带
+func fitChatMessages(ctx context.Context, p AIProvider, systemPrompt string, prompt string, contextTexts []string, retrieval bool, images []string) ([]chatMessage, *ContextReport, []Citation, error) {
+	budget := contextBudget(p, systemPrompt, []chatMessage{{Role: "user", Content: prompt}})
+	texts, report := fitContext(textContextItems(contextTexts), prompt, currentContextStrategy(), budget)
+	var citations []Citation
+	if retrieval {
+		hits, err := retrieveChunks(ctx, prompt, 0, nil)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		retrievedTexts, retrievedReport, cited := fitRetrieved(hits, budget-report.Used)
+		texts = append(texts, retrievedTexts...)
+		mergeContextReport(report, retrievedReport)
+		citations = cited
+	}
+	messages := buildChatMessages(systemPrompt, prompt, texts)
+	attached, omitted, err := attachImages(p, messages, images, append([]string{prompt}, texts...), budget-report.Used)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	report.Images, report.OmittedImages = attached, omitted
+	return messages, report, citations, nil
+}
+
+// completeChat 以非流式方式请求服务商，返回完整回复
+func completeChat(ctx context.Context, p AIProvider, messages []chatMessage) (string, error) {
+	adapter, err := adapterFor(p.Kind)
+	if err != nil {
+		return "", err
+	}
+	req, err := adapter.newRequest(ctx, p, messages, false)
+	if err != nil {
+		return "", err
+	}
+	start := time.Now()
+	body, err := sendAIRequest(req)
+	if err != nil {
+		recordAIUsage(p, p.Model, UsageSourceChat, tokenUsage{}, start, err)
+		return "", err
+	}
+	reply, err := adapter.parseResponse(body)
+	recordAIUsage(p, p.Model, UsageSourceChat, estimateUsage(adapter.parseUsage(body), messages, reply), start, err)
+	return reply, err
+}
+
+// sendAIRequest 通过 doAIRequest 发送请求并读取完整响应，失败时返回 *AIError
+func sendAIRequest(req *http.Request) ([]byte, error) {
+	resp, err := doAIRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, &AIError{Kind: AIErrNetwork, Err: fmt.Errorf("读取响应失败: %v", err)}
+	}
+	return body, nil
+}
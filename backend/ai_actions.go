@@ -0,0 +1,295 @@
+package backend
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+// AI 笔记操作
+const (
+	ActionSummarize   = "summarize"    // 生成摘要，插入到笔记开头或选中内容之后
+	ActionRetitle     = "retitle"      // 建议标题
+	ActionTranslate   = "translate"    // 翻译，替换笔记或选中内容
+	ActionFixGrammar  = "fix_grammar"  // 修正语法和错别字，替换笔记或选中内容
+	ActionExplainCode = "explain_code" // 解释代码，插入到笔记末尾或选中内容之后
+
+	// ActionSnippetAnalysis 代码片段分析，由 GenerateSnippetAnalysis 写入笔记的 Analysis 字段
+	ActionSnippetAnalysis = "snippet_analysis"
+)
+
+// aiActionTimeout 单次笔记操作的超时时间
+const aiActionTimeout = 2 * time.Minute
+
+// defaultAIActionPrompts 各操作的默认提示词模板
+// 可用变量：{{selection}} 操作的内容，{{note_title}} 笔记标题，{{target_language}} 翻译的目标语言，{{language}} 代码片段的语言
+var defaultAIActionPrompts = map[string]string{
+	ActionSummarize:   "请用简洁的中文为以下内容写一段摘要，不超过 200 字，只输出摘要正文：\n\n{{selection}}",
+	ActionRetitle:     "请为以下笔记拟定一个简洁准确的标题，不超过 30 字，只输出标题本身，不要加引号或标点。\n\n当前标题：{{note_title}}\n\n{{selection}}",
+	ActionTranslate:   "请将以下 Markdown 内容翻译为{{target_language}}，保持 Markdown 结构、代码块和链接不变，只输出译文：\n\n{{selection}}",
+	ActionFixGrammar:  "请修正以下 Markdown 内容中的语法错误、错别字和不通顺的句子，保持原意、语言和 Markdown 结构不变，代码块不要修改，只输出修改后的全文：\n\n{{selection}}",
+	ActionExplainCode: "请用中文逐段解释以下代码的作用、关键逻辑和需要注意的地方，使用 Markdown 输出：\n\n{{selection}}",
+
+	ActionSnippetAnalysis: "以下是一段 {{language}} 代码片段，标题为「{{note_title}}」。请用中文分析：它解决什么问题、关键步骤和用到的 API、输入输出，以及使用时需要注意的地方。使用 Markdown 输出，不要重复贴出完整代码：\n\n```{{language}}\n{{selection}}\n```",
+}
+
+// aiActionNames 操作的显示名称，也用于插入内容的标题
+var aiActionNames = map[string]string{
+	ActionSummarize:   "摘要",
+	ActionRetitle:     "建议标题",
+	ActionTranslate:   "翻译",
+	ActionFixGrammar:  "语法修正",
+	ActionExplainCode: "代码说明",
+
+	ActionSnippetAnalysis: "代码片段分析",
+}
+
+// AIActionPrompt 操作及其提示词模板
+type AIActionPrompt struct {
+	Action    string `json:"action"`
+	Name      string `json:"name"`
+	Template  string `json:"template"`
+	IsDefault bool   `json:"isDefault"` // 是否为内置模板
+}
+
+// AIActionResult 操作生成的建议，确认后通过 ApplyAIAction 写入笔记
+type AIActionResult struct {
+	Action    string `json:"action"`
+	NoteID    uint   `json:"noteId"`
+	Title     string `json:"title"`     // 建议的标题，仅 retitle 会修改
+	ContentMD string `json:"contentMd"` // 建议的完整笔记内容
+	Output    string `json:"output"`    // 模型的原始输出
+	Diff      string `json:"diff"`      // 与当前内容的 unified diff
+	BaseHash  string `json:"baseHash"`  // 生成建议时笔记内容的哈希，应用时用于检测笔记是否已被修改
+}
+
+// noteContentHash 笔记标题和内容的哈希
+func noteContentHash(title, contentMD string) string {
+	sum := sha256.Sum256([]byte(title + "\x00" + contentMD))
+	return hex.EncodeToString(sum[:])
+}
+
+// aiActionPrompt 读取操作的提示词模板，未自定义时使用默认模板
+func aiActionPrompt(action string) (string, error) {
+	def, ok := defaultAIActionPrompts[action]
+	if !ok {
+		return "", fmt.Errorf("不支持的 AI 操作: %s", action)
+	}
+	cfgMu.RLock()
+	defer cfgMu.RUnlock()
+	if t := Cfg.AIActionPrompts[action]; t != "" {
+		return t, nil
+	}
+	return def, nil
+}
+
+// renderActionPrompt 替换模板中的变量
+func renderActionPrompt(template string, vars map[string]string) string {
+	pairs := make([]string, 0, len(vars)*2)
+	for k, v := range vars {
+		pairs = append(pairs, "{{"+k+"}}", v)
+	}
+	return strings.NewReplacer(pairs...).Replace(template)
+}
+
+// cleanActionOutput 去掉模型常见的多余包装，如整体包在 ```markdown 代码块中
+func cleanActionOutput(output string) string {
+	output = strings.TrimSpace(output)
+	if strings.HasPrefix(output, "```") && strings.HasSuffix(output, "```") && strings.Count(output, "```") == 2 {
+		if _, body, ok := strings.Cut(output, "\n"); ok {
+			output = strings.TrimSpace(strings.TrimSuffix(body, "```"))
+		}
+	}
+	return output
+}
+
+// unifiedDiff 生成当前内容与建议内容的 unified diff
+func unifiedDiff(before, after string) string {
+	diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(before),
+		B:        difflib.SplitLines(after),
+		FromFile: "当前",
+		ToFile:   "建议",
+		Context:  3,
+	})
+	if err != nil {
+		return ""
+	}
+	return diff
+}
+
+// RunAIAction 对笔记或选中的内容执行 AI 操作，返回建议的内容和差异，不修改笔记
+// selection: 选中的内容，为空时对整篇笔记操作；非空时必须是笔记内容的一部分
+// targetLanguage: 翻译的目标语言，为空时为英文
+// provider: AI 服务商名称，为空时使用默认服务商
+func (a *App) RunAIAction(noteID uint, action string, selection string, targetLanguage string, provider string) (*AIActionResult, error) {
+	template, err := aiActionPrompt(action)
+	if err != nil {
+		return nil, err
+	}
+	if action == ActionSnippetAnalysis {
+		return nil, errors.New("代码片段分析请使用 GenerateSnippetAnalysis")
+	}
+	var note Note
+	if err := DB.First(&note, noteID).Error; err != nil {
+		return nil, fmt.Errorf("笔记不存在: %v", err)
+	}
+	if note.Type == 1 || note.Type == 3 {
+		return nil, errors.New("PDF 和 EPUB 笔记不支持 AI 操作")
+	}
+	target := note.ContentMD
+	if strings.TrimSpace(selection) != "" {
+		if !strings.Contains(note.ContentMD, selection) {
+			return nil, errors.New("选中的内容已不在笔记中，请重新选择")
+		}
+		target = selection
+	}
+	if strings.TrimSpace(target) == "" {
+		return nil, errors.New("笔记内容为空")
+	}
+	if targetLanguage == "" {
+		targetLanguage = "英文"
+	}
+
+	p, err := resolveAIProvider(provider)
+	if err != nil {
+		return nil, err
+	}
+	prompt := renderActionPrompt(template, map[string]string{
+		"selection":       target,
+		"note_title":      note.Title,
+		"target_language": targetLanguage,
+	})
+	systemPrompt, _ := systemPromptFor(note.CategoryID, note.Title)
+	messages := []chatMessage{
+		{Role: "system", Content: systemPrompt},
+		{Role: "user", Content: prompt},
+	}
+	if estimateMessagesTokens(messages) > contextBudget(p, "", nil) {
+		return nil, errors.New("内容超出模型上下文窗口，请选择部分内容后再试")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), aiActionTimeout)
+	defer cancel()
+	startTime := time.Now()
+	output, err := completeChat(ctx, p, messages)
+	if err != nil {
+		log.Printf("[AI Action] 请求失败 (Note ID: %d, 操作: %s, 耗时: %v): %v", noteID, action, time.Since(startTime), err)
+		return nil, err
+	}
+	output = cleanActionOutput(output)
+	if output == "" {
+		return nil, errors.New("AI 未返回任何内容")
+	}
+	log.Printf("[AI Action] 完成 (Note ID: %d, 操作: %s, 耗时: %v, 输出长度: %d 字符)", noteID, action, time.Since(startTime), len(output))
+
+	result := &AIActionResult{
+		Action:    action,
+		NoteID:    noteID,
+		Title:     note.Title,
+		ContentMD: note.ContentMD,
+		Output:    output,
+		BaseHash:  noteContentHash(note.Title, note.ContentMD),
+	}
+	whole := target == note.ContentMD
+	switch action {
+	case ActionRetitle:
+		title, _, _ := strings.Cut(output, "\n")
+		result.Title = strings.Trim(strings.TrimSpace(strings.TrimLeft(title, "# ")), "\"“”《》")
+	case ActionTranslate, ActionFixGrammar:
+		if whole {
+			result.ContentMD = output
+		} else {
+			result.ContentMD = strings.Replace(note.ContentMD, selection, output, 1)
+		}
+	case ActionSummarize:
+		block := "> **摘要**：" + strings.ReplaceAll(output, "\n", "\n> ")
+		if whole {
+			result.ContentMD = block + "\n\n" + note.ContentMD
+		} else {
+			result.ContentMD = strings.Replace(note.ContentMD, selection, selection+"\n\n"+block+"\n", 1)
+		}
+	case ActionExplainCode:
+		section := "### " + aiActionNames[action] + "\n\n" + output
+		if whole {
+			result.ContentMD = strings.TrimRight(note.ContentMD, "\n") + "\n\n" + section + "\n"
+		} else {
+			result.ContentMD = strings.Replace(note.ContentMD, selection, selection+"\n\n"+section+"\n", 1)
+		}
+	}
+
+	before := "# " + note.Title + "\n\n" + note.ContentMD
+	after := "# " + result.Title + "\n\n" + result.ContentMD
+	result.Diff = unifiedDiff(before, after)
+	return result, nil
+}
+
+// ApplyAIAction 将确认后的建议通过 UpdateNoteMD 写入笔记
+// baseHash 为生成建议时的内容哈希，笔记在此期间被修改过时拒绝覆盖
+func (a *App) ApplyAIAction(noteID uint, title string, contentMD string, baseHash string) error {
+	var note Note
+	if err := DB.First(&note, noteID).Error; err != nil {
+		return fmt.Errorf("笔记不存在: %v", err)
+	}
+	if baseHash != "" && baseHash != noteContentHash(note.Title, note.ContentMD) {
+		return errors.New("笔记在生成建议后已被修改，请重新生成")
+	}
+	if err := a.UpdateNoteMD(noteID, title, note.Language, contentMD, note.CategoryID); err != nil {
+		return fmt.Errorf("保存笔记失败: %v", err)
+	}
+	log.Printf("[AI Action] 已应用建议 (Note ID: %d)", noteID)
+	return nil
+}
+
+// ListAIActionPrompts 获取所有 AI 操作及其提示词模板
+func (a *App) ListAIActionPrompts() []AIActionPrompt {
+	cfgMu.RLock()
+	defer cfgMu.RUnlock()
+	var list []AIActionPrompt
+	for _, action := range []string{ActionSummarize, ActionRetitle, ActionTranslate, ActionFixGrammar, ActionExplainCode, ActionSnippetAnalysis} {
+		item := AIActionPrompt{Action: action, Name: aiActionNames[action], Template: defaultAIActionPrompts[action], IsDefault: true}
+		if t := Cfg.AIActionPrompts[action]; t != "" {
+			item.Template = t
+			item.IsDefault = false
+		}
+		list = append(list, item)
+	}
+	return list
+}
+
+// UpdateAIActionPrompt 修改 AI 操作的提示词模板，template 为空时恢复默认模板
+func (a *App) UpdateAIActionPrompt(action string, template string) error {
+	if _, ok := defaultAIActionPrompts[action]; !ok {
+		return fmt.Errorf("不支持的 AI 操作: %s", action)
+	}
+	template = strings.TrimSpace(template)
+	if template != "" && !strings.Contains(template, "{{selection}}") {
+		return errors.New("提示词模板中需要包含 {{selection}}")
+	}
+
+	cfgMu.Lock()
+	if Cfg.AIActionPrompts == nil {
+		Cfg.AIActionPrompts = map[string]string{}
+	}
+	if template == "" {
+		delete(Cfg.AIActionPrompts, action)
+	} else {
+		Cfg.AIActionPrompts[action] = template
+	}
+	saveConfig := snapshotConfigFile()
+	filePath := configFilePath
+	cfgMu.Unlock()
+
+	if err := writeConfigFile(filePath, saveConfig); err != nil {
+		return fmt.Errorf("保存配置文件失败: %v", err)
+	}
+	log.Printf("[Config] AI 操作提示词已更新: %s", action)
+	return nil
+}
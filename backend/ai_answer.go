@@ -0,0 +1,171 @@
+package backend
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+)
+
+// 由 AI 回答保存的笔记中各部分的标题，反向链接据此提取回答内容
+const (
+	promptHeading  = "## 提问"
+	answerHeading  = "## 回答"
+	sourcesHeading = "## 来源"
+)
+
+// contextNoteIDs 上下文报告中实际放入的笔记，按出现顺序去重
+func contextNoteIDs(report *ContextReport) []uint {
+	var ids []uint
+	seen := map[uint]bool{}
+	for _, entry := range report.Included {
+		if entry.NoteID != 0 && !seen[entry.NoteID] {
+			seen[entry.NoteID] = true
+			ids = append(ids, entry.NoteID)
+		}
+	}
+	return ids
+}
+
+// answerSourceIDs 回答使用的笔记：实际放入上下文的笔记和检索引用的笔记
+// 早期保存的消息没有记录上下文笔记，改为按之前各轮提问关联的目录和笔记推断
+func (a *App) answerSourceIDs(reply ChatMessage, history []ChatMessage) []uint {
+	var ids []uint
+	seen := map[uint]bool{}
+	add := func(id uint) {
+		if id != 0 && !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	for _, id := range reply.ContextNotes {
+		add(id)
+	}
+	if reply.ContextNotes == nil {
+		for i := len(history) - 1; i >= 0; i-- {
+			for _, ref := range history[i].ContextRefs {
+				switch ref.Type {
+				case "note":
+					add(ref.ID)
+				case "category":
+					categoryID := ref.ID
+					notes, err := a.ListNotes(&categoryID)
+					if err != nil {
+						continue
+					}
+					for _, note := range notes {
+						add(note.ID)
+					}
+				}
+			}
+		}
+	}
+	for _, c := range reply.Citations {
+		add(c.NoteID)
+	}
+	return ids
+}
+
+// SaveChatAnswerAsNote 将对话中的 AI 回答保存为 Markdown 笔记
+// messageID 为 0 时保存 conversationID 对应对话的最后一条回答；非 0 时 conversationID 可为 0
+// 笔记包含提问、回答、模型名称，以及链接到作为上下文的笔记的来源列表，这些笔记的反向链接中会显示该回答
+func (a *App) SaveChatAnswerAsNote(conversationID uint, messageID uint, categoryID uint) (*Note, error) {
+	var cat Category
+	if err := DB.Select("id").First(&cat, categoryID).Error; err != nil {
+		return nil, fmt.Errorf("目录不存在: %v", err)
+	}
+
+	var reply ChatMessage
+	q := DB.Where("role = ?", "assistant")
+	if messageID != 0 {
+		q = q.Where("id = ?", messageID)
+	}
+	if conversationID != 0 {
+		q = q.Where("conversation_id = ?", conversationID)
+	} else if messageID == 0 {
+		return nil, errors.New("未指定对话或消息")
+	}
+	if err := q.Order("id desc").First(&reply).Error; err != nil {
+		return nil, fmt.Errorf("回答不存在: %v", err)
+	}
+	if strings.TrimSpace(reply.Content) == "" {
+		return nil, errors.New("回答内容为空")
+	}
+
+	var conv Conversation
+	if err := DB.First(&conv, reply.ConversationID).Error; err != nil {
+		return nil, fmt.Errorf("对话不存在: %v", err)
+	}
+	var history []ChatMessage
+	if err := DB.Where("conversation_id = ? AND id < ? AND role = ?", conv.ID, reply.ID, "user").
+		Order("id asc").Find(&history).Error; err != nil {
+		return nil, err
+	}
+	prompt := ""
+	if len(history) > 0 {
+		prompt = history[len(history)-1].Content
+	}
+	model := reply.Model
+	if model == "" {
+		// 早期保存的消息没有记录模型，按对话当前的服务商推断
+		model = providerModel(conv.Provider)
+	}
+
+	ids := a.answerSourceIDs(reply, history)
+	var sources []Note
+	if len(ids) > 0 {
+		if err := DB.Select("id", "title").Where("id IN ?", ids).Find(&sources).Error; err != nil {
+			return nil, err
+		}
+	}
+	titles := map[uint]string{}
+	for _, note := range sources {
+		titles[note.ID] = note.Title
+	}
+	headings := map[uint][]string{}
+	for _, c := range reply.Citations {
+		if c.Heading != "" {
+			headings[c.NoteID] = append(headings[c.NoteID], c.Heading)
+		}
+	}
+
+	var sb strings.Builder
+	meta := []string{"由 AI 回答保存"}
+	if model != "" {
+		meta = append(meta, "模型："+model)
+	}
+	meta = append(meta, "时间："+reply.CreatedAt.Format("2006-01-02 15:04"), "对话："+conv.Title)
+	sb.WriteString("> " + strings.Join(meta, " · ") + "\n\n")
+	sb.WriteString(promptHeading + "\n\n" + strings.TrimSpace(prompt) + "\n\n")
+	sb.WriteString(answerHeading + "\n\n" + strings.TrimSpace(reply.Content) + "\n\n")
+	sb.WriteString(sourcesHeading + "\n\n")
+	linked := 0
+	for _, id := range ids {
+		title, ok := titles[id]
+		if !ok {
+			// 已删除的笔记不再链接
+			continue
+		}
+		sb.WriteString("- " + noteLink(id, title))
+		if len(headings[id]) > 0 {
+			sb.WriteString(" / " + strings.Join(headings[id], "、"))
+		}
+		sb.WriteString("\n")
+		linked++
+	}
+	if linked == 0 {
+		sb.WriteString("未使用笔记作为上下文\n")
+	}
+
+	title := conversationTitle(prompt)
+	if prompt == "" {
+		title = conv.Title
+	}
+	note := &Note{Title: title, ContentMD: sb.String(), CategoryID: categoryID, SourceMessage: reply.ID}
+	if err := DB.Create(note).Error; err != nil {
+		return nil, fmt.Errorf("保存笔记失败: %v", err)
+	}
+	a.enqueueIndex(note.ID)
+	log.Printf("[Conversation] 回答已保存为笔记 (Message ID: %d, Note ID: %d, 来源: %d 篇)", reply.ID, note.ID, linked)
+	return note, nil
+}
@@ -0,0 +1,380 @@
+package backend
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AI 请求错误类型，前端据此给出处理建议
+const (
+	AIErrAuth          = "auth"             // API Key 无效或无权限
+	AIErrQuota         = "quota"            // 额度用尽或请求频率超限
+	AIErrContextLength = "context_too_long" // 内容超出模型上下文窗口
+	AIErrNetwork       = "network"          // 网络错误，如无法连接、超时、代理错误
+	AIErrProvider      = "provider"         // 服务商返回的其他错误
+)
+
+const (
+	// maxRetryWait Retry-After 超过该时间时不再重试，直接返回错误
+	maxRetryWait = time.Minute
+	// maxAIRequestTimeout 一次 AI 请求（含重试）的最长耗时，不限制响应超时时也以此为准
+	maxAIRequestTimeout = 5 * time.Minute
+	// maxBackoff 指数退避的最长等待时间
+	maxBackoff = 30 * time.Second
+	// maxErrorBodyBytes 错误响应最多读取的字节数
+	maxErrorBodyBytes = 64 * 1024
+)
+
+// AIClientConfig AI 请求的网络设置，对所有服务商和向量接口生效
+type AIClientConfig struct {
+	ConnectTimeout  int `json:"connectTimeout"`  // 建立连接的超时（秒）
+	ResponseTimeout int `json:"responseTimeout"` // 等待响应头的超时（秒），0 表示不限制；流式回复开始后不受该限制，整个请求仍受 maxAIRequestTimeout 限制
+	// Proxy 代理地址，支持 http、https、socks5；为空时使用 HTTP_PROXY 等环境变量，direct 表示不使用代理
+	Proxy          string `json:"proxy"`
+	MaxRetries     int    `json:"maxRetries"`     // 429 和 5xx 的最大重试次数
+	RetryBaseDelay int    `json:"retryBaseDelay"` // 首次重试的等待时间（毫秒），之后按指数增长
+}
+
+func defaultAIClientConfig() AIClientConfig {
+	return AIClientConfig{
+		ConnectTimeout:  10,
+		ResponseTimeout: 120,
+		MaxRetries:      3,
+		RetryBaseDelay:  1000,
+	}
+}
+
+func currentAIClientConfig() AIClientConfig {
+	cfgMu.RLock()
+	defer cfgMu.RUnlock()
+	return Cfg.AIClient
+}
+
+// AIError AI 请求的错误
+type AIError struct {
+	Kind    string // AIErr* 之一
+	Status  int    // HTTP 状态码，网络错误时为 0
+	Message string // 服务商返回的错误信息
+	Retries int    // 已重试的次数
+	Err     error  // 网络错误的原始错误
+}
+
+func (e *AIError) Error() string {
+	var desc string
+	switch e.Kind {
+	case AIErrAuth:
+		desc = "API Key 无效或没有权限，请检查 AI 配置"
+	case AIErrQuota:
+		desc = "额度已用尽或请求过于频繁"
+	case AIErrContextLength:
+		desc = "内容超出模型上下文窗口，请减少关联内容或调小上下文窗口设置"
+	case AIErrNetwork:
+		desc = "网络错误，请检查网络和代理设置"
+	default:
+		desc = "AI 服务商返回错误"
+	}
+	if e.Status != 0 {
+		desc += fmt.Sprintf(" (状态码: %d)", e.Status)
+	}
+	if e.Retries > 0 {
+		desc += fmt.Sprintf("，已重试 %d 次", e.Retries)
+	}
+	if e.Err != nil {
+		return desc + ": " + e.Err.Error()
+	}
+	if e.Message != "" {
+		return desc + ": " + e.Message
+	}
+	return desc
+}
+
+func (e *AIError) Unwrap() error {
+	return e.Err
+}
+
+// aiErrorKind 返回错误的类型，不是 AIError 时返回空字符串
+func aiErrorKind(err error) string {
+	var aiErr *AIError
+	if errors.As(err, &aiErr) {
+		return aiErr.Kind
+	}
+	return ""
+}
+
+// providerErrorMessage 从错误响应中提取服务商的错误信息，兼容 OpenAI、Anthropic、Ollama 的格式
+func providerErrorMessage(body []byte) string {
+	var resp struct {
+		Error   json.RawMessage `json:"error"`
+		Message string          `json:"message"`
+	}
+	if json.Unmarshal(body, &resp) == nil {
+		var detail struct {
+			Message string `json:"message"`
+			Type    string `json:"type"`
+			Code    any    `json:"code"`
+		}
+		var text string
+		switch {
+		case json.Unmarshal(resp.Error, &detail) == nil && detail.Message != "":
+			msg := detail.Message
+			if code, ok := detail.Code.(string); ok && code != "" {
+				msg += " (" + code + ")"
+			} else if detail.Type != "" {
+				msg += " (" + detail.Type + ")"
+			}
+			return msg
+		case json.Unmarshal(resp.Error, &text) == nil && text != "":
+			return text
+		case resp.Message != "":
+			return resp.Message
+		}
+	}
+	msg := strings.TrimSpace(string(body))
+	if len([]rune(msg)) > 500 {
+		msg = string([]rune(msg)[:500]) + "..."
+	}
+	return msg
+}
+
+// classifyHTTPError 按状态码和错误信息归类失败的响应
+func classifyHTTPError(status int, body []byte) *AIError {
+	msg := providerErrorMessage(body)
+	lower := strings.ToLower(msg + " " + string(body))
+	containsAny := func(words ...string) bool {
+		for _, w := range words {
+			if strings.Contains(lower, w) {
+				return true
+			}
+		}
+		return false
+	}
+
+	kind := AIErrProvider
+	switch {
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		kind = AIErrAuth
+	case status == http.StatusPaymentRequired || status == http.StatusTooManyRequests:
+		kind = AIErrQuota
+	case containsAny("context_length_exceeded", "maximum context length", "context length", "context window",
+		"prompt is too long", "too many tokens", "input is too long"):
+		kind = AIErrContextLength
+	case status == http.StatusRequestEntityTooLarge:
+		kind = AIErrContextLength
+	case containsAny("insufficient_quota", "credit balance", "billing"):
+		kind = AIErrQuota
+	}
+	return &AIError{Kind: kind, Status: status, Message: msg}
+}
+
+// retryable 是否为可重试的失败：429（额度用尽除外）和 5xx
+func (e *AIError) retryable() bool {
+	if e.Status == http.StatusTooManyRequests {
+		lower := strings.ToLower(e.Message)
+		return !strings.Contains(lower, "insufficient_quota") && !strings.Contains(lower, "billing")
+	}
+	return e.Status >= 500
+}
+
+// parseRetryAfter 解析 Retry-After 响应头，支持秒数和 HTTP 日期两种格式
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		if d := t.Sub(now); d > 0 {
+			return d, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+// backoffDelay 第 attempt 次重试（从 0 开始）的等待时间，指数增长并加入最多 20% 的随机抖动
+func backoffDelay(base time.Duration, attempt int) time.Duration {
+	d := base << attempt
+	if d <= 0 || d > maxBackoff {
+		d = maxBackoff
+	}
+	return d + time.Duration(rand.Int63n(int64(d)/5+1))
+}
+
+var (
+	aiClientMu sync.Mutex
+	aiClient   *http.Client
+)
+
+// aiHTTPClient 所有 AI 请求共用的 http.Client，配置修改后重新创建
+func aiHTTPClient() *http.Client {
+	aiClientMu.Lock()
+	defer aiClientMu.Unlock()
+	if aiClient == nil {
+		aiClient = newAIHTTPClient(currentAIClientConfig())
+	}
+	return aiClient
+}
+
+// resetAIHTTPClient 丢弃共用的 http.Client，下次请求时按新配置创建
+func resetAIHTTPClient() {
+	aiClientMu.Lock()
+	defer aiClientMu.Unlock()
+	if aiClient != nil {
+		aiClient.CloseIdleConnections()
+		aiClient = nil
+	}
+}
+
+func newAIHTTPClient(config AIClientConfig) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	dialer := &net.Dialer{
+		Timeout:   time.Duration(config.ConnectTimeout) * time.Second,
+		KeepAlive: 30 * time.Second,
+	}
+	transport.DialContext = dialer.DialContext
+	transport.TLSHandshakeTimeout = time.Duration(config.ConnectTimeout) * time.Second
+	transport.ResponseHeaderTimeout = time.Duration(config.ResponseTimeout) * time.Second
+
+	switch proxy := strings.TrimSpace(config.Proxy); proxy {
+	case "":
+		transport.Proxy = http.ProxyFromEnvironment
+	case "direct":
+		transport.Proxy = nil
+	default:
+		proxyURL, err := url.Parse(proxy)
+		if err != nil {
+			log.Printf("[AI Client] 代理地址无效，使用环境变量中的代理: %v", err)
+			transport.Proxy = http.ProxyFromEnvironment
+		} else {
+			transport.Proxy = http.ProxyURL(proxyURL)
+		}
+	}
+	// 整体超时由调用方的 ctx 控制，流式回复可能持续较长时间
+	return &http.Client{Transport: transport}
+}
+
+// doAIRequest 使用共用的 http.Client 发送请求，429 和 5xx 按 Retry-After 或指数退避重试
+// 返回状态码为 200 的响应，其余情况返回 *AIError；ctx 取消时返回的错误可用 errors.Is 判断
+func doAIRequest(req *http.Request) (*http.Response, error) {
+	config := currentAIClientConfig()
+	client := aiHTTPClient()
+	ctx := req.Context()
+	base := time.Duration(config.RetryBaseDelay) * time.Millisecond
+
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, &AIError{Kind: AIErrNetwork, Retries: attempt, Err: err}
+			}
+			req = req.Clone(ctx)
+			req.Body = body
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, &AIError{Kind: AIErrNetwork, Retries: attempt, Err: err}
+		}
+		if resp.StatusCode == http.StatusOK {
+			return resp, nil
+		}
+
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodyBytes))
+		resp.Body.Close()
+		aiErr := classifyHTTPError(resp.StatusCode, body)
+		aiErr.Retries = attempt
+		if !aiErr.retryable() || attempt >= config.MaxRetries || req.GetBody == nil {
+			return nil, aiErr
+		}
+
+		wait, ok := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+		if !ok {
+			wait = backoffDelay(base, attempt)
+		} else if wait > maxRetryWait {
+			aiErr.Message += fmt.Sprintf("（服务商要求 %v 后重试）", wait.Round(time.Second))
+			return nil, aiErr
+		}
+		log.Printf("[AI Client] 请求失败 (状态码: %d)，%v 后重试 (%d/%d)", resp.StatusCode, wait.Round(time.Millisecond), attempt+1, config.MaxRetries)
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, &AIError{Kind: AIErrNetwork, Retries: attempt, Err: ctx.Err()}
+		case <-timer.C:
+		}
+	}
+}
+
+// aiRequestTimeout 按网络设置计算一次 AI 请求（含重试）的最长耗时，不超过 maxAIRequestTimeout
+// 每次尝试包括建立连接、等待响应头和读取回复，两次尝试之间最多等待 maxRetryWait
+func aiRequestTimeout(config AIClientConfig) time.Duration {
+	if config.ResponseTimeout <= 0 {
+		return maxAIRequestTimeout
+	}
+	attempt := time.Duration(config.ConnectTimeout+2*config.ResponseTimeout) * time.Second
+	retries := time.Duration(max(config.MaxRetries, 0))
+	return min((retries+1)*attempt+retries*maxRetryWait, maxAIRequestTimeout)
+}
+
+// GetAIClientConfig 获取 AI 请求的网络设置
+func (a *App) GetAIClientConfig() AIClientConfig {
+	return currentAIClientConfig()
+}
+
+// UpdateAIClientConfig 更新 AI 请求的网络设置，立即对之后的请求生效
+func (a *App) UpdateAIClientConfig(config AIClientConfig) error {
+	config.Proxy = strings.TrimSpace(config.Proxy)
+	if config.Proxy != "" && config.Proxy != "direct" {
+		u, err := url.Parse(config.Proxy)
+		if err != nil || u.Host == "" {
+			return errors.New("代理地址无效，格式如 http://127.0.0.1:7890")
+		}
+		switch u.Scheme {
+		case "http", "https", "socks5", "socks5h":
+		default:
+			return fmt.Errorf("不支持的代理协议: %s", u.Scheme)
+		}
+	}
+	if config.ConnectTimeout <= 0 {
+		config.ConnectTimeout = defaultAIClientConfig().ConnectTimeout
+	}
+	if config.ResponseTimeout < 0 {
+		config.ResponseTimeout = 0
+	}
+	if config.MaxRetries < 0 {
+		config.MaxRetries = 0
+	}
+	if config.RetryBaseDelay <= 0 {
+		config.RetryBaseDelay = defaultAIClientConfig().RetryBaseDelay
+	}
+
+	cfgMu.Lock()
+	Cfg.AIClient = config
+	saveConfig := snapshotConfigFile()
+	filePath := configFilePath
+	cfgMu.Unlock()
+	resetAIHTTPClient()
+
+	if err := writeConfigFile(filePath, saveConfig); err != nil {
+		return fmt.Errorf("保存配置文件失败: %v", err)
+	}
+	log.Printf("[Config] AI 网络设置已更新: connectTimeout=%ds, responseTimeout=%ds, proxy=%v, maxRetries=%d", config.ConnectTimeout, config.ResponseTimeout, config.Proxy != "", config.MaxRetries)
+	return nil
+}
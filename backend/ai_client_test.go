@@ -0,0 +1,208 @@
+package backend
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// useAIClientConfig 在测试期间替换 AI 请求的网络设置，结束后恢复
+func useAIClientConfig(t *testing.T, config AIClientConfig) {
+	t.Helper()
+	cfgMu.Lock()
+	saved := Cfg.AIClient
+	Cfg.AIClient = config
+	cfgMu.Unlock()
+	resetAIHTTPClient()
+	t.Cleanup(func() {
+		cfgMu.Lock()
+		Cfg.AIClient = saved
+		cfgMu.Unlock()
+		resetAIHTTPClient()
+	})
+}
+
+// testAIClientConfig 不使用代理、重试间隔很短的网络设置
+func testAIClientConfig(maxRetries int) AIClientConfig {
+	return AIClientConfig{
+		ConnectTimeout:  5,
+		ResponseTimeout: 5,
+		Proxy:           "direct",
+		MaxRetries:      maxRetries,
+		RetryBaseDelay:  1,
+	}
+}
+
+func newTestAIRequest(t *testing.T, url string) *http.Request {
+	t.Helper()
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, url, bytes.NewReader([]byte(`{"model":"test"}`)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return req
+}
+
+func TestDoAIRequestRetriesAfterTooManyRequests(t *testing.T) {
+	useAIClientConfig(t, testAIClientConfig(3))
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if string(body) != `{"model":"test"}` {
+			t.Errorf("第 %d 次请求的请求体为 %q", calls.Load()+1, body)
+		}
+		if calls.Add(1) == 1 {
+			w.Header().Set("Retry-After", "0")
+			http.Error(w, `{"error":{"message":"Rate limit reached","type":"requests"}}`, http.StatusTooManyRequests)
+			return
+		}
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	defer srv.Close()
+
+	resp, err := doAIRequest(newTestAIRequest(t, srv.URL))
+	if err != nil {
+		t.Fatalf("doAIRequest: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("状态码为 %d，期望 200", resp.StatusCode)
+	}
+	if n := calls.Load(); n != 2 {
+		t.Errorf("请求了 %d 次，期望 2 次", n)
+	}
+}
+
+func TestDoAIRequestExhaustsRetriesOnServerError(t *testing.T) {
+	useAIClientConfig(t, testAIClientConfig(2))
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		http.Error(w, `{"error":{"message":"overloaded"}}`, http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	_, err := doAIRequest(newTestAIRequest(t, srv.URL))
+	var aiErr *AIError
+	if !errors.As(err, &aiErr) {
+		t.Fatalf("错误为 %v，期望 *AIError", err)
+	}
+	if aiErr.Kind != AIErrProvider || aiErr.Status != http.StatusServiceUnavailable || aiErr.Retries != 2 {
+		t.Errorf("错误为 %+v，期望 provider/503 且重试 2 次", aiErr)
+	}
+	if n := calls.Load(); n != 3 {
+		t.Errorf("请求了 %d 次，期望 3 次", n)
+	}
+}
+
+func TestDoAIRequestFailsFastOnLongRetryAfter(t *testing.T) {
+	useAIClientConfig(t, testAIClientConfig(3))
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Retry-After", "3600")
+		http.Error(w, `{"error":{"message":"Rate limit reached"}}`, http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+
+	start := time.Now()
+	_, err := doAIRequest(newTestAIRequest(t, srv.URL))
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("耗时 %v，期望立即返回", elapsed)
+	}
+	if kind := aiErrorKind(err); kind != AIErrQuota {
+		t.Errorf("错误类型为 %q，期望 %q (err: %v)", kind, AIErrQuota, err)
+	}
+	if n := calls.Load(); n != 1 {
+		t.Errorf("请求了 %d 次，期望 1 次", n)
+	}
+}
+
+func TestDoAIRequestClassifiesErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		kind   string
+	}{
+		{"unauthorized", http.StatusUnauthorized, `{"error":{"message":"Incorrect API key provided","code":"invalid_api_key"}}`, AIErrAuth},
+		{"context length", http.StatusBadRequest, `{"error":{"message":"This model's maximum context length is 8192 tokens","code":"context_length_exceeded"}}`, AIErrContextLength},
+		{"anthropic prompt too long", http.StatusBadRequest, `{"type":"error","error":{"type":"invalid_request_error","message":"prompt is too long: 210000 tokens > 200000 maximum"}}`, AIErrContextLength},
+		{"insufficient quota", http.StatusTooManyRequests, `{"error":{"message":"You exceeded your current quota","code":"insufficient_quota"}}`, AIErrQuota},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useAIClientConfig(t, testAIClientConfig(3))
+			var calls atomic.Int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls.Add(1)
+				http.Error(w, tt.body, tt.status)
+			}))
+			defer srv.Close()
+
+			_, err := doAIRequest(newTestAIRequest(t, srv.URL))
+			if kind := aiErrorKind(err); kind != tt.kind {
+				t.Errorf("错误类型为 %q，期望 %q (err: %v)", kind, tt.kind, err)
+			}
+			if n := calls.Load(); n != 1 {
+				t.Errorf("请求了 %d 次，期望不重试", n)
+			}
+		})
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name  string
+		value string
+		want  time.Duration
+		ok    bool
+	}{
+		{"seconds", "30", 30 * time.Second, true},
+		{"zero seconds", "0", 0, true},
+		{"seconds with spaces", " 5 ", 5 * time.Second, true},
+		{"negative seconds", "-1", 0, false},
+		{"http date", now.Add(90 * time.Second).Format(http.TimeFormat), 90 * time.Second, true},
+		{"past http date", now.Add(-time.Minute).Format(http.TimeFormat), 0, true},
+		{"empty", "", 0, false},
+		{"garbage", "soon", 0, false},
+		{"fractional seconds", "1.5", 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseRetryAfter(tt.value, now)
+			if got != tt.want || ok != tt.ok {
+				t.Errorf("parseRetryAfter(%q) = %v, %v，期望 %v, %v", tt.value, got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestAIRequestTimeout(t *testing.T) {
+	tests := []struct {
+		name   string
+		mutate func(*AIClientConfig)
+		want   time.Duration
+	}{
+		// 2 次尝试各 10s 连接 + 2×30s 响应，1 次重试间隔最多 maxRetryWait
+		{"derived from settings", func(c *AIClientConfig) { c.ResponseTimeout, c.MaxRetries = 30, 1 }, 2*70*time.Second + maxRetryWait},
+		{"no retries", func(c *AIClientConfig) { c.ResponseTimeout, c.MaxRetries = 30, 0 }, 70 * time.Second},
+		{"capped", func(c *AIClientConfig) {}, maxAIRequestTimeout},
+		{"unlimited response timeout", func(c *AIClientConfig) { c.ResponseTimeout = 0 }, maxAIRequestTimeout},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := defaultAIClientConfig()
+			tt.mutate(&config)
+			if got := aiRequestTimeout(config); got != tt.want {
+				t.Errorf("aiRequestTimeout = %v，期望 %v", got, tt.want)
+			}
+		})
+	}
+}
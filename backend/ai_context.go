@@ -0,0 +1,450 @@
+package backend
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// defaultContextTokens 未配置时模型的上下文窗口（token）
+	defaultContextTokens = 16384
+	// defaultReplyTokens 未配置 max_tokens 时为回复预留的 token
+	defaultReplyTokens = 2048
+	// messageOverheadTokens 每条消息的格式开销
+	messageOverheadTokens = 4
+	// minSectionTokens 剩余预算低于该值时不再截断加入内容
+	minSectionTokens = 200
+)
+
+// 上下文裁剪策略
+const (
+	ContextByRelevance = "relevance" // 按与提问的相关度
+	ContextByRecency   = "recency"   // 按笔记更新时间
+
+	// contextByRank 保持调用方给出的顺序，如语义检索按相似度排好的片段
+	contextByRank = "rank"
+)
+
+var mdHeadingRegex = regexp.MustCompile(`^ {0,3}(#{1,6})\s+(.+?)\s*#*\s*$`)
+
+// isCJK 判断是否为中日韩字符或全角标点，这类字符通常每个字符约占一个 token
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul) ||
+		(r >= 0x3000 && r <= 0x303F) || (r >= 0xFF00 && r <= 0xFFEF)
+}
+
+// estimateTokens 估算文本的 token 数：CJK 字符按 1 个计，其余按约 4 字节 1 个计
+func estimateTokens(s string) int {
+	cjk, other := 0, 0
+	for _, r := range s {
+		if isCJK(r) {
+			cjk++
+		} else {
+			other += utf8.RuneLen(r)
+		}
+	}
+	return cjk + (other+3)/4
+}
+
+// estimateMessagesTokens 估算消息列表的 token 数
+func estimateMessagesTokens(messages []chatMessage) int {
+	total := 0
+	for _, m := range messages {
+		total += estimateTokens(m.Content) + messageOverheadTokens
+		for _, img := range m.Images {
+			total += img.Tokens
+		}
+	}
+	return total
+}
+
+// mdSection 按标题切分的 Markdown 片段
+type mdSection struct {
+	Heading string
+	Text    string
+}
+
+// splitMarkdownSections 在标题处切分 Markdown，忽略代码块中的 # 行
+func splitMarkdownSections(md string) []mdSection {
+	var sections []mdSection
+	current := mdSection{}
+	var lines []string
+	fence := ""
+	flush := func() {
+		text := strings.TrimSpace(strings.Join(lines, "\n"))
+		if text != "" {
+			current.Text = text
+			sections = append(sections, current)
+		}
+		lines = nil
+	}
+	for _, line := range strings.Split(md, "\n") {
+		if fence == "" {
+			if m := codeFenceRegex.FindStringSubmatch(line); m != nil {
+				fence = m[1]
+			} else if m := mdHeadingRegex.FindStringSubmatch(line); m != nil {
+				flush()
+				current = mdSection{Heading: m[2]}
+			}
+		} else if trimmed := strings.TrimSpace(line); strings.HasPrefix(trimmed, fence) && strings.Trim(trimmed, fence[:1]) == "" {
+			fence = ""
+		}
+		lines = append(lines, line)
+	}
+	flush()
+	return sections
+}
+
+// queryTerms 提取提问中的检索词：英文单词和中文二元组
+func queryTerms(query string) []string {
+	seen := map[string]bool{}
+	var terms []string
+	add := func(t string) {
+		if !seen[t] {
+			seen[t] = true
+			terms = append(terms, t)
+		}
+	}
+	var word []rune
+	var cjk []rune
+	flushWord := func() {
+		if len(word) >= 2 {
+			add(strings.ToLower(string(word)))
+		}
+		word = nil
+	}
+	flushCJK := func() {
+		if len(cjk) == 1 {
+			add(string(cjk))
+		}
+		for i := 0; i+1 < len(cjk); i++ {
+			add(string(cjk[i : i+2]))
+		}
+		cjk = nil
+	}
+	for _, r := range query {
+		switch {
+		case unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul):
+			flushWord()
+			cjk = append(cjk, r)
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_':
+			flushCJK()
+			word = append(word, r)
+		default:
+			flushWord()
+			flushCJK()
+		}
+	}
+	flushWord()
+	flushCJK()
+	return terms
+}
+
+// relevanceScore 按检索词出现次数打分，标题命中权重更高
+func relevanceScore(terms []string, title string, text string) float64 {
+	if len(terms) == 0 {
+		return 0
+	}
+	title = strings.ToLower(title)
+	text = strings.ToLower(text)
+	score := 0.0
+	for _, t := range terms {
+		if strings.Contains(title, t) {
+			score += 3
+		}
+		score += float64(min(strings.Count(text, t), 5))
+	}
+	return score
+}
+
+// contextItem 一篇候选上下文
+type contextItem struct {
+	NoteID    uint
+	Title     string
+	Heading   string // 检索得到的片段所在的小节标题
+	Label     string // 为空时不添加标题行，如前端传入的上下文文本已自带标题
+	Text      string
+	UpdatedAt time.Time
+	score     float64
+}
+
+// ContextEntry 上下文中的一段内容
+type ContextEntry struct {
+	NoteID    uint   `json:"noteId,omitempty"`
+	Title     string `json:"title"`
+	Heading   string `json:"heading,omitempty"` // 按标题切分后的片段，为空表示整篇或开头部分
+	Tokens    int    `json:"tokens"`
+	Truncated bool   `json:"truncated,omitempty"`
+}
+
+// ContextReport 一次请求实际使用的上下文
+type ContextReport struct {
+	Strategy string         `json:"strategy"`
+	Budget   int            `json:"budget"` // 可用于上下文的 token 数
+	Used     int            `json:"used"`
+	Included []ContextEntry `json:"included"`
+	Omitted  []ContextEntry `json:"omitted"`
+	// Images 附带的图片，为图片存储目录中的相对路径；OmittedImages 为超出数量或预算、无法读取而略过的图片
+	Images        []string `json:"images,omitempty"`
+	OmittedImages []string `json:"omittedImages,omitempty"`
+}
+
+// currentContextStrategy 读取配置的上下文裁剪策略
+func currentContextStrategy() string {
+	cfgMu.RLock()
+	defer cfgMu.RUnlock()
+	return Cfg.ContextStrategy
+}
+
+// contextBudget 计算可用于上下文和历史消息的 token 数
+func contextBudget(p AIProvider, systemPrompt string, fixed []chatMessage) int {
+	limit := p.ContextTokens
+	if limit <= 0 {
+		limit = defaultContextTokens
+	}
+	reply := p.MaxTokens
+	if reply <= 0 {
+		reply = defaultReplyTokens
+	}
+	return max(0, limit-reply-estimateMessagesTokens(fixed)-estimateTokens(buildSystemPrompt(systemPrompt, nil)))
+}
+
+// noteContextItem 将笔记转换为候选上下文，PDF 和空笔记返回 false
+func noteContextItem(note Note) (contextItem, bool) {
+	text := note.ContentMD
+	switch note.Type {
+	case 1:
+		return contextItem{}, false
+	case 3:
+		epubText, err := getEPUBText(note.ID)
+		if err != nil {
+			return contextItem{}, false
+		}
+		text = epubText
+	}
+	if strings.TrimSpace(text) == "" {
+		return contextItem{}, false
+	}
+	return contextItem{NoteID: note.ID, Title: note.Title, Label: "笔记", Text: text, UpdatedAt: note.UpdatedAt}, true
+}
+
+// collectContextItems 读取关联的目录和笔记，目录展开为其中的各篇笔记，重复的笔记只保留一次
+// 加密目录（含子目录）中的笔记不会发送给 AI 服务；只有直接关联的加密目录例外，前端发送前已要求解锁该目录
+// 解锁只对该目录本身生效，其下的其他加密目录仍然略过
+func (a *App) collectContextItems(refs []ContextRef) []contextItem {
+	locked, err := lockedCategoryIDs()
+	if err != nil {
+		log.Printf("[Context] 读取加密目录失败，不使用关联内容: %v", err)
+		return nil
+	}
+	var items []contextItem
+	seen := map[uint]bool{}
+	addNote := func(note Note) {
+		if seen[note.ID] {
+			return
+		}
+		seen[note.ID] = true
+		if item, ok := noteContextItem(note); ok {
+			items = append(items, item)
+		}
+	}
+	for _, ref := range refs {
+		switch ref.Type {
+		case "category":
+			categoryID := ref.ID
+			notes, err := a.ListNotes(&categoryID)
+			if err != nil {
+				log.Printf("[Context] 读取目录失败 (category:%d): %v", ref.ID, err)
+				continue
+			}
+			var unlocked map[uint]bool
+			if encryptedCategory(ref.ID) {
+				if unlocked, err = unlockedCategoryIDs(ref.ID); err != nil {
+					log.Printf("[Context] 读取加密目录失败 (category:%d): %v", ref.ID, err)
+					continue
+				}
+			}
+			skipped := 0
+			for _, note := range notes {
+				if locked[note.CategoryID] && !unlocked[note.CategoryID] {
+					skipped++
+					continue
+				}
+				addNote(note)
+			}
+			if skipped > 0 {
+				log.Printf("[Context] 略过加密目录中的笔记 (category:%d): %d 篇", ref.ID, skipped)
+			}
+		case "note":
+			var note Note
+			if err := DB.First(&note, ref.ID).Error; err != nil {
+				log.Printf("[Context] 读取笔记失败 (note:%d): %v", ref.ID, err)
+				continue
+			}
+			if locked[note.CategoryID] {
+				log.Printf("[Context] 略过加密目录中的笔记 (note:%d)", ref.ID)
+				continue
+			}
+			addNote(note)
+		}
+	}
+	return items
+}
+
+// encryptedCategory 目录本身是否使用加密颜色，不含继承自上级目录的情况
+func encryptedCategory(categoryID uint) bool {
+	var cat Category
+	if err := DB.Preload("ColorPreset").First(&cat, categoryID).Error; err != nil {
+		return false
+	}
+	return cat.ColorPreset != nil && cat.ColorPreset.Encrypted
+}
+
+// unlockedCategoryIDs 解锁加密目录后可以使用的目录：该目录及其子目录，遇到其他加密目录时不再向下展开
+func unlockedCategoryIDs(categoryID uint) (map[uint]bool, error) {
+	var cats []Category
+	if err := DB.Preload("ColorPreset").Find(&cats).Error; err != nil {
+		return nil, err
+	}
+	children := map[uint][]Category{}
+	for _, c := range cats {
+		if c.ParentID != nil {
+			children[*c.ParentID] = append(children[*c.ParentID], c)
+		}
+	}
+	unlocked := map[uint]bool{categoryID: true}
+	var walk func(uint)
+	walk = func(id uint) {
+		for _, c := range children[id] {
+			if unlocked[c.ID] || (c.ColorPreset != nil && c.ColorPreset.Encrypted) {
+				continue
+			}
+			unlocked[c.ID] = true
+			walk(c.ID)
+		}
+	}
+	walk(categoryID)
+	return unlocked, nil
+}
+
+// textContextItems 将前端传入的上下文文本转换为候选上下文，首行作为标题
+func textContextItems(texts []string) []contextItem {
+	var items []contextItem
+	for _, text := range texts {
+		title, _, _ := strings.Cut(strings.TrimSpace(text), "\n")
+		items = append(items, contextItem{Title: title, Text: text})
+	}
+	return items
+}
+
+// fitContext 在预算内挑选上下文：整篇放不下时在标题处切分，按片段挑选；单个片段仍放不下时截断
+// 返回按笔记分组的上下文文本和报告
+func fitContext(items []contextItem, query string, strategy string, budget int) ([]string, *ContextReport) {
+	if strategy != ContextByRecency && strategy != contextByRank {
+		strategy = ContextByRelevance
+	}
+	report := &ContextReport{Strategy: strategy, Budget: budget, Included: []ContextEntry{}, Omitted: []ContextEntry{}}
+	terms := queryTerms(query)
+	for i := range items {
+		items[i].score = relevanceScore(terms, items[i].Title, items[i].Text)
+	}
+	if strategy != contextByRank {
+		sort.SliceStable(items, func(i, j int) bool {
+			if strategy == ContextByRelevance && items[i].score != items[j].score {
+				return items[i].score > items[j].score
+			}
+			return items[i].UpdatedAt.After(items[j].UpdatedAt)
+		})
+	}
+
+	type part struct {
+		section   mdSection
+		tokens    int
+		score     float64
+		order     int
+		truncated bool
+	}
+	var texts []string
+	remaining := budget
+	for _, item := range items {
+		header := ""
+		if item.Label != "" {
+			header = fmt.Sprintf("[%s: %s]\n", item.Label, item.Title)
+		}
+		headerTokens := estimateTokens(header) + messageOverheadTokens
+		tokens := estimateTokens(item.Text)
+		if tokens+headerTokens <= remaining {
+			texts = append(texts, header+item.Text)
+			remaining -= tokens + headerTokens
+			report.Included = append(report.Included, ContextEntry{NoteID: item.NoteID, Title: item.Title, Heading: item.Heading, Tokens: tokens})
+			continue
+		}
+
+		var parts []part
+		for i, sec := range splitMarkdownSections(item.Text) {
+			parts = append(parts, part{section: sec, tokens: estimateTokens(sec.Text), score: relevanceScore(terms, sec.Heading, sec.Text), order: i})
+		}
+		if strategy == ContextByRelevance {
+			sort.SliceStable(parts, func(i, j int) bool { return parts[i].score > parts[j].score })
+		}
+		var picked []part
+		avail := remaining - headerTokens
+		for _, pt := range parts {
+			if pt.tokens > avail {
+				// 最相关的片段也放不下时截断后加入，其余放不下的片段略过
+				if len(picked) > 0 || avail < minSectionTokens {
+					report.Omitted = append(report.Omitted, ContextEntry{NoteID: item.NoteID, Title: item.Title, Heading: pt.section.Heading, Tokens: pt.tokens})
+					continue
+				}
+				pt.section.Text = truncateToTokens(pt.section.Text, avail)
+				pt.tokens = estimateTokens(pt.section.Text)
+				pt.truncated = true
+			}
+			picked = append(picked, pt)
+			avail -= pt.tokens
+			report.Included = append(report.Included, ContextEntry{NoteID: item.NoteID, Title: item.Title, Heading: pt.section.Heading, Tokens: pt.tokens, Truncated: pt.truncated})
+		}
+		if len(picked) == 0 {
+			continue
+		}
+		// 按原文顺序拼接选中的片段
+		sort.Slice(picked, func(i, j int) bool { return picked[i].order < picked[j].order })
+		var sb strings.Builder
+		sb.WriteString(header)
+		for i, pt := range picked {
+			if i > 0 {
+				sb.WriteString("\n\n")
+			}
+			sb.WriteString(pt.section.Text)
+		}
+		texts = append(texts, sb.String())
+		remaining = avail
+	}
+	report.Used = budget - remaining
+	return texts, report
+}
+
+// truncateToTokens 截断文本使其估算 token 数不超过 limit
+func truncateToTokens(s string, limit int) string {
+	const marker = "\n...(内容过长，已截断)"
+	limit -= estimateTokens(marker)
+	tokens, cjk, other := 0, 0, 0
+	for i, r := range s {
+		if isCJK(r) {
+			cjk++
+		} else {
+			other += utf8.RuneLen(r)
+		}
+		tokens = cjk + (other+3)/4
+		if tokens > limit {
+			return s[:i] + marker
+		}
+	}
+	return s
+}
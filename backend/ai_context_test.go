@@ -0,0 +1,70 @@
+package backend
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestCollectContextItemsSkipsNestedEncryptedCategories(t *testing.T) {
+	useTestDB(t)
+	app := NewApp()
+
+	plain := &ColorPreset{Name: "普通", Hex: "#1677ff"}
+	encrypted := &ColorPreset{Name: "加密", Hex: "#000000", Encrypted: true}
+	for _, p := range []*ColorPreset{plain, encrypted} {
+		if err := DB.Create(p).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 加密目录 A 下有普通子目录 B 和加密子目录 D，B 下还有加密子目录 C
+	newCategory := func(name string, preset *ColorPreset, parent *Category) *Category {
+		t.Helper()
+		c := &Category{Name: name, ColorPresetID: &preset.ID}
+		if parent != nil {
+			c.ParentID = &parent.ID
+		}
+		if err := DB.Create(c).Error; err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+	a := newCategory("A", encrypted, nil)
+	b := newCategory("B", plain, a)
+	c := newCategory("C", encrypted, b)
+	d := newCategory("D", encrypted, a)
+	open := newCategory("公开", plain, nil)
+
+	notes := map[string]uint{}
+	for title, cat := range map[string]*Category{"a": a, "b": b, "c": c, "d": d, "open": open} {
+		note := &Note{Title: title, ContentMD: "内容 " + title, CategoryID: cat.ID}
+		if err := DB.Create(note).Error; err != nil {
+			t.Fatal(err)
+		}
+		notes[title] = note.ID
+	}
+
+	tests := []struct {
+		name string
+		refs []ContextRef
+		want []string
+	}{
+		{"unlocked category skips encrypted descendants", []ContextRef{{Type: "category", ID: a.ID}}, []string{"a", "b"}},
+		{"nested encrypted category referenced directly", []ContextRef{{Type: "category", ID: c.ID}}, []string{"c"}},
+		{"plain subcategory of encrypted category", []ContextRef{{Type: "category", ID: b.ID}}, nil},
+		{"note in nested encrypted category", []ContextRef{{Type: "note", ID: notes["c"]}, {Type: "note", ID: notes["open"]}}, []string{"open"}},
+		{"note in plain subcategory of encrypted category", []ContextRef{{Type: "note", ID: notes["b"]}}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, item := range app.collectContextItems(tt.refs) {
+				got = append(got, item.Title)
+			}
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("collectContextItems = %v，期望 %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,464 @@
+package backend
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// EventSemanticIndexProgress 重建向量索引的进度，数据为 SemanticIndexProgress
+const EventSemanticIndexProgress = "semantic-index-progress"
+
+const (
+	// embeddingBatchSize 每次请求向量接口的片段数
+	embeddingBatchSize = 32
+	// embeddingInputTokens 单个片段送去计算向量的 token 上限，超出部分截断
+	embeddingInputTokens = 2000
+	// embeddingTimeout 单篇笔记建立索引的超时时间
+	embeddingTimeout = 2 * time.Minute
+	// defaultRetrievalTopK 检索模式默认返回的片段数
+	defaultRetrievalTopK = 5
+)
+
+// EmbeddingConfig 向量索引配置
+type EmbeddingConfig struct {
+	Enabled  bool   `json:"enabled"`
+	Provider string `json:"provider"` // 计算向量使用的服务商，为空时使用默认服务商
+	Model    string `json:"model"`    // 向量模型，如 text-embedding-3-small、nomic-embed-text
+	// APIURL 向量接口地址，为空时由服务商的对话接口地址推导（/chat/completions → /embeddings，/api/chat → /api/embed）
+	APIURL string `json:"apiURL,omitempty"`
+	TopK   int    `json:"topK"` // 检索模式返回的片段数
+}
+
+func defaultEmbeddingConfig() EmbeddingConfig {
+	return EmbeddingConfig{TopK: defaultRetrievalTopK}
+}
+
+// currentEmbeddingConfig 读取当前的向量索引配置
+func currentEmbeddingConfig() EmbeddingConfig {
+	cfgMu.RLock()
+	defer cfgMu.RUnlock()
+	return Cfg.Embedding
+}
+
+// embeddingAdapter 支持向量接口的服务商适配器
+type embeddingAdapter interface {
+	newEmbeddingRequest(ctx context.Context, url string, p AIProvider, model string, inputs []string) (*http.Request, error)
+	parseEmbeddings(body []byte) ([][]float32, error)
+	parseUsage(body []byte) tokenUsage
+}
+
+func (openAIAdapter) newEmbeddingRequest(ctx context.Context, url string, p AIProvider, model string, inputs []string) (*http.Request, error) {
+	req, err := newJSONRequest(ctx, url, map[string]interface{}{"model": model, "input": inputs})
+	if err != nil {
+		return nil, err
+	}
+	if p.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+p.APIKey)
+	}
+	return req, nil
+}
+
+func (openAIAdapter) parseEmbeddings(body []byte) ([][]float32, error) {
+	var resp struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float32 `json:"embedding"`
+		} `json:"data"`
+		Error struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("解析响应失败: %v", err)
+	}
+	if resp.Error.Message != "" {
+		return nil, fmt.Errorf("API 错误: %s", resp.Error.Message)
+	}
+	vectors := make([][]float32, len(resp.Data))
+	for _, d := range resp.Data {
+		if d.Index < 0 || d.Index >= len(vectors) {
+			return nil, errors.New("向量接口返回的序号无效")
+		}
+		vectors[d.Index] = d.Embedding
+	}
+	return vectors, nil
+}
+
+func (ollamaAdapter) newEmbeddingRequest(ctx context.Context, url string, p AIProvider, model string, inputs []string) (*http.Request, error) {
+	req, err := newJSONRequest(ctx, url, map[string]interface{}{"model": model, "input": inputs})
+	if err != nil {
+		return nil, err
+	}
+	if p.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+p.APIKey)
+	}
+	return req, nil
+}
+
+func (ollamaAdapter) parseEmbeddings(body []byte) ([][]float32, error) {
+	var resp struct {
+		Embeddings [][]float32 `json:"embeddings"`
+		Error      string      `json:"error"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("解析响应失败: %v", err)
+	}
+	if resp.Error != "" {
+		return nil, fmt.Errorf("API 错误: %s", resp.Error)
+	}
+	return resp.Embeddings, nil
+}
+
+// embeddingEndpoint 计算向量使用的服务商、适配器和接口地址
+type embeddingEndpoint struct {
+	provider AIProvider
+	adapter  embeddingAdapter
+	url      string
+	model    string
+}
+
+// resolveEmbedding 按配置解析向量接口，未启用或服务商不支持时返回错误
+func resolveEmbedding() (*embeddingEndpoint, error) {
+	cfg := currentEmbeddingConfig()
+	if !cfg.Enabled {
+		return nil, errors.New("未启用语义检索，请在 AI 配置中开启向量索引")
+	}
+	if cfg.Model == "" {
+		return nil, errors.New("未配置向量模型")
+	}
+	p, err := resolveAIProvider(cfg.Provider)
+	if err != nil {
+		return nil, err
+	}
+	adapter, err := adapterFor(p.Kind)
+	if err != nil {
+		return nil, err
+	}
+	embedder, ok := adapter.(embeddingAdapter)
+	if !ok {
+		return nil, fmt.Errorf("AI 服务商 %s 不支持向量接口", p.Name)
+	}
+	url := cfg.APIURL
+	if url == "" {
+		switch {
+		case strings.HasSuffix(p.APIURL, "/chat/completions"):
+			url = strings.TrimSuffix(p.APIURL, "/chat/completions") + "/embeddings"
+		case strings.HasSuffix(p.APIURL, "/api/chat"):
+			url = strings.TrimSuffix(p.APIURL, "/api/chat") + "/api/embed"
+		default:
+			return nil, errors.New("无法推导向量接口地址，请在配置中填写")
+		}
+	}
+	return &embeddingEndpoint{provider: p, adapter: embedder, url: url, model: cfg.Model}, nil
+}
+
+// embed 计算一批文本的向量
+func (e *embeddingEndpoint) embed(ctx context.Context, inputs []string) ([][]float32, error) {
+	req, err := e.adapter.newEmbeddingRequest(ctx, e.url, e.provider, e.model, inputs)
+	if err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	body, err := sendAIRequest(req)
+	if err != nil {
+		recordAIUsage(e.provider, e.model, UsageSourceEmbedding, tokenUsage{}, start, err)
+		return nil, err
+	}
+	vectors, err := e.adapter.parseEmbeddings(body)
+	usage := e.adapter.parseUsage(body)
+	if usage.Prompt == 0 {
+		for _, input := range inputs {
+			usage.Prompt += estimateTokens(input)
+		}
+		usage.Estimated = true
+	}
+	recordAIUsage(e.provider, e.model, UsageSourceEmbedding, usage, start, err)
+	if err != nil {
+		return nil, err
+	}
+	if len(vectors) != len(inputs) {
+		return nil, fmt.Errorf("向量数量不匹配: 请求 %d 条，返回 %d 条", len(inputs), len(vectors))
+	}
+	return vectors, nil
+}
+
+// encodeVector 将向量编码为小端 float32 字节序列
+func encodeVector(v []float32) []byte {
+	buf := make([]byte, 4*len(v))
+	for i, f := range v {
+		binary.LittleEndian.PutUint32(buf[i*4:], math.Float32bits(f))
+	}
+	return buf
+}
+
+// decodeVector 解码 encodeVector 生成的字节序列
+func decodeVector(buf []byte) []float32 {
+	v := make([]float32, len(buf)/4)
+	for i := range v {
+		v[i] = math.Float32frombits(binary.LittleEndian.Uint32(buf[i*4:]))
+	}
+	return v
+}
+
+// chunkHash 片段内容哈希，内容和向量模型都不变时复用已有向量
+func chunkHash(model string, sec mdSection) string {
+	sum := sha256.Sum256([]byte(model + "\x00" + sec.Heading + "\x00" + sec.Text))
+	return hex.EncodeToString(sum[:])
+}
+
+// embeddingInput 片段送去计算向量的文本，带上笔记标题和小节标题
+func embeddingInput(title string, sec mdSection) string {
+	text := title + "\n"
+	if sec.Heading != "" {
+		text += sec.Heading + "\n"
+	}
+	return truncateToTokens(text+sec.Text, embeddingInputTokens)
+}
+
+// lockedCategoryIDs 使用加密颜色的目录及其子目录，这些目录中的笔记不发送给 AI 服务
+func lockedCategoryIDs() (map[uint]bool, error) {
+	var cats []Category
+	if err := DB.Preload("ColorPreset").Find(&cats).Error; err != nil {
+		return nil, err
+	}
+	children := map[uint][]uint{}
+	var roots []uint
+	for _, c := range cats {
+		if c.ParentID != nil {
+			children[*c.ParentID] = append(children[*c.ParentID], c.ID)
+		}
+		if c.ColorPreset != nil && c.ColorPreset.Encrypted {
+			roots = append(roots, c.ID)
+		}
+	}
+	locked := map[uint]bool{}
+	var mark func(uint)
+	mark = func(id uint) {
+		if locked[id] {
+			return
+		}
+		locked[id] = true
+		for _, child := range children[id] {
+			mark(child)
+		}
+	}
+	for _, id := range roots {
+		mark(id)
+	}
+	return locked, nil
+}
+
+// indexNote 增量更新笔记的向量索引：内容未变的片段复用原有向量，只为新增或修改的片段计算向量
+func indexNote(ctx context.Context, e *embeddingEndpoint, noteID uint) error {
+	var note Note
+	if err := DB.First(&note, noteID).Error; err != nil {
+		// 笔记已删除
+		return DB.Where("note_id = ?", noteID).Delete(&NoteChunk{}).Error
+	}
+	locked, err := lockedCategoryIDs()
+	if err != nil {
+		return err
+	}
+	var sections []mdSection
+	if item, ok := noteContextItem(note); ok && !locked[note.CategoryID] {
+		sections = splitMarkdownSections(item.Text)
+	}
+
+	var existing []NoteChunk
+	if err := DB.Select("id", "hash").Where("note_id = ?", noteID).Find(&existing).Error; err != nil {
+		return err
+	}
+	reuse := map[string]uint{}
+	for _, c := range existing {
+		reuse[c.Hash] = c.ID
+	}
+
+	keep := map[uint]bool{}
+	var pending []NoteChunk
+	var inputs []string
+	for i, sec := range sections {
+		hash := chunkHash(e.model, sec)
+		if id, ok := reuse[hash]; ok && !keep[id] {
+			keep[id] = true
+			DB.Model(&NoteChunk{}).Where("id = ?", id).UpdateColumn("seq", i)
+			continue
+		}
+		pending = append(pending, NoteChunk{NoteID: noteID, Seq: uint(i), Heading: sec.Heading, Content: sec.Text, Hash: hash, Model: e.model})
+		inputs = append(inputs, embeddingInput(note.Title, sec))
+	}
+
+	for start := 0; start < len(pending); start += embeddingBatchSize {
+		end := min(start+embeddingBatchSize, len(pending))
+		vectors, err := e.embed(ctx, inputs[start:end])
+		if err != nil {
+			return err
+		}
+		for i, v := range vectors {
+			pending[start+i].Dim = len(v)
+			pending[start+i].Vector = encodeVector(v)
+		}
+	}
+
+	var stale []uint
+	for _, c := range existing {
+		if !keep[c.ID] {
+			stale = append(stale, c.ID)
+		}
+	}
+	if len(stale) > 0 {
+		if err := DB.Delete(&NoteChunk{}, stale).Error; err != nil {
+			return err
+		}
+	}
+	if len(pending) > 0 {
+		if err := DB.CreateInBatches(pending, 100).Error; err != nil {
+			return err
+		}
+	}
+	log.Printf("[Embedding] 笔记索引已更新 (Note ID: %d, 片段: %d, 新计算: %d, 删除: %d)", noteID, len(sections), len(pending), len(stale))
+	return nil
+}
+
+// enqueueIndex 在后台更新笔记的向量索引，未启用语义检索时忽略
+func (a *App) enqueueIndex(noteID uint) {
+	if !currentEmbeddingConfig().Enabled {
+		return
+	}
+	a.indexMu.Lock()
+	defer a.indexMu.Unlock()
+	a.indexPending[noteID] = true
+	if a.indexRunning {
+		return
+	}
+	a.indexRunning = true
+	go a.indexWorker()
+}
+
+// indexWorker 依次处理待更新索引的笔记，队列为空时退出
+func (a *App) indexWorker() {
+	for {
+		a.indexMu.Lock()
+		var noteID uint
+		for id := range a.indexPending {
+			noteID = id
+			break
+		}
+		if noteID == 0 {
+			a.indexRunning = false
+			a.indexMu.Unlock()
+			return
+		}
+		delete(a.indexPending, noteID)
+		a.indexMu.Unlock()
+
+		e, err := resolveEmbedding()
+		if err != nil {
+			log.Printf("[Embedding] 跳过索引更新 (Note ID: %d): %v", noteID, err)
+			continue
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), embeddingTimeout)
+		if err := indexNote(ctx, e, noteID); err != nil {
+			log.Printf("[Embedding] 索引更新失败 (Note ID: %d): %v", noteID, err)
+		}
+		cancel()
+	}
+}
+
+// SemanticIndexProgress 重建向量索引的进度
+type SemanticIndexProgress struct {
+	Done   int    `json:"done"`
+	Total  int    `json:"total"`
+	NoteID uint   `json:"noteId"`
+	Error  string `json:"error,omitempty"`
+}
+
+// RebuildSemanticIndex 为所有笔记建立向量索引，已索引且内容未变的片段不会重新计算
+// 进度通过 semantic-index-progress 事件推送，返回失败的笔记数
+func (a *App) RebuildSemanticIndex() (int, error) {
+	e, err := resolveEmbedding()
+	if err != nil {
+		return 0, err
+	}
+	var ids []uint
+	if err := DB.Model(&Note{}).Where("type <> ?", 1).Order("id asc").Pluck("id", &ids).Error; err != nil {
+		return 0, err
+	}
+	// 清理已删除笔记和其他向量模型留下的片段
+	DB.Where("note_id NOT IN (?) OR model <> ?", DB.Model(&Note{}).Select("id"), e.model).Delete(&NoteChunk{})
+
+	failed := 0
+	for i, id := range ids {
+		ctx, cancel := context.WithTimeout(context.Background(), embeddingTimeout)
+		err := indexNote(ctx, e, id)
+		cancel()
+		progress := SemanticIndexProgress{Done: i + 1, Total: len(ids), NoteID: id}
+		if err != nil {
+			failed++
+			progress.Error = err.Error()
+			log.Printf("[Embedding] 索引失败 (Note ID: %d): %v", id, err)
+		}
+		a.emit(EventSemanticIndexProgress, progress)
+	}
+	log.Printf("[Embedding] 向量索引重建完成 (笔记: %d, 失败: %d)", len(ids), failed)
+	return failed, nil
+}
+
+// SemanticIndexStatus 向量索引状态
+type SemanticIndexStatus struct {
+	Enabled bool  `json:"enabled"`
+	Notes   int64 `json:"notes"`  // 已建立索引的笔记数
+	Chunks  int64 `json:"chunks"` // 片段数
+}
+
+// GetSemanticIndexStatus 获取向量索引状态
+func (a *App) GetSemanticIndexStatus() (*SemanticIndexStatus, error) {
+	cfg := currentEmbeddingConfig()
+	status := &SemanticIndexStatus{Enabled: cfg.Enabled}
+	q := DB.Model(&NoteChunk{}).Where("model = ?", cfg.Model)
+	if err := q.Count(&status.Chunks).Error; err != nil {
+		return nil, err
+	}
+	if err := DB.Model(&NoteChunk{}).Where("model = ?", cfg.Model).Distinct("note_id").Count(&status.Notes).Error; err != nil {
+		return nil, err
+	}
+	return status, nil
+}
+
+// GetEmbeddingConfig 获取向量索引配置
+func (a *App) GetEmbeddingConfig() EmbeddingConfig {
+	return currentEmbeddingConfig()
+}
+
+// UpdateEmbeddingConfig 更新向量索引配置，更换向量模型后需要重建索引
+func (a *App) UpdateEmbeddingConfig(config EmbeddingConfig) error {
+	config.Model = strings.TrimSpace(config.Model)
+	config.APIURL = strings.TrimSpace(config.APIURL)
+	if config.Enabled && config.Model == "" {
+		return errors.New("请填写向量模型")
+	}
+	if config.TopK <= 0 {
+		config.TopK = defaultRetrievalTopK
+	}
+
+	cfgMu.Lock()
+	Cfg.Embedding = config
+	saveConfig := snapshotConfigFile()
+	filePath := configFilePath
+	cfgMu.Unlock()
+
+	if err := writeConfigFile(filePath, saveConfig); err != nil {
+		return fmt.Errorf("保存配置文件失败: %v", err)
+	}
+	log.Printf("[Config] 向量索引配置已更新: enabled=%v, provider=%s, model=%s, topK=%d", config.Enabled, config.Provider, config.Model, config.TopK)
+	return nil
+}
@@ -0,0 +1,84 @@
+package backend
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// sealedKeyPrefix 配置文件中已加密的 API Key 前缀，没有前缀的视为旧版明文
+const sealedKeyPrefix = "enc:"
+
+var (
+	secretMu  sync.Mutex
+	secretKey []byte
+)
+
+// configSecretKey 加密 API Key 使用的主密钥，与 Vault 共用，保存在系统钥匙串或 0600 密钥文件中
+// 读取 API Key 不需要生物识别认证，因此不经过 unlockVault
+func configSecretKey() ([]byte, error) {
+	secretMu.Lock()
+	defer secretMu.Unlock()
+	if secretKey == nil {
+		key, err := loadVaultKey()
+		if err != nil {
+			return nil, err
+		}
+		secretKey = key
+	}
+	return secretKey, nil
+}
+
+// sealAPIKey 加密 API Key 用于写入配置文件
+func sealAPIKey(apiKey string) (string, error) {
+	if apiKey == "" || strings.HasPrefix(apiKey, sealedKeyPrefix) {
+		return apiKey, nil
+	}
+	key, err := configSecretKey()
+	if err != nil {
+		return "", err
+	}
+	sealed, err := sealWithKey(key, []byte(apiKey))
+	if err != nil {
+		return "", err
+	}
+	return sealedKeyPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// openAPIKey 解密配置文件中的 API Key，plaintext 表示读到的是旧版明文，需要重新保存
+func openAPIKey(stored string) (apiKey string, plaintext bool, err error) {
+	encoded, ok := strings.CutPrefix(stored, sealedKeyPrefix)
+	if !ok {
+		return stored, stored != "", nil
+	}
+	sealed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", false, fmt.Errorf("API Key 格式无效: %v", err)
+	}
+	key, err := configSecretKey()
+	if err != nil {
+		return "", false, err
+	}
+	data, err := openWithKey(key, sealed)
+	if err != nil {
+		return "", false, err
+	}
+	return string(data), false, nil
+}
+
+// maskAPIKey 脱敏显示 API Key，只保留首尾少量字符，前端原样提交时表示不修改
+func maskAPIKey(apiKey string) string {
+	if apiKey == "" {
+		return ""
+	}
+	if len(apiKey) <= 12 {
+		return secretMask
+	}
+	return apiKey[:3] + secretMask + apiKey[len(apiKey)-4:]
+}
+
+// isMaskedAPIKey 判断提交的 API Key 是否为脱敏值
+func isMaskedAPIKey(apiKey string) bool {
+	return strings.Contains(apiKey, secretMask)
+}
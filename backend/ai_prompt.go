@@ -0,0 +1,217 @@
+package backend
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// CategoryPrompt 目录实际使用的系统提示词
+type CategoryPrompt struct {
+	TemplateID   uint   `json:"templateId"`   // 为 0 时使用默认系统提示词
+	TemplateName string `json:"templateName"` // 模板名称
+	FromCategory uint   `json:"fromCategory"` // 设置该模板的目录，可能是上级目录
+	Inherited    bool   `json:"inherited"`    // 是否继承自上级目录
+}
+
+// categoryPromptTemplate 沿目录的上级链查找最近设置的提示词模板，没有时返回 nil
+func categoryPromptTemplate(categoryID uint) (*PromptTemplate, uint, error) {
+	visited := map[uint]bool{}
+	for id := categoryID; id != 0 && !visited[id]; {
+		visited[id] = true
+		var cat Category
+		if err := DB.Select("id", "parent_id", "prompt_template_id").First(&cat, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, 0, nil
+			}
+			return nil, 0, err
+		}
+		if cat.PromptTemplateID != nil {
+			var tpl PromptTemplate
+			if err := DB.First(&tpl, *cat.PromptTemplateID).Error; err == nil {
+				return &tpl, cat.ID, nil
+			}
+		}
+		if cat.ParentID == nil {
+			break
+		}
+		id = *cat.ParentID
+	}
+	return nil, 0, nil
+}
+
+// systemPromptFor 对话使用的系统提示词：目录（含上级目录）设置的模板，没有时为默认系统提示词
+// 返回渲染后的提示词和模板名称，使用默认提示词时名称为空
+func systemPromptFor(categoryID uint, noteTitle string) (string, string) {
+	if categoryID == 0 || DB == nil {
+		return defaultSystemPrompt, ""
+	}
+	tpl, _, err := categoryPromptTemplate(categoryID)
+	if err != nil {
+		log.Printf("[Prompt] 查找目录提示词失败 (Category ID: %d): %v", categoryID, err)
+	}
+	if tpl == nil || strings.TrimSpace(tpl.Content) == "" {
+		return defaultSystemPrompt, ""
+	}
+	var cat Category
+	DB.Select("id", "name").First(&cat, categoryID)
+	prompt := renderActionPrompt(tpl.Content, map[string]string{
+		"selection":  "",
+		"note_title": noteTitle,
+		"category":   cat.Name,
+	})
+	return strings.TrimSpace(prompt), tpl.Name
+}
+
+// refsCategory 关联内容所在的目录，取第一个关联的目录或笔记所在目录
+func refsCategory(refs []ContextRef) (uint, string) {
+	for _, ref := range refs {
+		switch ref.Type {
+		case "category":
+			return ref.ID, ""
+		case "note":
+			var note Note
+			if err := DB.Select("id", "title", "category_id").First(&note, ref.ID).Error; err == nil {
+				return note.CategoryID, note.Title
+			}
+		}
+	}
+	return 0, ""
+}
+
+// ListPromptTemplates 获取所有提示词模板
+func (a *App) ListPromptTemplates() ([]PromptTemplate, error) {
+	var list []PromptTemplate
+	err := DB.Order("name asc").Find(&list).Error
+	return list, err
+}
+
+func validatePromptTemplate(name string, content string) (string, string, error) {
+	name = strings.TrimSpace(name)
+	content = strings.TrimSpace(content)
+	if name == "" {
+		return "", "", errors.New("模板名称不能为空")
+	}
+	if content == "" {
+		return "", "", errors.New("模板内容不能为空")
+	}
+	return name, content, nil
+}
+
+// CreatePromptTemplate 创建提示词模板
+func (a *App) CreatePromptTemplate(name string, description string, content string) (*PromptTemplate, error) {
+	name, content, err := validatePromptTemplate(name, content)
+	if err != nil {
+		return nil, err
+	}
+	var count int64
+	DB.Model(&PromptTemplate{}).Where("name = ?", name).Count(&count)
+	if count > 0 {
+		return nil, fmt.Errorf("模板名称已存在: %s", name)
+	}
+	tpl := &PromptTemplate{Name: name, Description: strings.TrimSpace(description), Content: content}
+	if err := DB.Create(tpl).Error; err != nil {
+		return nil, fmt.Errorf("创建模板失败: %v", err)
+	}
+	log.Printf("[Prompt] 模板已创建: %s (ID: %d)", tpl.Name, tpl.ID)
+	return tpl, nil
+}
+
+// UpdatePromptTemplate 修改提示词模板
+func (a *App) UpdatePromptTemplate(id uint, name string, description string, content string) error {
+	name, content, err := validatePromptTemplate(name, content)
+	if err != nil {
+		return err
+	}
+	var count int64
+	DB.Model(&PromptTemplate{}).Where("name = ? AND id <> ?", name, id).Count(&count)
+	if count > 0 {
+		return fmt.Errorf("模板名称已存在: %s", name)
+	}
+	result := DB.Model(&PromptTemplate{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"name":        name,
+		"description": strings.TrimSpace(description),
+		"content":     content,
+	})
+	if result.Error != nil {
+		return fmt.Errorf("保存模板失败: %v", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("模板不存在")
+	}
+	return nil
+}
+
+// DeletePromptTemplate 删除提示词模板，使用该模板的目录恢复为继承上级目录
+func (a *App) DeletePromptTemplate(id uint) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&Category{}).Where("prompt_template_id = ?", id).Update("prompt_template_id", nil).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&PromptTemplate{}, id).Error
+	})
+}
+
+// SetCategoryPromptTemplate 设置目录的默认系统提示词，templateID 为 nil 时继承上级目录
+func (a *App) SetCategoryPromptTemplate(categoryID uint, templateID *uint) error {
+	if templateID != nil {
+		var tpl PromptTemplate
+		if err := DB.First(&tpl, *templateID).Error; err != nil {
+			return fmt.Errorf("模板不存在: %v", err)
+		}
+	}
+	result := DB.Model(&Category{}).Where("id = ?", categoryID).Update("prompt_template_id", templateID)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("目录不存在")
+	}
+	return nil
+}
+
+// GetCategoryPrompt 获取目录实际使用的系统提示词模板，包括继承自上级目录的
+func (a *App) GetCategoryPrompt(categoryID uint) (*CategoryPrompt, error) {
+	tpl, from, err := categoryPromptTemplate(categoryID)
+	if err != nil {
+		return nil, err
+	}
+	if tpl == nil {
+		return &CategoryPrompt{}, nil
+	}
+	return &CategoryPrompt{
+		TemplateID:   tpl.ID,
+		TemplateName: tpl.Name,
+		FromCategory: from,
+		Inherited:    from != categoryID,
+	}, nil
+}
+
+// RenderPromptTemplate 渲染提示词模板，用于在对话中插入
+// noteID: 提供 {{note_title}} 和 {{category}} 的笔记，为 0 时使用 categoryID 对应的目录
+// selection: {{selection}} 的内容
+func (a *App) RenderPromptTemplate(templateID uint, noteID uint, categoryID uint, selection string) (string, error) {
+	var tpl PromptTemplate
+	if err := DB.First(&tpl, templateID).Error; err != nil {
+		return "", fmt.Errorf("模板不存在: %v", err)
+	}
+	vars := map[string]string{"selection": selection, "note_title": "", "category": ""}
+	if noteID != 0 {
+		var note Note
+		if err := DB.Select("id", "title", "category_id").First(&note, noteID).Error; err != nil {
+			return "", fmt.Errorf("笔记不存在: %v", err)
+		}
+		vars["note_title"] = note.Title
+		categoryID = note.CategoryID
+	}
+	if categoryID != 0 {
+		var cat Category
+		if err := DB.Select("id", "name").First(&cat, categoryID).Error; err == nil {
+			vars["category"] = cat.Name
+		}
+	}
+	return renderActionPrompt(tpl.Content, vars), nil
+}
@@ -0,0 +1,574 @@
+package backend
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// AI 服务商类型
+const (
+	ProviderOpenAI    = "openai"    // OpenAI 及兼容接口（含 llama.cpp server、vLLM 等）
+	ProviderAnthropic = "anthropic" // Anthropic Messages API
+	ProviderOllama    = "ollama"    // Ollama 原生 /api/chat 接口
+)
+
+// legacyProviderName 顶层 AI 配置对应的服务商名称
+const legacyProviderName = "default"
+
+// anthropicVersion Anthropic API 版本头
+const anthropicVersion = "2023-06-01"
+
+// anthropicDefaultMaxTokens Anthropic 要求必须提供 max_tokens，未配置时使用该值
+const anthropicDefaultMaxTokens = 4096
+
+// AIProvider AI 服务商配置
+type AIProvider struct {
+	Name      string `json:"name"`
+	Kind      string `json:"kind"`   // openai, anthropic, ollama
+	APIURL    string `json:"apiURL"` // 完整的接口地址，如 .../v1/chat/completions、.../v1/messages、.../api/chat
+	APIKey    string `json:"apiKey"` // 本地服务可为空
+	Model     string `json:"model"`
+	MaxTokens int    `json:"maxTokens,omitempty"` // 回复的最大 token 数，0 表示使用服务商默认值
+	// ContextTokens 模型的上下文窗口（token），0 表示使用默认值，超出时裁剪关联内容
+	ContextTokens int `json:"contextTokens,omitempty"`
+	// Vision 模型是否支持图片输入：on、off，为空时按模型名称判断
+	Vision string `json:"vision,omitempty"`
+}
+
+// chatAdapter 服务商适配器，负责构建请求和解析响应
+type chatAdapter interface {
+	newRequest(ctx context.Context, p AIProvider, messages []chatMessage, stream bool) (*http.Request, error)
+	parseResponse(body []byte) (string, error)
+	// parseUsage 解析响应中的 token 用量，服务商未返回时为零值
+	parseUsage(body []byte) tokenUsage
+	// parseStreamLine 解析流式响应中的一行，返回文本片段；done 表示流已结束
+	// 行中带有 token 用量时写入 usage
+	parseStreamLine(line string, usage *tokenUsage) (delta string, done bool, err error)
+}
+
+func adapterFor(kind string) (chatAdapter, error) {
+	switch kind {
+	case "", ProviderOpenAI:
+		return openAIAdapter{}, nil
+	case ProviderAnthropic:
+		return anthropicAdapter{}, nil
+	case ProviderOllama:
+		return ollamaAdapter{}, nil
+	}
+	return nil, fmt.Errorf("不支持的 AI 服务商类型: %s", kind)
+}
+
+func newJSONRequest(ctx context.Context, url string, body interface{}) (*http.Request, error) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("序列化请求失败: %v", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("创建请求失败: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return req, nil
+}
+
+// openAIAdapter chat completions 接口，流式响应为 SSE
+type openAIAdapter struct{}
+
+func (openAIAdapter) newRequest(ctx context.Context, p AIProvider, messages []chatMessage, stream bool) (*http.Request, error) {
+	var list []map[string]interface{}
+	for _, m := range messages {
+		list = append(list, map[string]interface{}{"role": m.Role, "content": openAIContent(m)})
+	}
+	body := map[string]interface{}{
+		"model":       p.Model,
+		"messages":    list,
+		"temperature": 0.7,
+	}
+	if p.MaxTokens > 0 {
+		body["max_tokens"] = p.MaxTokens
+	}
+	if stream {
+		body["stream"] = true
+		// 流式响应默认不含用量，需要显式要求在最后一个片段中返回
+		body["stream_options"] = map[string]interface{}{"include_usage": true}
+	}
+	req, err := newJSONRequest(ctx, p.APIURL, body)
+	if err != nil {
+		return nil, err
+	}
+	if p.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+p.APIKey)
+	}
+	if stream {
+		req.Header.Set("Accept", "text/event-stream")
+	}
+	return req, nil
+}
+
+func (openAIAdapter) parseResponse(body []byte) (string, error) {
+	var resp struct {
+		Choices []struct {
+			Message struct {
+				Content string `json:"content"`
+			} `json:"message"`
+		} `json:"choices"`
+		Error struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return "", fmt.Errorf("解析响应失败: %v", err)
+	}
+	if resp.Error.Message != "" {
+		return "", fmt.Errorf("API 错误: %s", resp.Error.Message)
+	}
+	if len(resp.Choices) == 0 {
+		return "", errors.New("API 未返回任何回复")
+	}
+	return resp.Choices[0].Message.Content, nil
+}
+
+// openAIUsage chat completions 和 embeddings 接口的用量字段
+type openAIUsage struct {
+	Usage *struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+	} `json:"usage"`
+}
+
+func (u openAIUsage) tokens() tokenUsage {
+	if u.Usage == nil {
+		return tokenUsage{}
+	}
+	return tokenUsage{Prompt: u.Usage.PromptTokens, Completion: u.Usage.CompletionTokens}
+}
+
+func (openAIAdapter) parseUsage(body []byte) tokenUsage {
+	var resp openAIUsage
+	if json.Unmarshal(body, &resp) != nil {
+		return tokenUsage{}
+	}
+	return resp.tokens()
+}
+
+func (openAIAdapter) parseStreamLine(line string, usage *tokenUsage) (string, bool, error) {
+	// SSE 中只关心 data 行，忽略注释、event 和空行
+	data, ok := strings.CutPrefix(line, "data:")
+	if !ok {
+		return "", false, nil
+	}
+	data = strings.TrimSpace(data)
+	if data == "[DONE]" {
+		return "", true, nil
+	}
+	var chunk struct {
+		Choices []struct {
+			Delta struct {
+				Content string `json:"content"`
+			} `json:"delta"`
+		} `json:"choices"`
+		Error struct {
+			Message string `json:"message"`
+		} `json:"error"`
+		openAIUsage
+	}
+	if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+		return "", false, fmt.Errorf("解析响应失败: %v", err)
+	}
+	if chunk.Error.Message != "" {
+		return "", false, fmt.Errorf("API 错误: %s", chunk.Error.Message)
+	}
+	if chunk.Usage != nil {
+		*usage = chunk.tokens()
+	}
+	var delta strings.Builder
+	for _, choice := range chunk.Choices {
+		delta.WriteString(choice.Delta.Content)
+	}
+	return delta.String(), false, nil
+}
+
+// anthropicAdapter Messages API，系统提示词为独立字段，流式响应为带类型的 SSE 事件
+type anthropicAdapter struct{}
+
+func (anthropicAdapter) newRequest(ctx context.Context, p AIProvider, messages []chatMessage, stream bool) (*http.Request, error) {
+	var system []string
+	var list []map[string]interface{}
+	for _, m := range messages {
+		if m.Role == "system" {
+			system = append(system, m.Content)
+			continue
+		}
+		list = append(list, map[string]interface{}{"role": m.Role, "content": anthropicContent(m)})
+	}
+	maxTokens := p.MaxTokens
+	if maxTokens <= 0 {
+		maxTokens = anthropicDefaultMaxTokens
+	}
+	body := map[string]interface{}{
+		"model":      p.Model,
+		"messages":   list,
+		"max_tokens": maxTokens,
+	}
+	if len(system) > 0 {
+		body["system"] = strings.Join(system, "\n\n")
+	}
+	if stream {
+		body["stream"] = true
+	}
+	req, err := newJSONRequest(ctx, p.APIURL, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("x-api-key", p.APIKey)
+	req.Header.Set("anthropic-version", anthropicVersion)
+	return req, nil
+}
+
+func (anthropicAdapter) parseResponse(body []byte) (string, error) {
+	var resp struct {
+		Content []struct {
+			Type string `json:"type"`
+			Text string `json:"text"`
+		} `json:"content"`
+		Error struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return "", fmt.Errorf("解析响应失败: %v", err)
+	}
+	if resp.Error.Message != "" {
+		return "", fmt.Errorf("API 错误: %s", resp.Error.Message)
+	}
+	var text strings.Builder
+	for _, block := range resp.Content {
+		if block.Type == "text" {
+			text.WriteString(block.Text)
+		}
+	}
+	if text.Len() == 0 {
+		return "", errors.New("API 未返回任何回复")
+	}
+	return text.String(), nil
+}
+
+// anthropicUsage Messages API 的用量字段
+type anthropicUsage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+}
+
+func (anthropicAdapter) parseUsage(body []byte) tokenUsage {
+	var resp struct {
+		Usage anthropicUsage `json:"usage"`
+	}
+	if json.Unmarshal(body, &resp) != nil {
+		return tokenUsage{}
+	}
+	return tokenUsage{Prompt: resp.Usage.InputTokens, Completion: resp.Usage.OutputTokens}
+}
+
+func (anthropicAdapter) parseStreamLine(line string, usage *tokenUsage) (string, bool, error) {
+	data, ok := strings.CutPrefix(line, "data:")
+	if !ok {
+		return "", false, nil
+	}
+	// 输入用量在 message_start 中，输出用量在 message_delta 中
+	var event struct {
+		Type  string `json:"type"`
+		Delta struct {
+			Type string `json:"type"`
+			Text string `json:"text"`
+		} `json:"delta"`
+		Message struct {
+			Usage anthropicUsage `json:"usage"`
+		} `json:"message"`
+		Usage anthropicUsage `json:"usage"`
+		Error struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal([]byte(strings.TrimSpace(data)), &event); err != nil {
+		return "", false, fmt.Errorf("解析响应失败: %v", err)
+	}
+	switch event.Type {
+	case "message_start":
+		usage.Prompt = event.Message.Usage.InputTokens
+	case "message_delta":
+		usage.Completion = event.Usage.OutputTokens
+	case "content_block_delta":
+		if event.Delta.Type == "text_delta" {
+			return event.Delta.Text, false, nil
+		}
+	case "message_stop":
+		return "", true, nil
+	case "error":
+		return "", false, fmt.Errorf("API 错误: %s", event.Error.Message)
+	}
+	return "", false, nil
+}
+
+// ollamaAdapter Ollama 原生接口，流式响应为逐行 JSON
+type ollamaAdapter struct{}
+
+func (ollamaAdapter) newRequest(ctx context.Context, p AIProvider, messages []chatMessage, stream bool) (*http.Request, error) {
+	var list []map[string]interface{}
+	for _, m := range messages {
+		msg := map[string]interface{}{"role": m.Role, "content": m.Content}
+		if len(m.Images) > 0 {
+			msg["images"] = ollamaImages(m)
+		}
+		list = append(list, msg)
+	}
+	body := map[string]interface{}{
+		"model":    p.Model,
+		"messages": list,
+		"stream":   stream, // Ollama 默认流式，需要显式关闭
+	}
+	options := map[string]interface{}{"temperature": 0.7}
+	if p.MaxTokens > 0 {
+		options["num_predict"] = p.MaxTokens
+	}
+	body["options"] = options
+	req, err := newJSONRequest(ctx, p.APIURL, body)
+	if err != nil {
+		return nil, err
+	}
+	if p.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+p.APIKey)
+	}
+	return req, nil
+}
+
+type ollamaChunk struct {
+	Message struct {
+		Content string `json:"content"`
+	} `json:"message"`
+	Done  bool   `json:"done"`
+	Error string `json:"error"`
+	// 用量只在最后一个片段中返回
+	PromptEvalCount int `json:"prompt_eval_count"`
+	EvalCount       int `json:"eval_count"`
+}
+
+func (ollamaAdapter) parseUsage(body []byte) tokenUsage {
+	var resp ollamaChunk
+	if json.Unmarshal(body, &resp) != nil {
+		return tokenUsage{}
+	}
+	return tokenUsage{Prompt: resp.PromptEvalCount, Completion: resp.EvalCount}
+}
+
+func (ollamaAdapter) parseResponse(body []byte) (string, error) {
+	var resp ollamaChunk
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return "", fmt.Errorf("解析响应失败: %v", err)
+	}
+	if resp.Error != "" {
+		return "", fmt.Errorf("API 错误: %s", resp.Error)
+	}
+	return resp.Message.Content, nil
+}
+
+func (ollamaAdapter) parseStreamLine(line string, usage *tokenUsage) (string, bool, error) {
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return "", false, nil
+	}
+	var chunk ollamaChunk
+	if err := json.Unmarshal([]byte(line), &chunk); err != nil {
+		return "", false, fmt.Errorf("解析响应失败: %v", err)
+	}
+	if chunk.Error != "" {
+		return "", false, fmt.Errorf("API 错误: %s", chunk.Error)
+	}
+	if chunk.Done {
+		*usage = tokenUsage{Prompt: chunk.PromptEvalCount, Completion: chunk.EvalCount}
+	}
+	return chunk.Message.Content, chunk.Done, nil
+}
+
+// legacyProvider 由顶层 AI 配置构成的服务商，调用方需持有 cfgMu
+func legacyProvider() AIProvider {
+	return AIProvider{
+		Name:      legacyProviderName,
+		Kind:      ProviderOpenAI,
+		APIURL:    Cfg.OpenAIAPIURL,
+		APIKey:    Cfg.OpenAIAPIKey,
+		Model:     Cfg.OpenAIModel,
+		MaxTokens: Cfg.OpenAIMaxTokens,
+
+		ContextTokens: Cfg.OpenAIContextTokens,
+		Vision:        Cfg.OpenAIVision,
+	}
+}
+
+// resolveAIProvider 按名称查找服务商，名称为空时使用默认服务商；本月费用超出预算且为 block 模式时返回错误
+func resolveAIProvider(name string) (AIProvider, error) {
+	if err := checkAIBudget(); err != nil {
+		return AIProvider{}, err
+	}
+	cfgMu.RLock()
+	defer cfgMu.RUnlock()
+
+	if name == "" {
+		name = Cfg.DefaultProvider
+	}
+	if name == "" || name == legacyProviderName {
+		p := legacyProvider()
+		if p.APIKey == "" {
+			return p, errors.New("未配置 OpenAI API Key，请在 AI 配置中设置")
+		}
+		return p, nil
+	}
+	for _, p := range Cfg.Providers {
+		if p.Name == name {
+			if p.Kind == ProviderAnthropic && p.APIKey == "" {
+				return p, fmt.Errorf("AI 服务商 %s 未配置 API Key", name)
+			}
+			return p, nil
+		}
+	}
+	return AIProvider{}, fmt.Errorf("AI 服务商不存在: %s", name)
+}
+
+// providerModel 服务商当前配置的模型，不检查预算和 API Key；服务商不存在时返回空字符串
+func providerModel(name string) string {
+	cfgMu.RLock()
+	defer cfgMu.RUnlock()
+
+	if name == "" {
+		name = Cfg.DefaultProvider
+	}
+	if name == "" || name == legacyProviderName {
+		return Cfg.OpenAIModel
+	}
+	for _, p := range Cfg.Providers {
+		if p.Name == name {
+			return p.Model
+		}
+	}
+	return ""
+}
+
+// AIProviderList 服务商列表及默认服务商
+type AIProviderList struct {
+	Providers []AIProvider `json:"providers"` // 第一项为顶层 AI 配置对应的 default
+	Default   string       `json:"default"`
+}
+
+// ListAIProviders 获取所有 AI 服务商，API Key 为脱敏后的值
+func (a *App) ListAIProviders() AIProviderList {
+	cfgMu.RLock()
+	defer cfgMu.RUnlock()
+	list := AIProviderList{
+		Providers: append([]AIProvider{legacyProvider()}, Cfg.Providers...),
+		Default:   Cfg.DefaultProvider,
+	}
+	for i := range list.Providers {
+		list.Providers[i].APIKey = maskAPIKey(list.Providers[i].APIKey)
+	}
+	if list.Default == "" {
+		list.Default = legacyProviderName
+	}
+	return list
+}
+
+// saveProviders 修改服务商配置并写入配置文件
+func saveProviders(update func() error) error {
+	cfgMu.Lock()
+	if err := update(); err != nil {
+		cfgMu.Unlock()
+		return err
+	}
+	saveConfig := snapshotConfigFile()
+	filePath := configFilePath
+	cfgMu.Unlock()
+
+	if err := writeConfigFile(filePath, saveConfig); err != nil {
+		return fmt.Errorf("保存配置文件失败: %v", err)
+	}
+	return nil
+}
+
+// SaveAIProvider 新增或按名称更新 AI 服务商，apiKey 为空或为脱敏值时保留原值
+// 名称 default 对应顶层 AI 配置，请使用 UpdateAIConfig 修改
+func (a *App) SaveAIProvider(p AIProvider) error {
+	p.Name = strings.TrimSpace(p.Name)
+	if p.Name == "" {
+		return errors.New("服务商名称不能为空")
+	}
+	if p.Name == legacyProviderName {
+		return errors.New("default 为保留名称，请在 AI 配置中修改")
+	}
+	if _, err := adapterFor(p.Kind); err != nil {
+		return err
+	}
+	if err := validateVision(p.Vision); err != nil {
+		return err
+	}
+	if p.APIURL == "" || p.Model == "" {
+		return errors.New("接口地址和模型不能为空")
+	}
+
+	err := saveProviders(func() error {
+		for i, old := range Cfg.Providers {
+			if old.Name == p.Name {
+				if p.APIKey == "" || isMaskedAPIKey(p.APIKey) {
+					p.APIKey = old.APIKey
+				}
+				Cfg.Providers[i] = p
+				return nil
+			}
+		}
+		if isMaskedAPIKey(p.APIKey) {
+			p.APIKey = ""
+		}
+		Cfg.Providers = append(Cfg.Providers, p)
+		return nil
+	})
+	if err == nil {
+		log.Printf("[Config] AI 服务商已保存: %s (%s, %s)", p.Name, p.Kind, p.Model)
+	}
+	return err
+}
+
+// DeleteAIProvider 删除 AI 服务商，删除默认服务商时恢复为 default
+func (a *App) DeleteAIProvider(name string) error {
+	return saveProviders(func() error {
+		for i, p := range Cfg.Providers {
+			if p.Name == name {
+				Cfg.Providers = append(Cfg.Providers[:i], Cfg.Providers[i+1:]...)
+				if Cfg.DefaultProvider == name {
+					Cfg.DefaultProvider = ""
+				}
+				return nil
+			}
+		}
+		return fmt.Errorf("AI 服务商不存在: %s", name)
+	})
+}
+
+// SetDefaultAIProvider 设置默认 AI 服务商
+func (a *App) SetDefaultAIProvider(name string) error {
+	return saveProviders(func() error {
+		if name == legacyProviderName {
+			Cfg.DefaultProvider = ""
+			return nil
+		}
+		for _, p := range Cfg.Providers {
+			if p.Name == name {
+				Cfg.DefaultProvider = name
+				return nil
+			}
+		}
+		return fmt.Errorf("AI 服务商不存在: %s", name)
+	})
+}
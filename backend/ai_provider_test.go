@@ -0,0 +1,395 @@
+package backend
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+var testMessages = []chatMessage{
+	{Role: "system", Content: "你是笔记助手"},
+	{Role: "user", Content: "总结这篇笔记"},
+}
+
+// decodeRequestBody 读取请求的 JSON 请求体
+func decodeRequestBody(t *testing.T, req *http.Request) map[string]interface{} {
+	t.Helper()
+	data, err := io.ReadAll(req.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var body map[string]interface{}
+	if err := json.Unmarshal(data, &body); err != nil {
+		t.Fatalf("请求体不是 JSON: %v\n%s", err, data)
+	}
+	return body
+}
+
+func TestAdapterNewRequest(t *testing.T) {
+	tests := []struct {
+		name    string
+		p       AIProvider
+		stream  bool
+		headers map[string]string
+		// body 期望的请求体字段，值为 nil 表示该字段不应存在
+		body     map[string]interface{}
+		messages int // 请求体 messages 中的消息数
+	}{
+		{
+			name:    "openai",
+			p:       AIProvider{Kind: ProviderOpenAI, APIURL: "https://api.openai.com/v1/chat/completions", APIKey: "sk-test", Model: "gpt-4o-mini", MaxTokens: 512},
+			headers: map[string]string{"Authorization": "Bearer sk-test", "Content-Type": "application/json", "Accept": ""},
+			body: map[string]interface{}{
+				"model": "gpt-4o-mini", "max_tokens": float64(512), "temperature": 0.7, "stream": nil, "stream_options": nil,
+			},
+			messages: 2,
+		},
+		{
+			name:    "openai stream without key",
+			p:       AIProvider{Kind: ProviderOpenAI, APIURL: "http://localhost:8080/v1/chat/completions", Model: "llama"},
+			stream:  true,
+			headers: map[string]string{"Authorization": "", "Accept": "text/event-stream"},
+			body: map[string]interface{}{
+				"model": "llama", "max_tokens": nil, "stream": true,
+				"stream_options": map[string]interface{}{"include_usage": true},
+			},
+			messages: 2,
+		},
+		{
+			name:    "anthropic",
+			p:       AIProvider{Kind: ProviderAnthropic, APIURL: "https://api.anthropic.com/v1/messages", APIKey: "sk-ant", Model: "claude-3-5-haiku-latest"},
+			headers: map[string]string{"x-api-key": "sk-ant", "anthropic-version": anthropicVersion, "Authorization": ""},
+			body: map[string]interface{}{
+				"model": "claude-3-5-haiku-latest", "max_tokens": float64(anthropicDefaultMaxTokens), "system": "你是笔记助手", "stream": nil,
+			},
+			messages: 1,
+		},
+		{
+			name:     "anthropic stream",
+			p:        AIProvider{Kind: ProviderAnthropic, APIURL: "https://api.anthropic.com/v1/messages", APIKey: "sk-ant", Model: "claude", MaxTokens: 1024},
+			stream:   true,
+			headers:  map[string]string{"x-api-key": "sk-ant"},
+			body:     map[string]interface{}{"max_tokens": float64(1024), "stream": true},
+			messages: 1,
+		},
+		{
+			name:    "ollama",
+			p:       AIProvider{Kind: ProviderOllama, APIURL: "http://localhost:11434/api/chat", Model: "qwen2.5", MaxTokens: 256},
+			headers: map[string]string{"Authorization": ""},
+			body: map[string]interface{}{
+				"model": "qwen2.5", "stream": false,
+				"options": map[string]interface{}{"temperature": 0.7, "num_predict": float64(256)},
+			},
+			messages: 2,
+		},
+		{
+			name:     "ollama stream",
+			p:        AIProvider{Kind: ProviderOllama, APIURL: "http://localhost:11434/api/chat", APIKey: "token", Model: "qwen2.5"},
+			stream:   true,
+			headers:  map[string]string{"Authorization": "Bearer token"},
+			body:     map[string]interface{}{"stream": true, "options": map[string]interface{}{"temperature": 0.7}},
+			messages: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			adapter, err := adapterFor(tt.p.Kind)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req, err := adapter.newRequest(context.Background(), tt.p, testMessages, tt.stream)
+			if err != nil {
+				t.Fatalf("newRequest: %v", err)
+			}
+			if req.Method != http.MethodPost || req.URL.String() != tt.p.APIURL {
+				t.Errorf("请求为 %s %s，期望 POST %s", req.Method, req.URL, tt.p.APIURL)
+			}
+			if req.GetBody == nil {
+				t.Error("请求不支持重发，doAIRequest 无法重试")
+			}
+			for k, want := range tt.headers {
+				if got := req.Header.Get(k); got != want {
+					t.Errorf("请求头 %s 为 %q，期望 %q", k, got, want)
+				}
+			}
+			body := decodeRequestBody(t, req)
+			for k, want := range tt.body {
+				got, ok := body[k]
+				if want == nil {
+					if ok {
+						t.Errorf("请求体不应包含 %s，实际为 %v", k, got)
+					}
+					continue
+				}
+				gotJSON, _ := json.Marshal(got)
+				wantJSON, _ := json.Marshal(want)
+				if string(gotJSON) != string(wantJSON) {
+					t.Errorf("请求体 %s 为 %s，期望 %s", k, gotJSON, wantJSON)
+				}
+			}
+			list, _ := body["messages"].([]interface{})
+			if len(list) != tt.messages {
+				t.Errorf("messages 有 %d 条，期望 %d 条", len(list), tt.messages)
+			}
+		})
+	}
+}
+
+func TestAdapterParseResponse(t *testing.T) {
+	tests := []struct {
+		kind    string
+		body    string
+		want    string
+		usage   tokenUsage
+		wantErr bool
+	}{
+		{
+			kind:  ProviderOpenAI,
+			body:  `{"choices":[{"message":{"role":"assistant","content":"你好"}}],"usage":{"prompt_tokens":12,"completion_tokens":3,"total_tokens":15}}`,
+			want:  "你好",
+			usage: tokenUsage{Prompt: 12, Completion: 3},
+		},
+		{kind: ProviderOpenAI, body: `{"choices":[]}`, wantErr: true},
+		{kind: ProviderOpenAI, body: `{"error":{"message":"model not found"}}`, wantErr: true},
+		{
+			kind:  ProviderAnthropic,
+			body:  `{"content":[{"type":"text","text":"第一段"},{"type":"tool_use","id":"x"},{"type":"text","text":"第二段"}],"usage":{"input_tokens":20,"output_tokens":8}}`,
+			want:  "第一段第二段",
+			usage: tokenUsage{Prompt: 20, Completion: 8},
+		},
+		{kind: ProviderAnthropic, body: `{"content":[]}`, wantErr: true},
+		{kind: ProviderAnthropic, body: `{"type":"error","error":{"type":"overloaded_error","message":"Overloaded"}}`, wantErr: true},
+		{
+			kind:  ProviderOllama,
+			body:  `{"model":"qwen2.5","message":{"role":"assistant","content":"好的"},"done":true,"prompt_eval_count":30,"eval_count":5}`,
+			want:  "好的",
+			usage: tokenUsage{Prompt: 30, Completion: 5},
+		},
+		{kind: ProviderOllama, body: `{"error":"model \"qwen\" not found"}`, wantErr: true},
+		{kind: ProviderOllama, body: `not json`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.kind, func(t *testing.T) {
+			adapter, err := adapterFor(tt.kind)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := adapter.parseResponse([]byte(tt.body))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseResponse(%s) 错误为 %v，期望出错: %v", tt.body, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("parseResponse(%s) = %q，期望 %q", tt.body, got, tt.want)
+			}
+			if tt.wantErr {
+				return
+			}
+			if usage := adapter.parseUsage([]byte(tt.body)); usage != tt.usage {
+				t.Errorf("parseUsage(%s) = %+v，期望 %+v", tt.body, usage, tt.usage)
+			}
+		})
+	}
+}
+
+// readStream 通过适配器向测试服务器发送流式请求，逐行解析响应
+func readStream(t *testing.T, kind string, response string) (string, tokenUsage, error) {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if body := decodeRequestBody(t, r); body["stream"] != true {
+			t.Errorf("流式请求的 stream 为 %v", body["stream"])
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		flusher := w.(http.Flusher)
+		for _, line := range strings.SplitAfter(response, "\n") {
+			io.WriteString(w, line)
+			flusher.Flush()
+		}
+	}))
+	defer srv.Close()
+
+	adapter, err := adapterFor(kind)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := AIProvider{Kind: kind, APIURL: srv.URL, APIKey: "key", Model: "test"}
+	req, err := adapter.newRequest(context.Background(), p, testMessages, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := doAIRequest(req)
+	if err != nil {
+		t.Fatalf("doAIRequest: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var content strings.Builder
+	var usage tokenUsage
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		delta, done, err := adapter.parseStreamLine(scanner.Text(), &usage)
+		if err != nil {
+			return content.String(), usage, err
+		}
+		content.WriteString(delta)
+		if done {
+			break
+		}
+	}
+	return content.String(), usage, scanner.Err()
+}
+
+func TestAdapterParseStream(t *testing.T) {
+	tests := []struct {
+		name     string
+		kind     string
+		response string
+		want     string
+		usage    tokenUsage
+		wantErr  bool
+	}{
+		{
+			name: "openai",
+			kind: ProviderOpenAI,
+			response: ": keep-alive\n\n" +
+				`data: {"choices":[{"delta":{"role":"assistant","content":""}}]}` + "\n\n" +
+				`data: {"choices":[{"delta":{"content":"你"}}]}` + "\n\n" +
+				`data:{"choices":[{"delta":{"content":"好"}}]}` + "\n\n" +
+				`data: {"choices":[],"usage":{"prompt_tokens":9,"completion_tokens":2}}` + "\n\n" +
+				"data: [DONE]\n\n" +
+				`data: {"choices":[{"delta":{"content":"结束后的内容"}}]}` + "\n\n",
+			want:  "你好",
+			usage: tokenUsage{Prompt: 9, Completion: 2},
+		},
+		{
+			name:     "openai error chunk",
+			kind:     ProviderOpenAI,
+			response: `data: {"choices":[{"delta":{"content":"部分"}}]}` + "\n\n" + `data: {"error":{"message":"server overloaded"}}` + "\n\n",
+			want:     "部分",
+			wantErr:  true,
+		},
+		{
+			name: "anthropic",
+			kind: ProviderAnthropic,
+			response: "event: message_start\n" +
+				`data: {"type":"message_start","message":{"id":"msg_1","usage":{"input_tokens":25,"output_tokens":1}}}` + "\n\n" +
+				"event: content_block_start\n" +
+				`data: {"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}` + "\n\n" +
+				"event: ping\n" + `data: {"type":"ping"}` + "\n\n" +
+				"event: content_block_delta\n" +
+				`data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"你"}}` + "\n\n" +
+				"event: content_block_delta\n" +
+				`data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"好"}}` + "\n\n" +
+				"event: message_delta\n" +
+				`data: {"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":6}}` + "\n\n" +
+				"event: message_stop\n" + `data: {"type":"message_stop"}` + "\n\n",
+			want:  "你好",
+			usage: tokenUsage{Prompt: 25, Completion: 6},
+		},
+		{
+			name:     "anthropic error event",
+			kind:     ProviderAnthropic,
+			response: "event: error\n" + `data: {"type":"error","error":{"type":"overloaded_error","message":"Overloaded"}}` + "\n\n",
+			wantErr:  true,
+		},
+		{
+			name: "ollama",
+			kind: ProviderOllama,
+			response: `{"message":{"role":"assistant","content":"你"},"done":false}` + "\n" +
+				`{"message":{"role":"assistant","content":"好"},"done":false}` + "\n\n" +
+				`{"message":{"role":"assistant","content":""},"done":true,"prompt_eval_count":14,"eval_count":4}` + "\n",
+			want:  "你好",
+			usage: tokenUsage{Prompt: 14, Completion: 4},
+		},
+		{
+			name:     "ollama error line",
+			kind:     ProviderOllama,
+			response: `{"error":"model runner has unexpectedly stopped"}` + "\n",
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useAIClientConfig(t, testAIClientConfig(0))
+			got, usage, err := readStream(t, tt.kind, tt.response)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("错误为 %v，期望出错: %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("回复为 %q，期望 %q", got, tt.want)
+			}
+			if !tt.wantErr && usage != tt.usage {
+				t.Errorf("用量为 %+v，期望 %+v", usage, tt.usage)
+			}
+		})
+	}
+}
+
+// useProviderConfig 在测试期间替换服务商相关的配置，结束后恢复
+func useProviderConfig(t *testing.T, apiKey string, defaultProvider string, providers []AIProvider) {
+	t.Helper()
+	cfgMu.Lock()
+	savedKey, savedURL, savedModel := Cfg.OpenAIAPIKey, Cfg.OpenAIAPIURL, Cfg.OpenAIModel
+	savedDefault, savedProviders := Cfg.DefaultProvider, Cfg.Providers
+	Cfg.OpenAIAPIKey, Cfg.OpenAIAPIURL, Cfg.OpenAIModel = apiKey, "https://api.openai.com/v1/chat/completions", "gpt-4o-mini"
+	Cfg.DefaultProvider, Cfg.Providers = defaultProvider, providers
+	cfgMu.Unlock()
+	t.Cleanup(func() {
+		cfgMu.Lock()
+		Cfg.OpenAIAPIKey, Cfg.OpenAIAPIURL, Cfg.OpenAIModel = savedKey, savedURL, savedModel
+		Cfg.DefaultProvider, Cfg.Providers = savedDefault, savedProviders
+		cfgMu.Unlock()
+	})
+}
+
+func TestResolveAIProvider(t *testing.T) {
+	providers := []AIProvider{
+		{Name: "claude", Kind: ProviderAnthropic, APIKey: "sk-ant", Model: "claude-3-5-haiku-latest"},
+		{Name: "local", Kind: ProviderOllama, Model: "qwen2.5"},
+		{Name: "claude-nokey", Kind: ProviderAnthropic, Model: "claude"},
+	}
+	tests := []struct {
+		name            string
+		apiKey          string
+		defaultProvider string
+		request         string // 单次请求指定的服务商
+		want            string // 期望使用的服务商，为空表示应出错
+		model           string
+	}{
+		{name: "legacy default", apiKey: "sk-test", want: legacyProviderName, model: "gpt-4o-mini"},
+		{name: "legacy default without key", apiKey: ""},
+		{name: "configured default", apiKey: "sk-test", defaultProvider: "claude", want: "claude", model: "claude-3-5-haiku-latest"},
+		{name: "default provider is legacy", apiKey: "sk-test", defaultProvider: legacyProviderName, want: legacyProviderName, model: "gpt-4o-mini"},
+		{name: "override default", apiKey: "sk-test", defaultProvider: "claude", request: "local", want: "local", model: "qwen2.5"},
+		{name: "override to legacy", apiKey: "sk-test", defaultProvider: "claude", request: legacyProviderName, want: legacyProviderName, model: "gpt-4o-mini"},
+		{name: "ollama without key", defaultProvider: "local", want: "local", model: "qwen2.5"},
+		{name: "anthropic without key", apiKey: "sk-test", request: "claude-nokey"},
+		{name: "unknown override", apiKey: "sk-test", defaultProvider: "claude", request: "missing"},
+		{name: "unknown default", apiKey: "sk-test", defaultProvider: "missing"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useProviderConfig(t, tt.apiKey, tt.defaultProvider, providers)
+			p, err := resolveAIProvider(tt.request)
+			if tt.want == "" {
+				if err == nil {
+					t.Fatalf("resolveAIProvider(%q) = %s，期望出错", tt.request, p.Name)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("resolveAIProvider(%q): %v", tt.request, err)
+			}
+			if p.Name != tt.want || p.Model != tt.model {
+				t.Errorf("resolveAIProvider(%q) = %s/%s，期望 %s/%s", tt.request, p.Name, p.Model, tt.want, tt.model)
+			}
+			if got := providerModel(tt.request); got != tt.model {
+				t.Errorf("providerModel(%q) = %q，期望 %q", tt.request, got, tt.model)
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package backend
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Citation 回答引用的笔记片段
+type Citation struct {
+	NoteID  uint    `json:"noteId"`
+	Title   string  `json:"title"`
+	Heading string  `json:"heading,omitempty"`
+	Score   float64 `json:"score"` // 与提问的余弦相似度
+}
+
+// retrievedChunk 检索得到的片段
+type retrievedChunk struct {
+	Citation
+	Text string
+}
+
+// cosineSimilarity 计算两个向量的余弦相似度，维度不同时返回 0
+func cosineSimilarity(a, b []float32) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+	var dot, na, nb float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(na) * math.Sqrt(nb))
+}
+
+// retrieveChunks 计算提问的向量，返回最相似的 k 个片段；exclude 中的笔记以及加密目录中的笔记不参与检索
+func retrieveChunks(ctx context.Context, query string, k int, exclude map[uint]bool) ([]retrievedChunk, error) {
+	e, err := resolveEmbedding()
+	if err != nil {
+		return nil, err
+	}
+	if k <= 0 {
+		k = currentEmbeddingConfig().TopK
+	}
+	vectors, err := e.embed(ctx, []string{truncateToTokens(query, embeddingInputTokens)})
+	if err != nil {
+		return nil, fmt.Errorf("计算提问向量失败: %v", err)
+	}
+	queryVector := vectors[0]
+
+	var chunks []NoteChunk
+	if err := DB.Where("model = ? AND dim = ?", e.model, len(queryVector)).Find(&chunks).Error; err != nil {
+		return nil, err
+	}
+	locked, err := lockedCategoryIDs()
+	if err != nil {
+		return nil, err
+	}
+	var notes []Note
+	if err := DB.Select("id", "title", "category_id").Find(&notes).Error; err != nil {
+		return nil, err
+	}
+	noteByID := map[uint]Note{}
+	for _, n := range notes {
+		noteByID[n.ID] = n
+	}
+
+	var hits []retrievedChunk
+	for _, c := range chunks {
+		note, ok := noteByID[c.NoteID]
+		if !ok || exclude[c.NoteID] || locked[note.CategoryID] {
+			continue
+		}
+		hits = append(hits, retrievedChunk{
+			Citation: Citation{NoteID: c.NoteID, Title: note.Title, Heading: c.Heading, Score: cosineSimilarity(queryVector, decodeVector(c.Vector))},
+			Text:     c.Content,
+		})
+	}
+	sort.SliceStable(hits, func(i, j int) bool { return hits[i].Score > hits[j].Score })
+	if len(hits) > k {
+		hits = hits[:k]
+	}
+	return hits, nil
+}
+
+// fitRetrieved 按相似度顺序在预算内放入检索得到的片段，返回上下文文本、报告和实际引用的片段
+func fitRetrieved(hits []retrievedChunk, budget int) ([]string, *ContextReport, []Citation) {
+	items := make([]contextItem, len(hits))
+	for i, h := range hits {
+		items[i] = contextItem{NoteID: h.NoteID, Title: h.Title, Heading: h.Heading, Label: "笔记", Text: h.Text}
+	}
+	texts, report := fitContext(items, "", contextByRank, budget)
+	citations := []Citation{}
+	for _, h := range hits {
+		for _, entry := range report.Included {
+			if entry.NoteID == h.NoteID && entry.Heading == h.Heading {
+				citations = append(citations, h.Citation)
+				break
+			}
+		}
+	}
+	return texts, report, citations
+}
+
+// mergeContextReport 将检索部分的报告并入关联内容的报告，检索部分使用的是关联内容剩余的预算
+func mergeContextReport(report, retrieved *ContextReport) {
+	report.Used += retrieved.Used
+	report.Included = append(report.Included, retrieved.Included...)
+	report.Omitted = append(report.Omitted, retrieved.Omitted...)
+}
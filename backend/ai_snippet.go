@@ -0,0 +1,128 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// EventSnippetAnalysisProgress 批量生成代码片段分析的进度，数据为 SnippetAnalysisProgress
+const EventSnippetAnalysisProgress = "snippet-analysis-progress"
+
+// SnippetAnalysisProgress 批量生成代码片段分析的进度
+type SnippetAnalysisProgress struct {
+	Done   int    `json:"done"`
+	Total  int    `json:"total"`
+	NoteID uint   `json:"noteId"`
+	Title  string `json:"title"`
+	Error  string `json:"error,omitempty"`
+}
+
+// generateSnippetAnalysis 请求模型分析笔记的代码片段并写入 Analysis
+// 只更新 analysis 列，不修改笔记的更新时间，避免批量生成后笔记列表顺序被打乱
+func generateSnippetAnalysis(p AIProvider, note Note) (string, error) {
+	if strings.TrimSpace(note.Snippet) == "" {
+		return "", errors.New("笔记没有代码片段")
+	}
+	template, err := aiActionPrompt(ActionSnippetAnalysis)
+	if err != nil {
+		return "", err
+	}
+	language := note.Language
+	if language == "" {
+		language = "text"
+	}
+	prompt := renderActionPrompt(template, map[string]string{
+		"selection":  note.Snippet,
+		"note_title": note.Title,
+		"language":   language,
+	})
+	systemPrompt, _ := systemPromptFor(note.CategoryID, note.Title)
+	messages := []chatMessage{
+		{Role: "system", Content: systemPrompt},
+		{Role: "user", Content: prompt},
+	}
+	if estimateMessagesTokens(messages) > contextBudget(p, "", nil) {
+		return "", errors.New("代码片段超出模型上下文窗口")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), aiActionTimeout)
+	defer cancel()
+	startTime := time.Now()
+	output, err := completeChat(ctx, p, messages)
+	if err != nil {
+		log.Printf("[AI Action] 代码片段分析失败 (Note ID: %d, 耗时: %v): %v", note.ID, time.Since(startTime), err)
+		return "", err
+	}
+	output = cleanActionOutput(output)
+	if output == "" {
+		return "", errors.New("AI 未返回任何内容")
+	}
+	if err := DB.Model(&Note{}).Where("id = ?", note.ID).UpdateColumn("analysis", output).Error; err != nil {
+		return "", fmt.Errorf("保存分析失败: %v", err)
+	}
+	log.Printf("[AI Action] 代码片段分析完成 (Note ID: %d, 耗时: %v, 输出长度: %d 字符)", note.ID, time.Since(startTime), len(output))
+	return output, nil
+}
+
+// GenerateSnippetAnalysis 为笔记的代码片段生成分析，保存到笔记的 Analysis 并返回
+// 已有的分析会被覆盖；provider 为空时使用默认服务商
+func (a *App) GenerateSnippetAnalysis(noteID uint, provider string) (string, error) {
+	var note Note
+	if err := DB.First(&note, noteID).Error; err != nil {
+		return "", fmt.Errorf("笔记不存在: %v", err)
+	}
+	p, err := resolveAIProvider(provider)
+	if err != nil {
+		return "", err
+	}
+	return generateSnippetAnalysis(p, note)
+}
+
+// GenerateMissingSnippetAnalyses 为所有分析为空的代码片段笔记生成分析，加密目录中的笔记不会发送给 AI
+// 进度通过 snippet-analysis-progress 事件推送，返回失败的笔记数
+// 认证失败、额度或本月预算用尽时中止并返回错误，已生成的分析会保留
+func (a *App) GenerateMissingSnippetAnalyses(provider string) (int, error) {
+	p, err := resolveAIProvider(provider)
+	if err != nil {
+		return 0, err
+	}
+	locked, err := lockedCategoryIDs()
+	if err != nil {
+		return 0, err
+	}
+	var candidates []Note
+	if err := DB.Where("snippet <> '' AND (analysis IS NULL OR analysis = '')").Order("id asc").Find(&candidates).Error; err != nil {
+		return 0, err
+	}
+	var notes []Note
+	for _, note := range candidates {
+		if strings.TrimSpace(note.Snippet) != "" && !locked[note.CategoryID] {
+			notes = append(notes, note)
+		}
+	}
+
+	failed := 0
+	for i, note := range notes {
+		if err := checkAIBudget(); err != nil {
+			log.Printf("[AI Action] 代码片段分析批量生成中止 (已处理: %d/%d): %v", i, len(notes), err)
+			return failed, err
+		}
+		progress := SnippetAnalysisProgress{Done: i + 1, Total: len(notes), NoteID: note.ID, Title: note.Title}
+		_, err := generateSnippetAnalysis(p, note)
+		if err != nil {
+			failed++
+			progress.Error = err.Error()
+		}
+		a.emit(EventSnippetAnalysisProgress, progress)
+		if kind := aiErrorKind(err); kind == AIErrAuth || kind == AIErrQuota {
+			log.Printf("[AI Action] 代码片段分析批量生成中止 (已处理: %d/%d): %v", i+1, len(notes), err)
+			return failed, err
+		}
+	}
+	log.Printf("[AI Action] 代码片段分析批量生成完成 (笔记: %d, 失败: %d)", len(notes), failed)
+	return failed, nil
+}
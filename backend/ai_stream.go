@@ -0,0 +1,189 @@
+package backend
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// AI 流式对话事件
+const (
+	EventAIChatDelta = "ai-chat-delta" // 回复片段，数据为 AIChatDelta
+	EventAIChatDone  = "ai-chat-done"  // 回复结束，数据为 AIChatDone
+)
+
+// AIChatDelta 流式回复片段
+type AIChatDelta struct {
+	RequestID string `json:"requestId"`
+	Delta     string `json:"delta"`
+}
+
+// AIChatDone 流式回复结束事件
+type AIChatDone struct {
+	RequestID string `json:"requestId"`
+	Content   string `json:"content"` // 完整回复，取消或出错时为已收到的部分
+	Canceled  bool   `json:"canceled"`
+	Error     string `json:"error,omitempty"`
+	ErrorKind string `json:"errorKind,omitempty"` // 错误类型：auth、quota、context_too_long、network、provider
+	// BudgetWarning 本月费用超出预算时的提示，仅在预算为 warn 模式时返回
+	BudgetWarning string `json:"budgetWarning,omitempty"`
+
+	ConversationID uint `json:"conversationId,omitempty"` // 通过 SendMessage 发起时所属的对话
+	MessageID      uint `json:"messageId,omitempty"`      // 保存的助手消息 ID
+
+	Context   *ContextReport   `json:"context,omitempty"`   // 本次实际放入的关联内容
+	Citations []Citation       `json:"citations,omitempty"` // 语义检索模式下引用的笔记片段
+	ToolCalls []ToolCallRecord `json:"toolCalls,omitempty"` // 工具模式下执行的工具调用
+}
+
+// streamChatCompletion 以流式方式请求服务商，逐段回调 onDelta，返回完整回复
+func streamChatCompletion(ctx context.Context, p AIProvider, messages []chatMessage, onDelta func(string)) (reply string, err error) {
+	adapter, err := adapterFor(p.Kind)
+	if err != nil {
+		return "", err
+	}
+	req, err := adapter.newRequest(ctx, p, messages, true)
+	if err != nil {
+		return "", err
+	}
+	var usage tokenUsage
+	start := time.Now()
+	defer func() {
+		// 取消时已收到的部分回复仍然计入用量
+		recordErr := err
+		if ctx.Err() != nil {
+			recordErr = ctx.Err()
+		}
+		recordAIUsage(p, p.Model, UsageSourceStream, estimateUsage(usage, messages, reply), start, recordErr)
+	}()
+	// 流式请求由 ctx 控制取消，不设置整体超时；只在收到响应前重试
+	resp, err := doAIRequest(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var content strings.Builder
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		delta, done, err := adapter.parseStreamLine(scanner.Text(), &usage)
+		if err != nil {
+			return content.String(), err
+		}
+		if delta != "" {
+			content.WriteString(delta)
+			onDelta(delta)
+		}
+		if done {
+			break
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return content.String(), &AIError{Kind: AIErrNetwork, Err: fmt.Errorf("读取响应失败: %v", err)}
+	}
+	return content.String(), nil
+}
+
+// StartChatStream 以流式方式与 AI 对话
+// 回复片段通过 ai-chat-delta 事件推送，结束时发送 ai-chat-done 事件
+// provider: AI 服务商名称，为空时使用默认服务商
+// 返回: requestID，可用于 CancelChat
+func (a *App) StartChatStream(prompt string, contextTexts []string, provider string) (string, error) {
+	p, err := resolveAIProvider(provider)
+	if err != nil {
+		return "", err
+	}
+	messages, report, _, err := fitChatMessages(context.Background(), p, defaultSystemPrompt, prompt, contextTexts, false, nil)
+	if err != nil {
+		return "", err
+	}
+	return a.startChatStream(p, messages, false, func(done *AIChatDone) {
+		done.Context = report
+	}), nil
+}
+
+// startChatStream 在后台执行流式请求，onDone 在发送结束事件前调用，可为 nil
+// tools 为 true 时改为执行工具循环，最终回复一次性通过 ai-chat-delta 推送
+func (a *App) startChatStream(p AIProvider, messages []chatMessage, tools bool, onDone func(done *AIChatDone)) string {
+	requestID := newRunID()
+	ctx, cancel := context.WithCancel(context.Background())
+	a.aiMu.Lock()
+	a.aiRequests[requestID] = cancel
+	a.aiMu.Unlock()
+
+	go func() {
+		defer cancel()
+		startTime := time.Now()
+		log.Printf("[AI Chat] 流式请求开始 (Request ID: %s, 服务商: %s, 模型: %s, 消息数: %d, 工具: %v)", requestID, p.Name, p.Model, len(messages), tools)
+
+		done := &AIChatDone{RequestID: requestID}
+		onDelta := func(delta string) {
+			a.emit(EventAIChatDelta, AIChatDelta{RequestID: requestID, Delta: delta})
+		}
+		var content string
+		var err error
+		if tools {
+			var box *noteToolbox
+			if box, err = a.newNoteToolbox(requestID); err == nil {
+				content, err = runToolLoop(ctx, p, messages, box)
+				done.ToolCalls = box.records
+			}
+			if content != "" {
+				onDelta(content)
+			}
+		} else {
+			content, err = streamChatCompletion(ctx, p, messages, onDelta)
+		}
+
+		a.aiMu.Lock()
+		delete(a.aiRequests, requestID)
+		a.aiMu.Unlock()
+
+		done.Content = content
+		if err != nil {
+			if errors.Is(ctx.Err(), context.Canceled) {
+				done.Canceled = true
+				log.Printf("[AI Chat] 流式请求已取消 (Request ID: %s, 耗时: %v)", requestID, time.Since(startTime))
+			} else {
+				done.Error = err.Error()
+				done.ErrorKind = aiErrorKind(err)
+				log.Printf("[AI Chat] 流式请求失败 (Request ID: %s, 耗时: %v): %v", requestID, time.Since(startTime), err)
+			}
+		} else {
+			log.Printf("[AI Chat] 流式请求完成 (Request ID: %s, 耗时: %v, 回复长度: %d 字符)", requestID, time.Since(startTime), len(content))
+		}
+		done.BudgetWarning = budgetWarning()
+		if onDone != nil {
+			onDone(done)
+		}
+		a.emit(EventAIChatDone, done)
+	}()
+
+	return requestID
+}
+
+// CancelChat 取消进行中的流式对话
+func (a *App) CancelChat(requestID string) error {
+	a.aiMu.Lock()
+	cancel, ok := a.aiRequests[requestID]
+	a.aiMu.Unlock()
+	if !ok {
+		return errors.New("对话请求不存在或已结束")
+	}
+	cancel()
+	return nil
+}
+
+// cancelAllChats 取消所有进行中的流式对话，应用退出时调用
+func (a *App) cancelAllChats() {
+	a.aiMu.Lock()
+	defer a.aiMu.Unlock()
+	for _, cancel := range a.aiRequests {
+		cancel()
+	}
+}
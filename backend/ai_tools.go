@@ -0,0 +1,596 @@
+package backend
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// EventAIToolConfirm 工具需要写入数据时请求用户确认，数据为 AIToolConfirm，前端通过 ConfirmAIToolCall 回复
+const EventAIToolConfirm = "ai-tool-confirm"
+
+const (
+	// maxToolSteps 一次对话中请求模型的最大轮数，防止模型反复调用工具
+	maxToolSteps = 8
+	// toolConfirmTimeout 等待用户确认的时间，超时视为拒绝
+	toolConfirmTimeout = 5 * time.Minute
+	// toolChatTimeout 工具模式下单次对话的超时时间，包含等待确认的时间
+	toolChatTimeout = 10 * time.Minute
+	// toolReadNoteTokens read_note 返回内容的 token 上限
+	toolReadNoteTokens = 6000
+	// toolSearchLimit search_notes 默认返回的笔记数
+	toolSearchLimit = 10
+)
+
+// toolSpec 提供给模型的工具定义
+type toolSpec struct {
+	Name        string
+	Description string
+	Parameters  map[string]interface{} // JSON Schema
+}
+
+// toolCall 模型发起的一次工具调用
+type toolCall struct {
+	ID        string
+	Name      string
+	Arguments json.RawMessage
+}
+
+// toolAdapter 支持工具调用的服务商适配器
+type toolAdapter interface {
+	newToolRequest(ctx context.Context, p AIProvider, messages []chatMessage, tools []toolSpec) (*http.Request, error)
+	parseToolResponse(body []byte) (content string, calls []toolCall, err error)
+}
+
+// noteTools 笔记库工具集
+var noteTools = []toolSpec{
+	{
+		Name:        "search_notes",
+		Description: "按关键字搜索笔记标题和内容，返回笔记 ID、标题、所在目录和内容开头",
+		Parameters: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"query": map[string]interface{}{"type": "string", "description": "搜索关键字"},
+				"limit": map[string]interface{}{"type": "integer", "description": "最多返回的笔记数，默认 10"},
+			},
+			"required": []string{"query"},
+		},
+	},
+	{
+		Name:        "read_note",
+		Description: "按 ID 读取笔记的完整内容",
+		Parameters: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"id": map[string]interface{}{"type": "integer", "description": "笔记 ID"},
+			},
+			"required": []string{"id"},
+		},
+	},
+	{
+		Name:        "list_categories",
+		Description: "列出所有目录，返回目录 ID、名称和上级目录 ID",
+		Parameters:  map[string]interface{}{"type": "object", "properties": map[string]interface{}{}},
+	},
+	{
+		Name:        "create_draft_note",
+		Description: "创建一篇 Markdown 笔记草稿，需要用户确认后才会创建",
+		Parameters: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"title":       map[string]interface{}{"type": "string", "description": "笔记标题"},
+				"content":     map[string]interface{}{"type": "string", "description": "Markdown 正文"},
+				"category_id": map[string]interface{}{"type": "integer", "description": "所在目录 ID，0 表示不放入目录"},
+			},
+			"required": []string{"title", "content"},
+		},
+	},
+}
+
+func (openAIAdapter) newToolRequest(ctx context.Context, p AIProvider, messages []chatMessage, tools []toolSpec) (*http.Request, error) {
+	var list []map[string]interface{}
+	for _, m := range messages {
+		msg := map[string]interface{}{"role": m.Role, "content": openAIContent(m)}
+		if len(m.ToolCalls) > 0 {
+			var calls []map[string]interface{}
+			for _, c := range m.ToolCalls {
+				calls = append(calls, map[string]interface{}{
+					"id":       c.ID,
+					"type":     "function",
+					"function": map[string]interface{}{"name": c.Name, "arguments": string(c.Arguments)},
+				})
+			}
+			msg["tool_calls"] = calls
+		}
+		if m.Role == "tool" {
+			msg["tool_call_id"] = m.ToolCallID
+		}
+		list = append(list, msg)
+	}
+	var defs []map[string]interface{}
+	for _, t := range tools {
+		defs = append(defs, map[string]interface{}{
+			"type":     "function",
+			"function": map[string]interface{}{"name": t.Name, "description": t.Description, "parameters": t.Parameters},
+		})
+	}
+	body := map[string]interface{}{
+		"model":       p.Model,
+		"messages":    list,
+		"tools":       defs,
+		"temperature": 0.7,
+	}
+	if p.MaxTokens > 0 {
+		body["max_tokens"] = p.MaxTokens
+	}
+	req, err := newJSONRequest(ctx, p.APIURL, body)
+	if err != nil {
+		return nil, err
+	}
+	if p.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+p.APIKey)
+	}
+	return req, nil
+}
+
+func (openAIAdapter) parseToolResponse(body []byte) (string, []toolCall, error) {
+	var resp struct {
+		Choices []struct {
+			Message struct {
+				Content   string `json:"content"`
+				ToolCalls []struct {
+					ID       string `json:"id"`
+					Function struct {
+						Name      string `json:"name"`
+						Arguments string `json:"arguments"`
+					} `json:"function"`
+				} `json:"tool_calls"`
+			} `json:"message"`
+		} `json:"choices"`
+		Error struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return "", nil, fmt.Errorf("解析响应失败: %v", err)
+	}
+	if resp.Error.Message != "" {
+		return "", nil, fmt.Errorf("API 错误: %s", resp.Error.Message)
+	}
+	if len(resp.Choices) == 0 {
+		return "", nil, errors.New("API 未返回任何回复")
+	}
+	msg := resp.Choices[0].Message
+	var calls []toolCall
+	for _, c := range msg.ToolCalls {
+		args := json.RawMessage(c.Function.Arguments)
+		if strings.TrimSpace(c.Function.Arguments) == "" {
+			args = json.RawMessage("{}")
+		}
+		calls = append(calls, toolCall{ID: c.ID, Name: c.Function.Name, Arguments: args})
+	}
+	return msg.Content, calls, nil
+}
+
+func (anthropicAdapter) newToolRequest(ctx context.Context, p AIProvider, messages []chatMessage, tools []toolSpec) (*http.Request, error) {
+	var system []string
+	var list []map[string]interface{}
+	for _, m := range messages {
+		switch {
+		case m.Role == "system":
+			system = append(system, m.Content)
+		case m.Role == "tool":
+			// 工具结果作为 user 消息中的 tool_result 块，连续的结果合并到同一条消息
+			block := map[string]interface{}{"type": "tool_result", "tool_use_id": m.ToolCallID, "content": m.Content}
+			if n := len(list); n > 0 && list[n-1]["role"] == "user" {
+				if blocks, ok := list[n-1]["content"].([]map[string]interface{}); ok {
+					list[n-1]["content"] = append(blocks, block)
+					continue
+				}
+			}
+			list = append(list, map[string]interface{}{"role": "user", "content": []map[string]interface{}{block}})
+		case len(m.ToolCalls) > 0:
+			var blocks []map[string]interface{}
+			if m.Content != "" {
+				blocks = append(blocks, map[string]interface{}{"type": "text", "text": m.Content})
+			}
+			for _, c := range m.ToolCalls {
+				blocks = append(blocks, map[string]interface{}{"type": "tool_use", "id": c.ID, "name": c.Name, "input": c.Arguments})
+			}
+			list = append(list, map[string]interface{}{"role": "assistant", "content": blocks})
+		default:
+			list = append(list, map[string]interface{}{"role": m.Role, "content": anthropicContent(m)})
+		}
+	}
+	var defs []map[string]interface{}
+	for _, t := range tools {
+		defs = append(defs, map[string]interface{}{"name": t.Name, "description": t.Description, "input_schema": t.Parameters})
+	}
+	maxTokens := p.MaxTokens
+	if maxTokens <= 0 {
+		maxTokens = anthropicDefaultMaxTokens
+	}
+	body := map[string]interface{}{
+		"model":      p.Model,
+		"messages":   list,
+		"tools":      defs,
+		"max_tokens": maxTokens,
+	}
+	if len(system) > 0 {
+		body["system"] = strings.Join(system, "\n\n")
+	}
+	req, err := newJSONRequest(ctx, p.APIURL, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("x-api-key", p.APIKey)
+	req.Header.Set("anthropic-version", anthropicVersion)
+	return req, nil
+}
+
+func (anthropicAdapter) parseToolResponse(body []byte) (string, []toolCall, error) {
+	var resp struct {
+		Content []struct {
+			Type  string          `json:"type"`
+			Text  string          `json:"text"`
+			ID    string          `json:"id"`
+			Name  string          `json:"name"`
+			Input json.RawMessage `json:"input"`
+		} `json:"content"`
+		Error struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return "", nil, fmt.Errorf("解析响应失败: %v", err)
+	}
+	if resp.Error.Message != "" {
+		return "", nil, fmt.Errorf("API 错误: %s", resp.Error.Message)
+	}
+	var text strings.Builder
+	var calls []toolCall
+	for _, block := range resp.Content {
+		switch block.Type {
+		case "text":
+			text.WriteString(block.Text)
+		case "tool_use":
+			calls = append(calls, toolCall{ID: block.ID, Name: block.Name, Arguments: block.Input})
+		}
+	}
+	return text.String(), calls, nil
+}
+
+func (ollamaAdapter) newToolRequest(ctx context.Context, p AIProvider, messages []chatMessage, tools []toolSpec) (*http.Request, error) {
+	var list []map[string]interface{}
+	for _, m := range messages {
+		msg := map[string]interface{}{"role": m.Role, "content": m.Content}
+		if len(m.Images) > 0 {
+			msg["images"] = ollamaImages(m)
+		}
+		if len(m.ToolCalls) > 0 {
+			var calls []map[string]interface{}
+			for _, c := range m.ToolCalls {
+				calls = append(calls, map[string]interface{}{
+					"function": map[string]interface{}{"name": c.Name, "arguments": c.Arguments},
+				})
+			}
+			msg["tool_calls"] = calls
+		}
+		list = append(list, msg)
+	}
+	var defs []map[string]interface{}
+	for _, t := range tools {
+		defs = append(defs, map[string]interface{}{
+			"type":     "function",
+			"function": map[string]interface{}{"name": t.Name, "description": t.Description, "parameters": t.Parameters},
+		})
+	}
+	options := map[string]interface{}{"temperature": 0.7}
+	if p.MaxTokens > 0 {
+		options["num_predict"] = p.MaxTokens
+	}
+	req, err := newJSONRequest(ctx, p.APIURL, map[string]interface{}{
+		"model":    p.Model,
+		"messages": list,
+		"tools":    defs,
+		"stream":   false,
+		"options":  options,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if p.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+p.APIKey)
+	}
+	return req, nil
+}
+
+func (ollamaAdapter) parseToolResponse(body []byte) (string, []toolCall, error) {
+	var resp struct {
+		Message struct {
+			Content   string `json:"content"`
+			ToolCalls []struct {
+				Function struct {
+					Name      string          `json:"name"`
+					Arguments json.RawMessage `json:"arguments"`
+				} `json:"function"`
+			} `json:"tool_calls"`
+		} `json:"message"`
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return "", nil, fmt.Errorf("解析响应失败: %v", err)
+	}
+	if resp.Error != "" {
+		return "", nil, fmt.Errorf("API 错误: %s", resp.Error)
+	}
+	// Ollama 不返回调用 ID，按序号生成
+	var calls []toolCall
+	for i, c := range resp.Message.ToolCalls {
+		calls = append(calls, toolCall{ID: fmt.Sprintf("call_%d", i), Name: c.Function.Name, Arguments: c.Function.Arguments})
+	}
+	return resp.Message.Content, calls, nil
+}
+
+// ToolCallRecord 一次工具调用的记录，随回复返回给前端
+type ToolCallRecord struct {
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
+	Result    string `json:"result,omitempty"` // 结果摘要
+	Error     string `json:"error,omitempty"`
+	Denied    bool   `json:"denied,omitempty"` // 用户拒绝了写入
+}
+
+// AIToolConfirm 写入类工具调用的确认请求
+type AIToolConfirm struct {
+	ConfirmID string `json:"confirmId"`
+	RequestID string `json:"requestId"`
+	Tool      string `json:"tool"`
+	Summary   string `json:"summary"`   // 给用户看的说明
+	Arguments string `json:"arguments"` // 原始参数
+}
+
+// noteToolbox 执行笔记库工具，加密目录中的笔记和目录对工具不可见
+type noteToolbox struct {
+	app       *App
+	requestID string
+	locked    map[uint]bool
+	records   []ToolCallRecord
+}
+
+func (a *App) newNoteToolbox(requestID string) (*noteToolbox, error) {
+	locked, err := lockedCategoryIDs()
+	if err != nil {
+		return nil, err
+	}
+	return &noteToolbox{app: a, requestID: requestID, locked: locked}, nil
+}
+
+// run 执行一次工具调用，返回给模型的结果；出错时把错误作为结果交给模型处理
+func (b *noteToolbox) run(ctx context.Context, call toolCall) string {
+	record := ToolCallRecord{Name: call.Name, Arguments: string(call.Arguments)}
+	result, err := b.exec(ctx, call)
+	if errors.Is(err, errToolDenied) {
+		record.Denied = true
+		result = `{"error":"用户拒绝了该操作"}`
+	} else if err != nil {
+		record.Error = err.Error()
+		data, _ := json.Marshal(map[string]string{"error": err.Error()})
+		result = string(data)
+	} else {
+		record.Result = truncateToTokens(result, 100)
+	}
+	b.records = append(b.records, record)
+	log.Printf("[AI Tool] %s (Request ID: %s, 结果长度: %d, 拒绝: %v, 错误: %s)", call.Name, b.requestID, len(result), record.Denied, record.Error)
+	return result
+}
+
+// errToolDenied 用户拒绝了写入类工具调用
+var errToolDenied = errors.New("用户拒绝了该操作")
+
+func (b *noteToolbox) exec(ctx context.Context, call toolCall) (string, error) {
+	var args struct {
+		Query      string `json:"query"`
+		Limit      int    `json:"limit"`
+		ID         uint   `json:"id"`
+		Title      string `json:"title"`
+		Content    string `json:"content"`
+		CategoryID uint   `json:"category_id"`
+	}
+	if len(call.Arguments) > 0 {
+		if err := json.Unmarshal(call.Arguments, &args); err != nil {
+			return "", fmt.Errorf("参数格式错误: %v", err)
+		}
+	}
+
+	switch call.Name {
+	case "search_notes":
+		notes, err := b.app.SearchNotes(args.Query)
+		if err != nil {
+			return "", err
+		}
+		limit := args.Limit
+		if limit <= 0 || limit > 50 {
+			limit = toolSearchLimit
+		}
+		type hit struct {
+			ID         uint   `json:"id"`
+			Title      string `json:"title"`
+			CategoryID uint   `json:"category_id"`
+			Preview    string `json:"preview"`
+		}
+		hits := []hit{}
+		for _, n := range notes {
+			if b.locked[n.CategoryID] || n.Type == 1 {
+				continue
+			}
+			hits = append(hits, hit{ID: n.ID, Title: n.Title, CategoryID: n.CategoryID, Preview: truncateToTokens(n.ContentMD, 80)})
+			if len(hits) >= limit {
+				break
+			}
+		}
+		return toolJSON(hits)
+
+	case "read_note":
+		var note Note
+		if err := DB.First(&note, args.ID).Error; err != nil || b.locked[note.CategoryID] {
+			// 加密目录中的笔记按不存在处理
+			return "", fmt.Errorf("笔记不存在: %d", args.ID)
+		}
+		item, ok := noteContextItem(note)
+		if !ok {
+			return "", errors.New("该笔记没有可读取的文本内容")
+		}
+		return toolJSON(map[string]interface{}{
+			"id":          note.ID,
+			"title":       note.Title,
+			"category_id": note.CategoryID,
+			"updated_at":  note.UpdatedAt.Format("2006-01-02 15:04"),
+			"content":     truncateToTokens(item.Text, toolReadNoteTokens),
+		})
+
+	case "list_categories":
+		var cats []Category
+		if err := DB.Order("name asc").Find(&cats).Error; err != nil {
+			return "", err
+		}
+		type cat struct {
+			ID       uint   `json:"id"`
+			Name     string `json:"name"`
+			ParentID *uint  `json:"parent_id"`
+		}
+		list := []cat{}
+		for _, c := range cats {
+			if !b.locked[c.ID] {
+				list = append(list, cat{ID: c.ID, Name: c.Name, ParentID: c.ParentID})
+			}
+		}
+		return toolJSON(list)
+
+	case "create_draft_note":
+		title := strings.TrimSpace(args.Title)
+		if title == "" {
+			return "", errors.New("标题不能为空")
+		}
+		if args.CategoryID != 0 {
+			var cat Category
+			if err := DB.First(&cat, args.CategoryID).Error; err != nil || b.locked[args.CategoryID] {
+				return "", fmt.Errorf("目录不存在: %d", args.CategoryID)
+			}
+		}
+		summary := fmt.Sprintf("AI 请求创建笔记「%s」（%d 字）", title, len([]rune(args.Content)))
+		ok, err := b.app.confirmToolCall(ctx, b.requestID, call, summary)
+		if err != nil {
+			return "", err
+		}
+		if !ok {
+			return "", errToolDenied
+		}
+		note, err := b.app.CreateNoteMD(title, "md", args.Content, args.CategoryID)
+		if err != nil {
+			return "", fmt.Errorf("创建笔记失败: %v", err)
+		}
+		return toolJSON(map[string]interface{}{"id": note.ID, "title": note.Title, "created": true})
+	}
+	return "", fmt.Errorf("未知的工具: %s", call.Name)
+}
+
+func toolJSON(v interface{}) (string, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// confirmToolCall 通过事件请求用户确认写入操作，等待 ConfirmAIToolCall 回复，超时或取消视为拒绝
+func (a *App) confirmToolCall(ctx context.Context, requestID string, call toolCall, summary string) (bool, error) {
+	confirmID := newRunID()
+	reply := make(chan bool, 1)
+	a.toolMu.Lock()
+	a.toolConfirms[confirmID] = reply
+	a.toolMu.Unlock()
+	defer func() {
+		a.toolMu.Lock()
+		delete(a.toolConfirms, confirmID)
+		a.toolMu.Unlock()
+	}()
+
+	a.emit(EventAIToolConfirm, AIToolConfirm{
+		ConfirmID: confirmID,
+		RequestID: requestID,
+		Tool:      call.Name,
+		Summary:   summary,
+		Arguments: string(call.Arguments),
+	})
+	select {
+	case ok := <-reply:
+		return ok, nil
+	case <-time.After(toolConfirmTimeout):
+		return false, nil
+	case <-ctx.Done():
+		return false, ctx.Err()
+	}
+}
+
+// ConfirmAIToolCall 回复工具调用的确认请求
+func (a *App) ConfirmAIToolCall(confirmID string, approved bool) error {
+	a.toolMu.Lock()
+	reply, ok := a.toolConfirms[confirmID]
+	a.toolMu.Unlock()
+	if !ok {
+		return errors.New("确认请求不存在或已超时")
+	}
+	select {
+	case reply <- approved:
+	default:
+	}
+	return nil
+}
+
+// runToolLoop 在 Go 中执行工具循环：模型请求工具时执行并返回结果，直到模型给出最终回复或达到轮数上限
+func runToolLoop(ctx context.Context, p AIProvider, messages []chatMessage, box *noteToolbox) (string, error) {
+	adapter, err := adapterFor(p.Kind)
+	if err != nil {
+		return "", err
+	}
+	tools, ok := adapter.(toolAdapter)
+	if !ok {
+		return "", fmt.Errorf("AI 服务商 %s 不支持工具调用", p.Name)
+	}
+	messages = append([]chatMessage(nil), messages...)
+	for step := 0; step < maxToolSteps; step++ {
+		req, err := tools.newToolRequest(ctx, p, messages, noteTools)
+		if err != nil {
+			return "", err
+		}
+		start := time.Now()
+		body, err := sendAIRequest(req)
+		if err != nil {
+			recordAIUsage(p, p.Model, UsageSourceTool, tokenUsage{}, start, err)
+			return "", err
+		}
+		content, calls, err := tools.parseToolResponse(body)
+		recordAIUsage(p, p.Model, UsageSourceTool, estimateUsage(adapter.parseUsage(body), messages, content), start, err)
+		if err != nil {
+			return "", err
+		}
+		if len(calls) == 0 {
+			return content, nil
+		}
+		messages = append(messages, chatMessage{Role: "assistant", Content: content, ToolCalls: calls})
+		for _, call := range calls {
+			result := box.run(ctx, call)
+			if ctx.Err() != nil {
+				return "", ctx.Err()
+			}
+			messages = append(messages, chatMessage{Role: "tool", Content: result, ToolCallID: call.ID})
+		}
+	}
+	return "", fmt.Errorf("工具调用超过 %d 轮仍未得到回复", maxToolSteps)
+}
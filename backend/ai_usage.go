@@ -0,0 +1,373 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+)
+
+// 用量记录的来源
+const (
+	UsageSourceChat      = "chat"      // 非流式对话和笔记操作
+	UsageSourceStream    = "stream"    // 流式对话
+	UsageSourceTool      = "tool"      // 工具模式中的每一轮请求
+	UsageSourceEmbedding = "embedding" // 向量索引
+)
+
+// 超出预算后的处理方式
+const (
+	BudgetWarn  = "warn"  // 仍然发送请求，在回复中提示
+	BudgetBlock = "block" // 拒绝新的请求
+)
+
+// 用量统计的分组方式
+const (
+	UsageGroupModel    = "model"
+	UsageGroupProvider = "provider"
+	UsageGroupDay      = "day"
+	UsageGroupMonth    = "month"
+	UsageGroupSource   = "source"
+	UsageGroupStatus   = "status"
+)
+
+// tokenUsage 一次请求的 token 用量
+type tokenUsage struct {
+	Prompt     int
+	Completion int
+	Estimated  bool
+}
+
+// ModelPrice 模型价格，单位为每百万 token
+// Model 以 * 结尾时按前缀匹配，如 gpt-4o*；精确匹配优先
+type ModelPrice struct {
+	Model  string  `json:"model"`
+	Input  float64 `json:"input"`
+	Output float64 `json:"output"`
+}
+
+// UsageConfig 费用估算和月度预算
+type UsageConfig struct {
+	Currency      string       `json:"currency"` // 仅用于显示，如 USD、CNY
+	Prices        []ModelPrice `json:"prices"`
+	MonthlyBudget float64      `json:"monthlyBudget"` // 每月预算，0 表示不限制
+	BudgetAction  string       `json:"budgetAction"`  // 超出预算后的处理：warn 或 block
+}
+
+func defaultUsageConfig() UsageConfig {
+	return UsageConfig{Currency: "USD", BudgetAction: BudgetWarn}
+}
+
+func currentUsageConfig() UsageConfig {
+	cfgMu.RLock()
+	defer cfgMu.RUnlock()
+	config := Cfg.Usage
+	config.Prices = append([]ModelPrice(nil), config.Prices...)
+	return config
+}
+
+// modelPrice 查找模型价格，没有配置时返回 false
+func modelPrice(prices []ModelPrice, model string) (ModelPrice, bool) {
+	var best ModelPrice
+	found := false
+	for _, p := range prices {
+		if p.Model == model {
+			return p, true
+		}
+		if prefix, ok := strings.CutSuffix(p.Model, "*"); ok && strings.HasPrefix(model, prefix) {
+			if !found || len(prefix) > len(strings.TrimSuffix(best.Model, "*")) {
+				best, found = p, true
+			}
+		}
+	}
+	return best, found
+}
+
+// usageCost 按模型价格估算费用
+func usageCost(prices []ModelPrice, model string, usage tokenUsage) float64 {
+	price, ok := modelPrice(prices, model)
+	if !ok {
+		return 0
+	}
+	return (float64(usage.Prompt)*price.Input + float64(usage.Completion)*price.Output) / 1e6
+}
+
+// estimateUsage 服务商未返回用量时按本地估算补齐
+func estimateUsage(usage tokenUsage, messages []chatMessage, reply string) tokenUsage {
+	if usage.Prompt > 0 || usage.Completion > 0 {
+		return usage
+	}
+	return tokenUsage{
+		Prompt:     estimateMessagesTokens(messages),
+		Completion: estimateTokens(reply),
+		Estimated:  true,
+	}
+}
+
+// recordAIUsage 记录一次 AI 请求的用量，失败的请求不计 token
+func recordAIUsage(p AIProvider, model string, source string, usage tokenUsage, start time.Time, err error) {
+	if DB == nil {
+		return
+	}
+	record := &AIUsage{
+		Provider:  p.Name,
+		Model:     model,
+		Source:    source,
+		LatencyMs: time.Since(start).Milliseconds(),
+		Status:    "ok",
+	}
+	switch {
+	case errors.Is(err, context.Canceled):
+		// 取消的流式请求已经产生了部分输出，用量仍然计入
+		record.Status = "canceled"
+	case err != nil:
+		record.Status = "error"
+		record.ErrorKind = aiErrorKind(err)
+		usage = tokenUsage{}
+	}
+	record.PromptTokens = usage.Prompt
+	record.CompletionTokens = usage.Completion
+	record.Estimated = usage.Estimated
+	record.Cost = usageCost(currentUsageConfig().Prices, model, usage)
+	if err := DB.Create(record).Error; err != nil {
+		log.Printf("[AI Usage] 记录用量失败: %v", err)
+	}
+}
+
+// monthStart 本月第一天的零点（本地时间）
+func monthStart(now time.Time) time.Time {
+	return time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+}
+
+// monthSpent 本月已产生的费用
+func monthSpent() (float64, error) {
+	var spent float64
+	err := DB.Model(&AIUsage{}).
+		Where("created_at >= ?", monthStart(time.Now())).
+		Select("COALESCE(SUM(cost), 0)").Scan(&spent).Error
+	return spent, err
+}
+
+// checkAIBudget 预算为 block 模式且本月费用已超出时拒绝请求
+func checkAIBudget() error {
+	config := currentUsageConfig()
+	if config.MonthlyBudget <= 0 || config.BudgetAction != BudgetBlock || DB == nil {
+		return nil
+	}
+	spent, err := monthSpent()
+	if err != nil {
+		log.Printf("[AI Usage] 统计本月费用失败: %v", err)
+		return nil
+	}
+	if spent >= config.MonthlyBudget {
+		return fmt.Errorf("本月 AI 费用 %.2f %s 已达到预算 %.2f %s，请在 AI 配置中调整预算", spent, config.Currency, config.MonthlyBudget, config.Currency)
+	}
+	return nil
+}
+
+// budgetWarning 预算为 warn 模式且本月费用已超出时返回提示，否则为空
+func budgetWarning() string {
+	config := currentUsageConfig()
+	if config.MonthlyBudget <= 0 || config.BudgetAction != BudgetWarn || DB == nil {
+		return ""
+	}
+	spent, err := monthSpent()
+	if err != nil || spent < config.MonthlyBudget {
+		return ""
+	}
+	return fmt.Sprintf("本月 AI 费用 %.2f %s 已超出预算 %.2f %s", spent, config.Currency, config.MonthlyBudget, config.Currency)
+}
+
+// AIUsageGroup 一组请求的用量汇总
+type AIUsageGroup struct {
+	Key              string  `json:"key"`
+	Requests         int     `json:"requests"`
+	Errors           int     `json:"errors"`
+	PromptTokens     int     `json:"promptTokens"`
+	CompletionTokens int     `json:"completionTokens"`
+	Cost             float64 `json:"cost"`
+	AvgLatencyMs     int64   `json:"avgLatencyMs"`
+	Estimated        bool    `json:"estimated"` // 组内有本地估算的用量
+}
+
+// AIUsageReport 用量统计结果
+type AIUsageReport struct {
+	From          string         `json:"from"`
+	To            string         `json:"to"`
+	GroupBy       string         `json:"groupBy"`
+	Currency      string         `json:"currency"`
+	Groups        []AIUsageGroup `json:"groups"`
+	Total         AIUsageGroup   `json:"total"`
+	MonthSpent    float64        `json:"monthSpent"`    // 本月已产生的费用
+	MonthlyBudget float64        `json:"monthlyBudget"` // 每月预算，0 表示不限制
+}
+
+// usageGroupKey 记录在指定分组方式下的分组键
+func usageGroupKey(u AIUsage, groupBy string) string {
+	switch groupBy {
+	case UsageGroupProvider:
+		return u.Provider
+	case UsageGroupDay:
+		return u.CreatedAt.Local().Format("2006-01-02")
+	case UsageGroupMonth:
+		return u.CreatedAt.Local().Format("2006-01")
+	case UsageGroupSource:
+		return u.Source
+	case UsageGroupStatus:
+		return u.Status
+	}
+	return u.Model
+}
+
+func (g *AIUsageGroup) add(u AIUsage, latency *int64) {
+	g.Requests++
+	if u.Status == "error" {
+		g.Errors++
+	}
+	g.PromptTokens += u.PromptTokens
+	g.CompletionTokens += u.CompletionTokens
+	g.Cost += u.Cost
+	g.Estimated = g.Estimated || u.Estimated
+	*latency += u.LatencyMs
+}
+
+// GetAIUsage 统计时间范围内的 AI 用量
+// from, to: 起止日期（YYYY-MM-DD，本地时间，包含当天），为空时不限制
+// groupBy: model、provider、day、month、source、status，为空时按模型分组
+func (a *App) GetAIUsage(from string, to string, groupBy string) (*AIUsageReport, error) {
+	switch groupBy {
+	case "":
+		groupBy = UsageGroupModel
+	case UsageGroupModel, UsageGroupProvider, UsageGroupDay, UsageGroupMonth, UsageGroupSource, UsageGroupStatus:
+	default:
+		return nil, fmt.Errorf("不支持的分组方式: %s", groupBy)
+	}
+
+	query := DB.Model(&AIUsage{})
+	if from != "" {
+		t, err := time.ParseInLocation("2006-01-02", from, time.Local)
+		if err != nil {
+			return nil, fmt.Errorf("开始日期无效: %v", err)
+		}
+		query = query.Where("created_at >= ?", t)
+	}
+	if to != "" {
+		t, err := time.ParseInLocation("2006-01-02", to, time.Local)
+		if err != nil {
+			return nil, fmt.Errorf("结束日期无效: %v", err)
+		}
+		query = query.Where("created_at < ?", t.AddDate(0, 0, 1))
+	}
+	var records []AIUsage
+	if err := query.Order("created_at asc").Find(&records).Error; err != nil {
+		return nil, fmt.Errorf("查询用量失败: %v", err)
+	}
+
+	config := currentUsageConfig()
+	report := &AIUsageReport{
+		From:          from,
+		To:            to,
+		GroupBy:       groupBy,
+		Currency:      config.Currency,
+		Groups:        []AIUsageGroup{},
+		Total:         AIUsageGroup{Key: "total"},
+		MonthlyBudget: config.MonthlyBudget,
+	}
+	groups := map[string]*AIUsageGroup{}
+	latency := map[string]*int64{}
+	var totalLatency int64
+	for _, u := range records {
+		key := usageGroupKey(u, groupBy)
+		g, ok := groups[key]
+		if !ok {
+			g = &AIUsageGroup{Key: key}
+			groups[key] = g
+			latency[key] = new(int64)
+		}
+		g.add(u, latency[key])
+		report.Total.add(u, &totalLatency)
+	}
+	for key, g := range groups {
+		g.AvgLatencyMs = *latency[key] / int64(g.Requests)
+		report.Groups = append(report.Groups, *g)
+	}
+	if report.Total.Requests > 0 {
+		report.Total.AvgLatencyMs = totalLatency / int64(report.Total.Requests)
+	}
+	// 按时间分组时按时间排序，其余按费用和请求数降序
+	sort.Slice(report.Groups, func(i, j int) bool {
+		gi, gj := report.Groups[i], report.Groups[j]
+		if groupBy == UsageGroupDay || groupBy == UsageGroupMonth {
+			return gi.Key < gj.Key
+		}
+		if gi.Cost != gj.Cost {
+			return gi.Cost > gj.Cost
+		}
+		if gi.Requests != gj.Requests {
+			return gi.Requests > gj.Requests
+		}
+		return gi.Key < gj.Key
+	})
+
+	spent, err := monthSpent()
+	if err != nil {
+		return nil, fmt.Errorf("统计本月费用失败: %v", err)
+	}
+	report.MonthSpent = spent
+	return report, nil
+}
+
+// GetUsageConfig 获取模型价格和预算配置
+func (a *App) GetUsageConfig() UsageConfig {
+	return currentUsageConfig()
+}
+
+// UpdateUsageConfig 更新模型价格和预算配置，价格只影响之后的用量记录
+func (a *App) UpdateUsageConfig(config UsageConfig) error {
+	config.Currency = strings.TrimSpace(config.Currency)
+	if config.Currency == "" {
+		config.Currency = defaultUsageConfig().Currency
+	}
+	if config.MonthlyBudget < 0 {
+		return errors.New("月度预算不能为负数")
+	}
+	switch config.BudgetAction {
+	case "":
+		config.BudgetAction = BudgetWarn
+	case BudgetWarn, BudgetBlock:
+	default:
+		return fmt.Errorf("不支持的预算处理方式: %s", config.BudgetAction)
+	}
+	prices := make([]ModelPrice, 0, len(config.Prices))
+	seen := map[string]bool{}
+	for _, p := range config.Prices {
+		p.Model = strings.TrimSpace(p.Model)
+		if p.Model == "" {
+			continue
+		}
+		if p.Input < 0 || p.Output < 0 {
+			return fmt.Errorf("模型 %s 的价格不能为负数", p.Model)
+		}
+		if seen[p.Model] {
+			return fmt.Errorf("模型 %s 的价格重复", p.Model)
+		}
+		seen[p.Model] = true
+		prices = append(prices, p)
+	}
+	config.Prices = prices
+
+	cfgMu.Lock()
+	Cfg.Usage = config
+	saveConfig := snapshotConfigFile()
+	filePath := configFilePath
+	cfgMu.Unlock()
+
+	if err := writeConfigFile(filePath, saveConfig); err != nil {
+		return fmt.Errorf("保存配置文件失败: %v", err)
+	}
+	log.Printf("[Config] 用量配置已更新: 价格 %d 条, 月度预算 %.2f %s (%s)", len(prices), config.MonthlyBudget, config.Currency, config.BudgetAction)
+	return nil
+}
@@ -0,0 +1,314 @@
+package backend
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif" // 注册 GIF 解码器
+	"image/jpeg"
+	"image/png"
+	"log"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// 模型是否支持图片输入
+const (
+	VisionAuto = ""    // 按模型名称判断
+	VisionOn   = "on"  // 支持
+	VisionOff  = "off" // 不支持
+)
+
+const (
+	// maxChatImages 一次请求最多附带的图片数
+	maxChatImages = 8
+	// maxImageSide 图片长边的像素上限，超出时按比例缩小
+	maxImageSide = 1568
+	// maxImagePixels 图片总像素上限，约为各服务商不再额外缩放的尺寸
+	maxImagePixels = 1150000
+	// maxImageBytes 编码后图片的大小上限，服务商一般限制为 5MB
+	maxImageBytes = 4 << 20
+	// imageTokenPixels 每个 token 对应的像素数，用于估算图片占用的上下文
+	imageTokenPixels = 750
+	// imageJPEGQuality 缩小后重新编码为 JPEG 时的质量
+	imageJPEGQuality = 85
+)
+
+// imageRefRegex Markdown 中引用的本地图片
+var imageRefRegex = regexp.MustCompile(`local://images/([^\s)"'<>]+)`)
+
+// visionModelHints 支持图片输入的常见模型名称片段，用于未显式配置时判断
+var visionModelHints = []string{
+	"gpt-4o", "gpt-4.1", "gpt-4-turbo", "gpt-4-vision", "gpt-5",
+	"claude-3", "claude-sonnet-4", "claude-opus-4", "claude-haiku-4",
+	"gemini", "llava", "bakllava", "vision", "-vl", "vl-", "pixtral", "minicpm-v", "moondream", "gemma3", "llama4",
+}
+
+// chatImage 随消息发送的图片
+type chatImage struct {
+	MediaType string
+	Data      string // base64
+	Tokens    int    // 估算占用的 token
+}
+
+// supportsVision 判断服务商的模型是否支持图片输入，未配置时按模型名称判断
+func supportsVision(p AIProvider) bool {
+	switch p.Vision {
+	case VisionOn:
+		return true
+	case VisionOff:
+		return false
+	}
+	model := strings.ToLower(p.Model)
+	for _, hint := range visionModelHints {
+		if strings.Contains(model, hint) {
+			return true
+		}
+	}
+	return false
+}
+
+func validateVision(v string) error {
+	switch v {
+	case VisionAuto, VisionOn, VisionOff:
+		return nil
+	}
+	return fmt.Errorf("无效的图片输入设置: %s", v)
+}
+
+// normalizeImagePath 将 local://images/ 引用或相对路径转换为图片存储目录中的相对路径
+func normalizeImagePath(ref string) (string, error) {
+	rel := strings.TrimPrefix(strings.TrimSpace(ref), "local://images/")
+	rel = filepath.Clean(filepath.FromSlash(rel))
+	if rel == "" || !filepath.IsLocal(rel) {
+		return "", fmt.Errorf("无效的图片路径: %s", ref)
+	}
+	return rel, nil
+}
+
+// imageRefs 提取文本中引用的本地图片，按出现顺序去重
+func imageRefs(texts ...string) []string {
+	var refs []string
+	seen := map[string]bool{}
+	for _, text := range texts {
+		for _, m := range imageRefRegex.FindAllStringSubmatch(text, -1) {
+			if !seen[m[1]] {
+				seen[m[1]] = true
+				refs = append(refs, m[1])
+			}
+		}
+	}
+	return refs
+}
+
+// loadChatImage 读取图片并按服务商限制缩小，过大的图片重新编码为 JPEG
+func loadChatImage(ref string) (chatImage, error) {
+	rel, err := normalizeImagePath(ref)
+	if err != nil {
+		return chatImage{}, err
+	}
+	data, err := os.ReadFile(GetImageFullPath(rel))
+	if err != nil {
+		return chatImage{}, fmt.Errorf("读取图片失败: %v", err)
+	}
+	img := chatImage{MediaType: http.DetectContentType(data)}
+
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		// 标准库无法解码的格式（如 WebP）不缩小，只检查大小
+		if img.MediaType != "image/webp" || len(data) > maxImageBytes {
+			return chatImage{}, fmt.Errorf("不支持的图片格式: %s", img.MediaType)
+		}
+		img.Data = base64.StdEncoding.EncodeToString(data)
+		img.Tokens = maxImagePixels / imageTokenPixels
+		return img, nil
+	}
+
+	w, h := fitImageSize(cfg.Width, cfg.Height)
+	img.Tokens = max(1, w*h/imageTokenPixels)
+	if w == cfg.Width && h == cfg.Height && len(data) <= maxImageBytes {
+		img.Data = base64.StdEncoding.EncodeToString(data)
+		return img, nil
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return chatImage{}, fmt.Errorf("解码图片失败: %v", err)
+	}
+	if w != cfg.Width || h != cfg.Height {
+		src = resizeImage(src, w, h)
+	}
+	var buf bytes.Buffer
+	img.MediaType = "image/png"
+	if format == "jpeg" {
+		img.MediaType = "image/jpeg"
+		err = jpeg.Encode(&buf, src, &jpeg.Options{Quality: imageJPEGQuality})
+	} else {
+		err = png.Encode(&buf, src)
+	}
+	if err == nil && buf.Len() > maxImageBytes && img.MediaType == "image/png" {
+		// 照片类图片编码为 PNG 时可能仍然过大
+		buf.Reset()
+		img.MediaType = "image/jpeg"
+		err = jpeg.Encode(&buf, src, &jpeg.Options{Quality: imageJPEGQuality})
+	}
+	if err != nil {
+		return chatImage{}, fmt.Errorf("编码图片失败: %v", err)
+	}
+	if buf.Len() > maxImageBytes {
+		return chatImage{}, errors.New("图片缩小后仍然过大")
+	}
+	img.Data = base64.StdEncoding.EncodeToString(buf.Bytes())
+	return img, nil
+}
+
+// fitImageSize 按比例缩小尺寸，使长边和总像素都不超过上限
+func fitImageSize(w, h int) (int, int) {
+	scale := 1.0
+	if long := max(w, h); long > maxImageSide {
+		scale = float64(maxImageSide) / float64(long)
+	}
+	if pixels := float64(w) * float64(h) * scale * scale; pixels > maxImagePixels {
+		scale *= math.Sqrt(maxImagePixels / pixels)
+	}
+	if scale >= 1 {
+		return w, h
+	}
+	return max(1, int(float64(w)*scale)), max(1, int(float64(h)*scale))
+}
+
+// resizeImage 按区域平均缩小图片，缩小倍数较大时也不会产生明显锯齿
+func resizeImage(src image.Image, w, h int) *image.RGBA {
+	b := src.Bounds()
+	sw, sh := b.Dx(), b.Dy()
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		y0 := b.Min.Y + y*sh/h
+		y1 := max(y0+1, b.Min.Y+(y+1)*sh/h)
+		for x := 0; x < w; x++ {
+			x0 := b.Min.X + x*sw/w
+			x1 := max(x0+1, b.Min.X+(x+1)*sw/w)
+			var r, g, bl, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := src.At(sx, sy).RGBA()
+					r, g, bl, a = r+uint64(cr), g+uint64(cg), bl+uint64(cb), a+uint64(ca)
+					n++
+				}
+			}
+			dst.SetRGBA(x, y, color.RGBA{R: uint8(r / n >> 8), G: uint8(g / n >> 8), B: uint8(bl / n >> 8), A: uint8(a / n >> 8)})
+		}
+	}
+	return dst
+}
+
+// attachImages 将图片附加到最后一条用户消息，返回实际附带和略过的图片
+// explicit 为用户明确选择的图片，模型不支持图片输入时返回错误；texts 中引用的图片只在模型支持时附带
+// 图片按 explicit、texts 的顺序加入，超出数量上限或剩余 token 预算的略过
+func attachImages(p AIProvider, messages []chatMessage, explicit []string, texts []string, budget int) (attached []string, omitted []string, err error) {
+	vision := supportsVision(p)
+	if len(explicit) > 0 && !vision {
+		return nil, nil, fmt.Errorf("模型 %s 不支持图片输入，可在 AI 服务商配置中开启图片输入", p.Model)
+	}
+	if !vision {
+		return nil, nil, nil
+	}
+	last := -1
+	for i := range messages {
+		if messages[i].Role == "user" {
+			last = i
+		}
+	}
+	if last < 0 {
+		return nil, nil, nil
+	}
+
+	var refs []string
+	for _, ref := range explicit {
+		rel, err := normalizeImagePath(ref)
+		if err != nil {
+			return nil, nil, err
+		}
+		refs = append(refs, rel)
+	}
+	for _, ref := range imageRefs(texts...) {
+		if rel, err := normalizeImagePath(ref); err == nil {
+			refs = append(refs, rel)
+		}
+	}
+	seen := map[string]bool{}
+	for _, rel := range refs {
+		key := filepath.ToSlash(rel)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		if len(messages[last].Images) >= maxChatImages {
+			omitted = append(omitted, key)
+			continue
+		}
+		img, err := loadChatImage(rel)
+		if err != nil {
+			log.Printf("[AI Vision] 略过图片 %s: %v", key, err)
+			omitted = append(omitted, key)
+			continue
+		}
+		if img.Tokens > budget {
+			omitted = append(omitted, key)
+			continue
+		}
+		budget -= img.Tokens
+		messages[last].Images = append(messages[last].Images, img)
+		attached = append(attached, key)
+	}
+	if len(attached) > 0 || len(omitted) > 0 {
+		log.Printf("[AI Vision] 附带图片 %d 张，略过 %d 张 (模型: %s)", len(attached), len(omitted), p.Model)
+	}
+	return attached, omitted, nil
+}
+
+// openAIContent OpenAI 格式的消息内容，带图片时为文本和 image_url 组成的数组
+func openAIContent(m chatMessage) interface{} {
+	if len(m.Images) == 0 {
+		return m.Content
+	}
+	parts := []map[string]interface{}{{"type": "text", "text": m.Content}}
+	for _, img := range m.Images {
+		parts = append(parts, map[string]interface{}{
+			"type":      "image_url",
+			"image_url": map[string]interface{}{"url": "data:" + img.MediaType + ";base64," + img.Data},
+		})
+	}
+	return parts
+}
+
+// anthropicContent Anthropic 格式的消息内容，带图片时图片块在文本之前
+func anthropicContent(m chatMessage) interface{} {
+	if len(m.Images) == 0 {
+		return m.Content
+	}
+	var blocks []map[string]interface{}
+	for _, img := range m.Images {
+		blocks = append(blocks, map[string]interface{}{
+			"type":   "image",
+			"source": map[string]interface{}{"type": "base64", "media_type": img.MediaType, "data": img.Data},
+		})
+	}
+	return append(blocks, map[string]interface{}{"type": "text", "text": m.Content})
+}
+
+// ollamaImages Ollama 格式的图片列表，为消息的 images 字段
+func ollamaImages(m chatMessage) []string {
+	var list []string
+	for _, img := range m.Images {
+		list = append(list, img.Data)
+	}
+	return list
+}
@@ -13,18 +13,56 @@ type App struct {
 	ctx         context.Context
 	mu          sync.Mutex
 	lastBioAuth time.Time
+	vaultKey    []byte // 解锁后缓存的主密钥
+
+	scriptMu   sync.Mutex
+	scriptRuns map[string]*scriptRun // 正在运行的脚本，key 为 runID
+
+	termMu    sync.Mutex
+	terminals map[string]*terminalSession // 终端会话，key 为 sessionID
+
+	aiMu       sync.Mutex
+	aiRequests map[string]context.CancelFunc // 进行中的流式对话，key 为 requestID
+
+	toolMu       sync.Mutex
+	toolConfirms map[string]chan bool // 等待用户确认的工具调用，key 为 confirmID
+
+	schedMu      sync.Mutex
+	schedCancel  context.CancelFunc
+	schedWake    chan struct{}
+	schedWG      sync.WaitGroup
+	schedRunning map[uint]bool // 正在运行的计划，key 为计划 ID
+
+	indexMu      sync.Mutex
+	indexPending map[uint]bool // 等待更新向量索引的笔记
+	indexRunning bool
 }
 
-func NewApp() *App { return &App{} }
+func NewApp() *App {
+	return &App{
+		scriptRuns:   map[string]*scriptRun{},
+		terminals:    map[string]*terminalSession{},
+		aiRequests:   map[string]context.CancelFunc{},
+		toolConfirms: map[string]chan bool{},
+		schedWake:    make(chan struct{}, 1),
+		schedRunning: map[uint]bool{},
+		indexPending: map[uint]bool{},
+	}
+}
 
 func (a *App) Startup(ctx context.Context) {
 	a.ctx = ctx
 	InitConfig()
 	InitDB()
 	AutoMigrate()
+	a.startScheduler()
 }
 
 func (a *App) Shutdown(ctx context.Context) {
+	a.stopScheduler()
+	a.cancelAllScripts()
+	a.closeAllTerminals()
+	a.cancelAllChats()
 	CloseDB()
 }
 
@@ -32,6 +70,14 @@ func (a *App) GetContext() context.Context {
 	return a.ctx
 }
 
+// emit 向前端发送事件，未启动时忽略
+func (a *App) emit(name string, data ...interface{}) {
+	if a.ctx == nil {
+		return
+	}
+	runtime.EventsEmit(a.ctx, name, data...)
+}
+
 // SetTheme 设置应用主题（用于更新窗口外观）
 func (a *App) SetTheme(isDark bool) {
 	if a.ctx == nil {
@@ -0,0 +1,216 @@
+package backend
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"mime"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	osruntime "runtime"
+	"strconv"
+	"strings"
+)
+
+// AddAttachment 为笔记添加附件
+// fileDataBase64: base64 编码的文件数据（可包含 data:xxx;base64, 前缀）
+func (a *App) AddAttachment(noteID uint, fileDataBase64 string, fileName string) (*Attachment, error) {
+	var note Note
+	if err := DB.First(&note, noteID).Error; err != nil {
+		return nil, fmt.Errorf("笔记不存在: %v", err)
+	}
+
+	if i := strings.Index(fileDataBase64, ";base64,"); i >= 0 && strings.HasPrefix(fileDataBase64, "data:") {
+		fileDataBase64 = fileDataBase64[i+len(";base64,"):]
+	}
+	fileData, err := base64.StdEncoding.DecodeString(fileDataBase64)
+	if err != nil {
+		log.Printf("Failed to decode base64 attachment data: %v\n", err)
+		return nil, fmt.Errorf("解码附件数据失败: %v", err)
+	}
+
+	sum := sha256.Sum256(fileData)
+	hash := hex.EncodeToString(sum[:])
+	ext := strings.ToLower(filepath.Ext(fileName))
+
+	// 相同内容的附件共用同一个文件
+	relativePath := hash + ext
+	fullPath := GetAttachmentFullPath(relativePath)
+	if _, err := os.Stat(fullPath); os.IsNotExist(err) {
+		if err := os.WriteFile(fullPath, fileData, 0644); err != nil {
+			log.Printf("Failed to save attachment file: %v\n", err)
+			return nil, fmt.Errorf("保存附件失败: %v", err)
+		}
+	}
+
+	mimeType := mime.TypeByExtension(ext)
+	if mimeType == "" {
+		mimeType = http.DetectContentType(fileData)
+	}
+
+	att := &Attachment{
+		NoteID:       noteID,
+		OriginalName: filepath.Base(fileName),
+		MimeType:     mimeType,
+		Size:         int64(len(fileData)),
+		Hash:         hash,
+		StoragePath:  relativePath,
+	}
+	if err := DB.Create(att).Error; err != nil {
+		removeAttachmentFileIfUnused(relativePath)
+		log.Printf("Failed to create attachment: %v\n", err)
+		return nil, fmt.Errorf("创建附件记录失败: %v", err)
+	}
+
+	log.Printf("Attachment added: %s (note: %d, size: %d bytes)\n", att.OriginalName, noteID, att.Size)
+	return att, nil
+}
+
+// ListAttachments 获取笔记的附件列表
+func (a *App) ListAttachments(noteID uint) ([]Attachment, error) {
+	var list []Attachment
+	err := DB.Where("note_id = ?", noteID).Order("created_at asc").Find(&list).Error
+	return list, err
+}
+
+// OpenAttachment 使用系统默认程序打开附件
+func (a *App) OpenAttachment(id uint) error {
+	fullPath, err := attachmentPath(id)
+	if err != nil {
+		return err
+	}
+
+	var cmd *exec.Cmd
+	switch osruntime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", fullPath)
+	case "windows":
+		cmd = exec.Command("cmd", "/c", "start", "", fullPath)
+	default:
+		cmd = exec.Command("xdg-open", fullPath)
+	}
+	if err := cmd.Start(); err != nil {
+		log.Printf("Failed to open attachment %d: %v\n", id, err)
+		return fmt.Errorf("打开附件失败: %v", err)
+	}
+	go cmd.Wait()
+	return nil
+}
+
+// DeleteAttachment 删除附件，文件在没有其他引用时一并删除
+func (a *App) DeleteAttachment(id uint) error {
+	var att Attachment
+	if err := DB.First(&att, id).Error; err != nil {
+		return fmt.Errorf("附件不存在: %v", err)
+	}
+	if err := DB.Delete(&Attachment{}, id).Error; err != nil {
+		return err
+	}
+	removeAttachmentFileIfUnused(att.StoragePath)
+	return nil
+}
+
+// deleteNoteAttachments 删除笔记的所有附件
+func deleteNoteAttachments(noteID uint) error {
+	var list []Attachment
+	if err := DB.Where("note_id = ?", noteID).Find(&list).Error; err != nil {
+		return err
+	}
+	if err := DB.Where("note_id = ?", noteID).Delete(&Attachment{}).Error; err != nil {
+		return err
+	}
+	for _, att := range list {
+		removeAttachmentFileIfUnused(att.StoragePath)
+	}
+	return nil
+}
+
+func removeAttachmentFileIfUnused(relativePath string) {
+	var count int64
+	DB.Model(&Attachment{}).Where("storage_path = ?", relativePath).Count(&count)
+	if count > 0 {
+		return
+	}
+	if err := os.Remove(GetAttachmentFullPath(relativePath)); err != nil && !os.IsNotExist(err) {
+		log.Printf("Failed to remove attachment file %s: %v\n", relativePath, err)
+	}
+}
+
+// attachmentPath 获取附件文件的完整路径
+func attachmentPath(id uint) (string, error) {
+	var att Attachment
+	if err := DB.First(&att, id).Error; err != nil {
+		return "", fmt.Errorf("附件不存在: %v", err)
+	}
+	fullPath := GetAttachmentFullPath(att.StoragePath)
+	if _, err := os.Stat(fullPath); os.IsNotExist(err) {
+		return "", fmt.Errorf("附件文件不存在: %s", fullPath)
+	}
+	return fullPath, nil
+}
+
+// AssetHandler 处理内嵌静态资源之外的请求
+// /local/attachments/<id> 返回附件内容，对应 Markdown 中的引用 [data.csv](local://attachments/<id>)
+type AssetHandler struct{}
+
+func NewAssetHandler() *AssetHandler { return &AssetHandler{} }
+
+func (h *AssetHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	idStr, ok := strings.CutPrefix(r.URL.Path, "/local/attachments/")
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	idStr, _, _ = strings.Cut(idStr, "/")
+	id, err := strconv.ParseUint(idStr, 10, 64)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	var att Attachment
+	if err := DB.First(&att, uint(id)).Error; err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	fullPath := GetAttachmentFullPath(att.StoragePath)
+	f, err := os.Open(fullPath)
+	if err != nil {
+		log.Printf("[AssetHandler] 打开附件失败 id=%d: %v", id, err)
+		http.Error(w, "附件文件不存在", http.StatusNotFound)
+		return
+	}
+	defer f.Close()
+	stat, err := f.Stat()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// 附件与应用同源，HTML、SVG 等能执行脚本的内容一律作为下载返回，并禁止浏览器猜测类型
+	disposition, contentType := "attachment", "application/octet-stream"
+	if inlineAttachmentType(att.MimeType) {
+		disposition, contentType = "inline", att.MimeType
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Content-Security-Policy", "sandbox")
+	w.Header().Set("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{"filename": att.OriginalName}))
+	http.ServeContent(w, r, att.OriginalName, stat.ModTime(), f)
+}
+
+// inlineAttachmentType 判断附件能否在应用内直接显示，只允许 PDF 和 SVG 以外的图片
+func inlineAttachmentType(mimeType string) bool {
+	mediaType, _, err := mime.ParseMediaType(mimeType)
+	if err != nil {
+		return false
+	}
+	if mediaType == "application/pdf" {
+		return true
+	}
+	return strings.HasPrefix(mediaType, "image/") && mediaType != "image/svg+xml"
+}
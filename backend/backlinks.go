@@ -0,0 +1,93 @@
+package backend
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// noteLinkRegex Markdown 中指向其他笔记的链接 note://<id>
+var noteLinkRegex = regexp.MustCompile(`note://(\d+)`)
+
+// backlinkExcerptRunes 反向链接摘要的最大字符数
+const backlinkExcerptRunes = 120
+
+// NoteBacklink 链接到某篇笔记的另一篇笔记
+type NoteBacklink struct {
+	NoteID     uint      `json:"noteId"`
+	Title      string    `json:"title"`
+	CategoryID uint      `json:"categoryId"`
+	FromAI     bool      `json:"fromAi"`  // 是否为由 AI 回答保存的笔记
+	Excerpt    string    `json:"excerpt"` // AI 回答的开头，或普通笔记中链接所在的行
+	UpdatedAt  time.Time `json:"updatedAt"`
+}
+
+// noteLink 指向笔记的 Markdown 链接，标题中的方括号会被转义
+func noteLink(noteID uint, title string) string {
+	title = strings.NewReplacer(`\`, `\\`, "[", `\[`, "]", `\]`).Replace(title)
+	return fmt.Sprintf("[%s](note://%d)", title, noteID)
+}
+
+// linksTo 判断内容中是否有指向 noteID 的链接，排除 note://12 匹配 note://123 的情况
+func linksTo(content string, noteID uint) bool {
+	for _, m := range noteLinkRegex.FindAllStringSubmatch(content, -1) {
+		if id, err := strconv.ParseUint(m[1], 10, 64); err == nil && uint(id) == noteID {
+			return true
+		}
+	}
+	return false
+}
+
+// truncateRunes 截断为最多 n 个字符，多行内容合并为一行
+func truncateRunes(text string, n int) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if utf8.RuneCountInString(text) > n {
+		text = string([]rune(text)[:n]) + "..."
+	}
+	return text
+}
+
+// backlinkExcerpt 反向链接的摘要：AI 回答笔记取回答部分的开头，其他笔记取链接所在的行
+func backlinkExcerpt(note Note, noteID uint) string {
+	if note.SourceMessage != 0 {
+		if _, answer, ok := strings.Cut(note.ContentMD, "\n"+answerHeading+"\n"); ok {
+			answer, _, _ = strings.Cut(answer, "\n"+sourcesHeading+"\n")
+			return truncateRunes(answer, backlinkExcerptRunes)
+		}
+	}
+	for _, line := range strings.Split(note.ContentMD, "\n") {
+		if linksTo(line, noteID) {
+			return truncateRunes(line, backlinkExcerptRunes)
+		}
+	}
+	return ""
+}
+
+// GetNoteBacklinks 获取链接到该笔记的其他笔记，按更新时间倒序
+func (a *App) GetNoteBacklinks(noteID uint) ([]NoteBacklink, error) {
+	var candidates []Note
+	err := DB.Select("id", "title", "category_id", "content_md", "source_message", "updated_at").
+		Where("id <> ? AND content_md LIKE ?", noteID, fmt.Sprintf("%%note://%d%%", noteID)).
+		Order("updated_at desc").Find(&candidates).Error
+	if err != nil {
+		return nil, err
+	}
+	list := []NoteBacklink{}
+	for _, note := range candidates {
+		if !linksTo(note.ContentMD, noteID) {
+			continue
+		}
+		list = append(list, NoteBacklink{
+			NoteID:     note.ID,
+			Title:      note.Title,
+			CategoryID: note.CategoryID,
+			FromAI:     note.SourceMessage != 0,
+			Excerpt:    backlinkExcerpt(note, noteID),
+			UpdatedAt:  note.UpdatedAt,
+		})
+	}
+	return list, nil
+}
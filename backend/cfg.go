@@ -2,7 +2,9 @@ package backend
 
 import (
 	"encoding/json"
+	"fmt"
 	"log"
+	"maps"
 	"os"
 	"path/filepath"
 	"sync"
@@ -11,24 +13,143 @@ import (
 var (
 	cfgMu sync.RWMutex
 	Cfg   = struct {
-		DB_PATH      string
-		OpenAIAPIKey string
-		OpenAIAPIURL string
-		OpenAIModel  string
+		DB_PATH             string
+		OpenAIAPIKey        string
+		OpenAIAPIURL        string
+		OpenAIModel         string
+		OpenAIMaxTokens     int
+		OpenAIContextTokens int
+		OpenAIVision        string
+		ContextStrategy     string
+		Providers           []AIProvider
+		DefaultProvider     string
+		Interpreters        map[string]Interpreter
+		ScriptPolicy        ScriptPolicy
+		Embedding           EmbeddingConfig
+		AIClient            AIClientConfig
+		Usage               UsageConfig
+		AIActionPrompts     map[string]string // 自定义的 AI 笔记操作提示词，key 为操作名
+		PrivacyMode         bool              // 隐私模式，AI 请求日志只记录长度和耗时，不记录提示词和回复内容
 	}{
 		DB_PATH:      "eaiser.db",
 		OpenAIAPIKey: "",
 		OpenAIAPIURL: "https://api.xiaomimimo.com/v1/chat/completions",
 		OpenAIModel:  "mimo-v2-flash",
+		Interpreters: defaultInterpreters(),
+		ScriptPolicy: defaultScriptPolicy(),
+		Embedding:    defaultEmbeddingConfig(),
+		AIClient:     defaultAIClientConfig(),
+		Usage:        defaultUsageConfig(),
+		PrivacyMode:  true,
 	}
 	configFilePath string
+	// configKeyErr 配置文件中已加密的 API Key 无法解密时的错误
+	// 此时内存中的 Key 为空，保存配置会覆盖加密的 Key，因此禁止保存，直到问题解决后重新启动
+	configKeyErr error
 )
 
 // AIConfig AI 配置结构
 type AIConfig struct {
+	// APIKey 配置文件中为加密后的值，GetAIConfig 返回脱敏后的值
 	APIKey string `json:"apiKey"`
 	APIURL string `json:"apiURL"`
 	Model  string `json:"model"`
+	// MaxTokens 回复的最大 token 数，0 表示不限制，由服务端决定
+	MaxTokens int `json:"maxTokens,omitempty"`
+	// ContextTokens 模型的上下文窗口（token），0 表示使用默认值
+	ContextTokens int `json:"contextTokens,omitempty"`
+	// ContextStrategy 关联内容超出上下文窗口时的裁剪策略：relevance 或 recency
+	ContextStrategy string `json:"contextStrategy,omitempty"`
+	// Vision 模型是否支持图片输入：on、off，为空时按模型名称判断
+	Vision string `json:"vision,omitempty"`
+	// PrivacyMode 隐私模式，未设置时默认开启；UpdateAIConfig 中为 nil 表示不修改
+	PrivacyMode *bool `json:"privacyMode,omitempty"`
+}
+
+// configFile 配置文件结构，AI 配置字段保持在顶层以兼容旧配置文件
+type configFile struct {
+	AIConfig
+	Interpreters map[string]Interpreter `json:"interpreters,omitempty"`
+	ScriptPolicy *ScriptPolicy          `json:"scriptPolicy,omitempty"`
+	// Providers 额外的 AI 服务商，顶层 AI 配置作为名为 default 的服务商
+	Providers       []AIProvider      `json:"providers,omitempty"`
+	DefaultProvider string            `json:"defaultProvider,omitempty"`
+	Embedding       *EmbeddingConfig  `json:"embedding,omitempty"`
+	AIClient        *AIClientConfig   `json:"aiClient,omitempty"`
+	Usage           *UsageConfig      `json:"usage,omitempty"`
+	AIActionPrompts map[string]string `json:"aiActionPrompts,omitempty"`
+
+	keyErr error // 生成快照时的 configKeyErr，不为空时 writeConfigFile 拒绝写入
+}
+
+// snapshotConfigFile 生成当前配置的文件结构，调用方需持有 cfgMu
+func snapshotConfigFile() configFile {
+	policy := Cfg.ScriptPolicy
+	embedding := Cfg.Embedding
+	client := Cfg.AIClient
+	usage := Cfg.Usage
+	usage.Prices = append([]ModelPrice(nil), usage.Prices...)
+	privacy := Cfg.PrivacyMode
+	return configFile{
+		AIConfig: AIConfig{
+			APIKey:    Cfg.OpenAIAPIKey,
+			APIURL:    Cfg.OpenAIAPIURL,
+			Model:     Cfg.OpenAIModel,
+			MaxTokens: Cfg.OpenAIMaxTokens,
+
+			ContextTokens:   Cfg.OpenAIContextTokens,
+			ContextStrategy: Cfg.ContextStrategy,
+			Vision:          Cfg.OpenAIVision,
+			PrivacyMode:     &privacy,
+		},
+		Interpreters:    copyInterpreters(Cfg.Interpreters),
+		ScriptPolicy:    &policy,
+		Providers:       append([]AIProvider(nil), Cfg.Providers...),
+		DefaultProvider: Cfg.DefaultProvider,
+		Embedding:       &embedding,
+		AIClient:        &client,
+		Usage:           &usage,
+		AIActionPrompts: maps.Clone(Cfg.AIActionPrompts),
+		keyErr:          configKeyErr,
+	}
+}
+
+// writeConfigFile 将配置写入文件，API Key 加密后写入，文件权限为 0600
+// 配置文件中的 API Key 无法解密时拒绝写入，避免覆盖加密的 Key
+func writeConfigFile(filePath string, config configFile) error {
+	if config.keyErr != nil {
+		return fmt.Errorf("配置文件中的 API Key 无法解密，为避免覆盖已加密的 Key，暂不保存配置: %v", config.keyErr)
+	}
+	var err error
+	if config.APIKey, err = sealAPIKey(config.APIKey); err != nil {
+		log.Printf("Failed to seal API key: %v\n", err)
+		return fmt.Errorf("加密 API Key 失败: %v", err)
+	}
+	providers := make([]AIProvider, len(config.Providers))
+	for i, p := range config.Providers {
+		if p.APIKey, err = sealAPIKey(p.APIKey); err != nil {
+			log.Printf("Failed to seal API key: %v\n", err)
+			return fmt.Errorf("加密 API Key 失败: %v", err)
+		}
+		providers[i] = p
+	}
+	config.Providers = providers
+
+	data, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		log.Printf("Failed to marshal config: %v\n", err)
+		return err
+	}
+
+	if err := os.WriteFile(filePath, data, 0600); err != nil {
+		log.Printf("Failed to save config: %v\n", err)
+		return err
+	}
+	// WriteFile 不会修改已存在文件的权限，旧版本创建的配置文件为 0644
+	if err := os.Chmod(filePath, 0600); err != nil {
+		log.Printf("Failed to chmod config: %v\n", err)
+	}
+	return nil
 }
 
 // InitConfig 初始化配置，从配置文件读取
@@ -66,21 +187,76 @@ func LoadConfig() error {
 		return err
 	}
 
-	var config AIConfig
+	// 旧配置文件中没有的策略字段保持默认值
+	policy := defaultScriptPolicy()
+	embedding := defaultEmbeddingConfig()
+	client := defaultAIClientConfig()
+	usage := defaultUsageConfig()
+	config := configFile{ScriptPolicy: &policy, Embedding: &embedding, AIClient: &client, Usage: &usage}
 	if err := json.Unmarshal(data, &config); err != nil {
 		log.Printf("Failed to unmarshal config: %v\n", err)
 		return err
 	}
 
-	Cfg.OpenAIAPIKey = config.APIKey
+	// 旧版配置文件中的明文 API Key 读取后立即加密保存
+	configKeyErr = nil
+	apiKey, migrate, err := openAPIKey(config.APIKey)
+	if err != nil {
+		log.Printf("Failed to open API key: %v\n", err)
+		configKeyErr = err
+	}
+	Cfg.OpenAIAPIKey = apiKey
+	for i, p := range config.Providers {
+		apiKey, plaintext, err := openAPIKey(p.APIKey)
+		if err != nil {
+			log.Printf("Failed to open API key of provider %s: %v\n", p.Name, err)
+			if configKeyErr == nil {
+				configKeyErr = fmt.Errorf("服务商 %s: %v", p.Name, err)
+			}
+		}
+		config.Providers[i].APIKey = apiKey
+		migrate = migrate || plaintext
+	}
 	if config.APIURL != "" {
 		Cfg.OpenAIAPIURL = config.APIURL
 	}
 	if config.Model != "" {
 		Cfg.OpenAIModel = config.Model
 	}
+	Cfg.OpenAIMaxTokens = config.MaxTokens
+	Cfg.OpenAIContextTokens = config.ContextTokens
+	Cfg.ContextStrategy = config.ContextStrategy
+	Cfg.OpenAIVision = config.Vision
+	for lang, interp := range config.Interpreters {
+		Cfg.Interpreters[lang] = interp
+	}
+	Cfg.Providers = config.Providers
+	Cfg.DefaultProvider = config.DefaultProvider
+	if config.ScriptPolicy != nil {
+		Cfg.ScriptPolicy = *config.ScriptPolicy
+	}
+	if config.Embedding != nil {
+		Cfg.Embedding = *config.Embedding
+	}
+	if config.AIClient != nil {
+		Cfg.AIClient = *config.AIClient
+	}
+	if config.Usage != nil {
+		Cfg.Usage = *config.Usage
+	}
+	Cfg.AIActionPrompts = config.AIActionPrompts
+	if config.PrivacyMode != nil {
+		Cfg.PrivacyMode = *config.PrivacyMode
+	}
 
 	log.Printf("Config loaded from: %s\n", configFilePath)
+	if migrate {
+		if err := writeConfigFile(configFilePath, snapshotConfigFile()); err != nil {
+			log.Printf("Failed to migrate plaintext API key: %v\n", err)
+		} else {
+			log.Printf("Plaintext API key encrypted in: %s\n", configFilePath)
+		}
+	}
 	return nil
 }
 
@@ -89,20 +265,7 @@ func SaveConfig() error {
 	cfgMu.Lock()
 	defer cfgMu.Unlock()
 
-	config := AIConfig{
-		APIKey: Cfg.OpenAIAPIKey,
-		APIURL: Cfg.OpenAIAPIURL,
-		Model:  Cfg.OpenAIModel,
-	}
-
-	data, err := json.MarshalIndent(config, "", "  ")
-	if err != nil {
-		log.Printf("Failed to marshal config: %v\n", err)
-		return err
-	}
-
-	if err := os.WriteFile(configFilePath, data, 0644); err != nil {
-		log.Printf("Failed to save config: %v\n", err)
+	if err := writeConfigFile(configFilePath, snapshotConfigFile()); err != nil {
 		return err
 	}
 
@@ -110,6 +273,23 @@ func SaveConfig() error {
 	return nil
 }
 
+// ConfigKeyError 配置文件中的 API Key 无法解密时的错误信息，正常时为空
+func ConfigKeyError() string {
+	cfgMu.RLock()
+	defer cfgMu.RUnlock()
+	if configKeyErr == nil {
+		return ""
+	}
+	return configKeyErr.Error()
+}
+
+// privacyMode 是否处于隐私模式
+func privacyMode() bool {
+	cfgMu.RLock()
+	defer cfgMu.RUnlock()
+	return Cfg.PrivacyMode
+}
+
 // GetConfigFilePath 获取配置文件路径
 func GetConfigFilePath() string {
 	return configFilePath
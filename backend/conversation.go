@@ -0,0 +1,270 @@
+package backend
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// conversationHistoryShare 历史消息最多占用上下文预算的比例（1/N），其余留给关联内容
+const conversationHistoryShare = 2
+
+// SendMessageResult SendMessage 的返回值
+type SendMessageResult struct {
+	ConversationID uint         `json:"conversationId"`
+	RequestID      string       `json:"requestId"`
+	UserMessage    *ChatMessage `json:"userMessage"`
+	// Context 本次实际放入的关联内容，超出模型上下文窗口的部分列在 Omitted 中
+	Context *ContextReport `json:"context"`
+	// PromptTemplate 本次使用的目录系统提示词模板名称，使用默认提示词时为空
+	PromptTemplate string `json:"promptTemplate,omitempty"`
+}
+
+// conversationTitle 以首条提问生成对话标题
+func conversationTitle(prompt string) string {
+	title := strings.Join(strings.Fields(prompt), " ")
+	if utf8.RuneCountInString(title) > 30 {
+		title = string([]rune(title)[:30]) + "..."
+	}
+	if title == "" {
+		title = "新对话"
+	}
+	return title
+}
+
+// conversationHistory 从最新的消息开始回放历史，直到达到 token 上限
+func conversationHistory(history []ChatMessage, budget int) []chatMessage {
+	var list []chatMessage
+	used := 0
+	for i := len(history) - 1; i >= 0; i-- {
+		m := history[i]
+		size := estimateTokens(m.Content) + messageOverheadTokens
+		if used+size > budget {
+			break
+		}
+		used += size
+		list = append(list, chatMessage{Role: m.Role, Content: m.Content})
+	}
+	// 回放需要按时间正序，且不能以助手消息开头
+	for i, j := 0, len(list)-1; i < j; i, j = i+1, j-1 {
+		list[i], list[j] = list[j], list[i]
+	}
+	for len(list) > 0 && list[0].Role != "user" {
+		list = list[1:]
+	}
+	return list
+}
+
+// SendMessage 在对话中发送消息，回放之前的对话并以流式方式返回回复
+// conversationID 为 0 时创建新对话；对话中关联过的目录和笔记在后续提问中继续作为上下文
+// opts.Provider: 本次使用的 AI 服务商，非空时同时记为对话的服务商；为空时沿用对话的服务商或默认服务商
+// opts.Retrieval: 是否自动检索与提问最相似的笔记片段作为上下文，引用的片段随助手消息保存
+// opts.Tools: 是否允许 AI 调用笔记库工具，写入操作需要用户通过 ai-tool-confirm 事件确认
+// opts.CategoryID: 对话所在的目录，使用其（含上级目录）设置的系统提示词模板；为 0 时按关联内容所在的目录
+// opts.Images: 随本轮提问发送的图片，保存在用户消息中；模型支持图片输入时提问和关联内容中引用的图片也会附带
+// 回复通过 ai-chat-delta / ai-chat-done 事件推送，结束后保存为助手消息
+func (a *App) SendMessage(conversationID uint, prompt string, contextRefs []ContextRef, opts ChatOptions) (*SendMessageResult, error) {
+	prompt = strings.TrimSpace(prompt)
+	if prompt == "" && len(contextRefs) == 0 && len(opts.Images) == 0 {
+		return nil, errors.New("消息内容为空")
+	}
+	if prompt == "" && len(contextRefs) == 0 {
+		prompt = "请描述图片的内容"
+	} else if prompt == "" {
+		prompt = "请分析关联的内容"
+	}
+
+	var conv Conversation
+	if conversationID != 0 {
+		if err := DB.First(&conv, conversationID).Error; err != nil {
+			return nil, fmt.Errorf("对话不存在: %v", err)
+		}
+	}
+	provider := opts.Provider
+	if provider == "" {
+		provider = conv.Provider
+	}
+	p, err := resolveAIProvider(provider)
+	if err != nil {
+		return nil, err
+	}
+	if conversationID == 0 {
+		conv.Title = conversationTitle(prompt)
+		conv.Provider = provider
+		if err := DB.Create(&conv).Error; err != nil {
+			return nil, fmt.Errorf("创建对话失败: %v", err)
+		}
+	} else if provider != conv.Provider {
+		DB.Model(&Conversation{}).Where("id = ?", conv.ID).UpdateColumn("provider", provider)
+	}
+
+	var history []ChatMessage
+	if err := DB.Where("conversation_id = ?", conv.ID).Order("id asc").Find(&history).Error; err != nil {
+		return nil, err
+	}
+
+	// 对话中所有关联过的上下文，本轮关联的排在最前
+	refs := append([]ContextRef(nil), contextRefs...)
+	for i := len(history) - 1; i >= 0; i-- {
+		refs = append(refs, history[i].ContextRefs...)
+	}
+	categoryID, noteTitle := opts.CategoryID, ""
+	if categoryID == 0 {
+		categoryID, noteTitle = refsCategory(refs)
+	}
+	basePrompt, templateName := systemPromptFor(categoryID, noteTitle)
+	userTurn := chatMessage{Role: "user", Content: prompt}
+	budget := contextBudget(p, basePrompt, []chatMessage{userTurn})
+	replay := conversationHistory(history, budget/conversationHistoryShare)
+	items := a.collectContextItems(refs)
+	available := budget - estimateMessagesTokens(replay)
+	contextTexts, report := fitContext(items, prompt, currentContextStrategy(), available)
+	var citations []Citation
+	if opts.Retrieval {
+		// 已作为关联内容的笔记不再重复检索
+		exclude := map[uint]bool{}
+		for _, item := range items {
+			exclude[item.NoteID] = true
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), embeddingTimeout)
+		hits, err := retrieveChunks(ctx, prompt, 0, exclude)
+		cancel()
+		if err != nil {
+			return nil, err
+		}
+		retrievedTexts, retrievedReport, cited := fitRetrieved(hits, available-report.Used)
+		contextTexts = append(contextTexts, retrievedTexts...)
+		mergeContextReport(report, retrievedReport)
+		citations = cited
+	}
+	systemPrompt := buildSystemPrompt(basePrompt, contextTexts)
+	if opts.Tools {
+		systemPrompt += toolSystemPrompt
+	}
+	messages := []chatMessage{{Role: "system", Content: systemPrompt}}
+	messages = append(messages, replay...)
+	messages = append(messages, userTurn)
+	// 图片只随本轮提问发送，历史消息中的图片不再重复附带
+	attached, omitted, err := attachImages(p, messages, opts.Images, append([]string{prompt}, contextTexts...), available-report.Used)
+	if err != nil {
+		return nil, err
+	}
+	report.Images, report.OmittedImages = attached, omitted
+
+	userMsg := &ChatMessage{ConversationID: conv.ID, Role: "user", Content: prompt, ContextRefs: contextRefs, Images: opts.Images}
+	if err := DB.Create(userMsg).Error; err != nil {
+		return nil, fmt.Errorf("保存消息失败: %v", err)
+	}
+	log.Printf("[Conversation] 发送消息 (Conversation ID: %d, 历史消息: %d/%d, 关联上下文: %d 段 %d/%d tokens, 略过: %d 段)",
+		conv.ID, len(replay), len(history), len(report.Included), report.Used, report.Budget, len(report.Omitted))
+
+	requestID := a.startChatStream(p, messages, opts.Tools, func(done *AIChatDone) {
+		done.ConversationID = conv.ID
+		done.Context = report
+		done.Citations = citations
+		if done.Content == "" {
+			return
+		}
+		reply := &ChatMessage{ConversationID: conv.ID, Role: "assistant", Content: done.Content, Citations: citations, ContextNotes: contextNoteIDs(report), Model: p.Model}
+		if err := DB.Create(reply).Error; err != nil {
+			log.Printf("[Conversation] 保存回复失败 (Conversation ID: %d): %v", conv.ID, err)
+			return
+		}
+		done.MessageID = reply.ID
+		DB.Model(&Conversation{}).Where("id = ?", conv.ID).UpdateColumn("updated_at", time.Now())
+	})
+
+	return &SendMessageResult{ConversationID: conv.ID, RequestID: requestID, UserMessage: userMsg, Context: report, PromptTemplate: templateName}, nil
+}
+
+// ListConversations 获取对话列表，按最近更新时间倒序
+func (a *App) ListConversations() ([]Conversation, error) {
+	var list []Conversation
+	err := DB.Order("updated_at desc").Find(&list).Error
+	return list, err
+}
+
+// GetConversationMessages 获取对话的全部消息
+func (a *App) GetConversationMessages(conversationID uint) ([]ChatMessage, error) {
+	var list []ChatMessage
+	err := DB.Where("conversation_id = ?", conversationID).Order("id asc").Find(&list).Error
+	return list, err
+}
+
+// RenameConversation 修改对话标题
+func (a *App) RenameConversation(conversationID uint, title string) error {
+	title = strings.TrimSpace(title)
+	if title == "" {
+		return errors.New("标题不能为空")
+	}
+	return DB.Model(&Conversation{}).Where("id = ?", conversationID).Update("title", title).Error
+}
+
+// DeleteConversation 删除对话及其消息
+func (a *App) DeleteConversation(conversationID uint) error {
+	if err := DB.Where("conversation_id = ?", conversationID).Delete(&ChatMessage{}).Error; err != nil {
+		return err
+	}
+	return DB.Delete(&Conversation{}, conversationID).Error
+}
+
+// ExportConversation 导出对话
+// format: markdown 或 json
+func (a *App) ExportConversation(conversationID uint, format string) (string, error) {
+	var conv Conversation
+	if err := DB.First(&conv, conversationID).Error; err != nil {
+		return "", fmt.Errorf("对话不存在: %v", err)
+	}
+	messages, err := a.GetConversationMessages(conversationID)
+	if err != nil {
+		return "", err
+	}
+
+	switch format {
+	case "json":
+		data, err := json.MarshalIndent(struct {
+			Conversation
+			Messages []ChatMessage `json:"messages"`
+		}{conv, messages}, "", "  ")
+		if err != nil {
+			return "", err
+		}
+		return string(data), nil
+	case "", "markdown":
+		var sb strings.Builder
+		sb.WriteString("# " + conv.Title + "\n\n")
+		for _, m := range messages {
+			role := "用户"
+			if m.Role == "assistant" {
+				role = "助手"
+			}
+			sb.WriteString(fmt.Sprintf("## %s · %s\n\n", role, m.CreatedAt.Format("2006-01-02 15:04:05")))
+			if len(m.ContextRefs) > 0 {
+				var names []string
+				for _, ref := range m.ContextRefs {
+					names = append(names, ref.Name)
+				}
+				sb.WriteString("> 关联内容：" + strings.Join(names, "、") + "\n\n")
+			}
+			sb.WriteString(strings.TrimSpace(m.Content) + "\n\n")
+			if len(m.Citations) > 0 {
+				sb.WriteString("> 引用：\n")
+				for _, c := range m.Citations {
+					sb.WriteString(fmt.Sprintf("> - %s", c.Title))
+					if c.Heading != "" {
+						sb.WriteString(" / " + c.Heading)
+					}
+					sb.WriteString(fmt.Sprintf("（笔记 #%d）\n", c.NoteID))
+				}
+				sb.WriteString("\n")
+			}
+		}
+		return sb.String(), nil
+	}
+	return "", fmt.Errorf("不支持的导出格式: %s", format)
+}
@@ -26,9 +26,11 @@ func InitDB() {
 }
 
 func AutoMigrate() {
-	DB.AutoMigrate(&Category{}, &ColorPreset{}, &Note{})
+	DB.AutoMigrate(&Category{}, &ColorPreset{}, &Note{}, &BookChapter{}, &Attachment{}, &ScriptRun{}, &ScriptSchedule{}, &EnvProfile{}, &Secret{}, &Conversation{}, &ChatMessage{}, &NoteChunk{}, &AIUsage{}, &PromptTemplate{}, &Quiz{}, &QuizQuestion{}, &QuizAttempt{}, &QuizAnswer{})
 	InitPDFStorage()
 	InitImageStorage()
+	InitEPUBStorage()
+	InitAttachmentStorage()
 }
 
 func CloseDB() {
@@ -0,0 +1,39 @@
+package backend
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// useTestDB 在测试期间使用临时目录中的数据库和存储目录，结束后恢复
+func useTestDB(t *testing.T) {
+	t.Helper()
+	dir := t.TempDir()
+	db, err := gorm.Open(sqlite.Open(filepath.Join(dir, "test.db")), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	savedDB := DB
+	savedDirs := []string{pdfStorageDir, imageStorageDir, epubStorageDir, attachmentDir}
+	DB = db
+	AutoMigrate()
+	pdfStorageDir = filepath.Join(dir, "pdf")
+	imageStorageDir = filepath.Join(dir, "images")
+	epubStorageDir = filepath.Join(dir, "epub")
+	attachmentDir = filepath.Join(dir, "attachments")
+	for _, d := range []string{pdfStorageDir, imageStorageDir, epubStorageDir, attachmentDir} {
+		if err := os.MkdirAll(d, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Cleanup(func() {
+		CloseDB()
+		DB = savedDB
+		pdfStorageDir, imageStorageDir, epubStorageDir, attachmentDir = savedDirs[0], savedDirs[1], savedDirs[2], savedDirs[3]
+	})
+}
@@ -0,0 +1,459 @@
+package backend
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+	"gorm.io/gorm"
+)
+
+// epubChapter 解析出的单个章节
+type epubChapter struct {
+	Title       string
+	Href        string
+	ContentHTML string
+	ContentMD   string
+}
+
+// epubBook 解析出的书籍
+type epubBook struct {
+	Title    string
+	Chapters []epubChapter
+	Images   []string // 保存到图片存储目录的图片相对路径，导入失败时需要删除
+}
+
+type epubContainer struct {
+	Rootfiles []struct {
+		FullPath string `xml:"full-path,attr"`
+	} `xml:"rootfiles>rootfile"`
+}
+
+type epubPackage struct {
+	Title    string `xml:"metadata>title"`
+	Manifest []struct {
+		ID         string `xml:"id,attr"`
+		Href       string `xml:"href,attr"`
+		MediaType  string `xml:"media-type,attr"`
+		Properties string `xml:"properties,attr"`
+	} `xml:"manifest>item"`
+	Spine struct {
+		Toc      string `xml:"toc,attr"`
+		ItemRefs []struct {
+			IDRef string `xml:"idref,attr"`
+		} `xml:"itemref"`
+	} `xml:"spine"`
+}
+
+type epubNavPoint struct {
+	Label    string         `xml:"navLabel>text"`
+	Content  epubNavContent `xml:"content"`
+	Children []epubNavPoint `xml:"navPoint"`
+}
+
+type epubNavContent struct {
+	Src string `xml:"src,attr"`
+}
+
+type epubNCX struct {
+	NavPoints []epubNavPoint `xml:"navMap>navPoint"`
+}
+
+// epubImageRegex 章节 HTML 中指向图片存储目录的图片引用
+var epubImageRegex = regexp.MustCompile(`local://images/([^"'\s)]+)`)
+
+// epubChapterImages 收集 EPUB 笔记各章节引用的图片相对路径，用于删除笔记时清理图片
+func epubChapterImages(noteID uint) ([]string, error) {
+	var chapters []BookChapter
+	if err := DB.Select("content_html").Where("note_id = ?", noteID).Find(&chapters).Error; err != nil {
+		return nil, err
+	}
+	var images []string
+	seen := map[string]bool{}
+	for _, ch := range chapters {
+		for _, m := range epubImageRegex.FindAllStringSubmatch(ch.ContentHTML, -1) {
+			// 导入时保存的图片都直接位于图片存储目录下
+			rel := m[1]
+			if rel != path.Base(rel) || seen[rel] {
+				continue
+			}
+			seen[rel] = true
+			images = append(images, rel)
+		}
+	}
+	return images, nil
+}
+
+// removeEPUBImages 删除 EPUB 导入时保存的图片
+func removeEPUBImages(images []string) {
+	for _, rel := range images {
+		if err := os.Remove(GetImageFullPath(rel)); err != nil && !os.IsNotExist(err) {
+			log.Printf("[EPUB] 删除图片失败 %s: %v", rel, err)
+		}
+	}
+}
+
+// parseEPUB 解析 EPUB 文件，提取章节并转换为清洗后的 HTML 和 Markdown
+// 章节中引用的图片会保存到图片存储目录，并改写为 local://images/ 路径；解析失败时已保存的图片会被删除
+func parseEPUB(data []byte) (*epubBook, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("无效的 EPUB 文件: %v", err)
+	}
+	files := map[string]*zip.File{}
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+
+	var container epubContainer
+	if err := readEPUBXML(files, "META-INF/container.xml", &container); err != nil {
+		return nil, err
+	}
+	if len(container.Rootfiles) == 0 || container.Rootfiles[0].FullPath == "" {
+		return nil, errors.New("EPUB 缺少 rootfile 声明")
+	}
+	opfPath := container.Rootfiles[0].FullPath
+	opfDir := path.Dir(opfPath)
+
+	var pkg epubPackage
+	if err := readEPUBXML(files, opfPath, &pkg); err != nil {
+		return nil, err
+	}
+
+	type manifestItem struct {
+		href      string
+		mediaType string
+	}
+	items := map[string]manifestItem{}
+	tocTitles := map[string]string{}
+	for _, item := range pkg.Manifest {
+		href := resolveEPUBPath(opfDir, item.Href)
+		items[item.ID] = manifestItem{href: href, mediaType: item.MediaType}
+		// EPUB 3 导航文档
+		if strings.Contains(item.Properties, "nav") {
+			collectEPUBNavTitles(files, href, tocTitles)
+		}
+	}
+	// EPUB 2 NCX 目录
+	if toc, ok := items[pkg.Spine.Toc]; ok {
+		var ncx epubNCX
+		if err := readEPUBXML(files, toc.href, &ncx); err == nil {
+			collectNCXTitles(path.Dir(toc.href), ncx.NavPoints, tocTitles)
+		}
+	}
+
+	book := &epubBook{Title: strings.TrimSpace(pkg.Title)}
+	savedImages := map[string]string{}
+	for _, ref := range pkg.Spine.ItemRefs {
+		item, ok := items[ref.IDRef]
+		if !ok || (item.mediaType != "application/xhtml+xml" && item.mediaType != "text/html") {
+			continue
+		}
+		chapter, err := parseEPUBChapter(files, item.href, savedImages, &book.Images)
+		if err != nil {
+			log.Printf("[EPUB] 跳过无法解析的章节 %s: %v", item.href, err)
+			continue
+		}
+		if chapter == nil {
+			continue
+		}
+		if title, ok := tocTitles[item.href]; ok {
+			chapter.Title = title
+		}
+		if chapter.Title == "" {
+			chapter.Title = fmt.Sprintf("第 %d 章", len(book.Chapters)+1)
+		}
+		book.Chapters = append(book.Chapters, *chapter)
+	}
+
+	if len(book.Chapters) == 0 {
+		removeEPUBImages(book.Images)
+		return nil, errors.New("EPUB 中没有可读取的章节")
+	}
+	return book, nil
+}
+
+// parseEPUBChapter 解析单个 XHTML 章节，内容为空时返回 nil
+// 保存的图片追加到 images；章节被跳过时删除本章节新保存的图片，其余由调用方在导入失败时清理
+func parseEPUBChapter(files map[string]*zip.File, href string, savedImages map[string]string, images *[]string) (chapter *epubChapter, err error) {
+	raw, err := readEPUBFile(files, href)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := html.Parse(bytes.NewReader(raw))
+	if err != nil {
+		return nil, err
+	}
+
+	title := ""
+	if t := findHTMLElement(doc, "title"); t != nil {
+		title = htmlText(t)
+	}
+	body := findHTMLElement(doc, "body")
+	if body == nil {
+		return nil, nil
+	}
+
+	start := len(*images)
+	defer func() {
+		if chapter != nil {
+			return
+		}
+		removeEPUBImages((*images)[start:])
+		for key, saved := range savedImages {
+			for _, rel := range (*images)[start:] {
+				if saved == "local://images/"+rel {
+					delete(savedImages, key)
+				}
+			}
+		}
+		*images = (*images)[:start]
+	}()
+
+	chapterDir := path.Dir(href)
+	sanitizeHTMLNode(body, func(src string) string {
+		if src == "" || strings.HasPrefix(src, "data:") || strings.Contains(src, "://") {
+			return ""
+		}
+		imgPath := resolveEPUBPath(chapterDir, src)
+		if saved, ok := savedImages[imgPath]; ok {
+			return saved
+		}
+		imgData, err := readEPUBFile(files, imgPath)
+		if err != nil {
+			log.Printf("[EPUB] 读取图片失败 %s: %v", imgPath, err)
+			return ""
+		}
+		relativePath, err := saveImageBytes(imgData, strings.ToLower(path.Ext(imgPath)))
+		if err != nil {
+			return ""
+		}
+		saved := "local://images/" + relativePath
+		savedImages[imgPath] = saved
+		*images = append(*images, relativePath)
+		return saved
+	})
+
+	if h := findHTMLElement(body, "h1"); h != nil && htmlText(h) != "" {
+		title = htmlText(h)
+	} else if h := findHTMLElement(body, "h2"); h != nil && htmlText(h) != "" {
+		title = htmlText(h)
+	}
+
+	contentHTML, err := renderHTMLChildren(body)
+	if err != nil {
+		return nil, err
+	}
+	contentMD := htmlToMarkdown(body)
+	if contentMD == "" {
+		return nil, nil
+	}
+
+	return &epubChapter{
+		Title:       title,
+		Href:        href,
+		ContentHTML: contentHTML,
+		ContentMD:   contentMD,
+	}, nil
+}
+
+// collectEPUBNavTitles 从 EPUB 3 导航文档中读取章节标题
+func collectEPUBNavTitles(files map[string]*zip.File, navHref string, titles map[string]string) {
+	raw, err := readEPUBFile(files, navHref)
+	if err != nil {
+		return
+	}
+	doc, err := html.Parse(bytes.NewReader(raw))
+	if err != nil {
+		return
+	}
+	nav := findHTMLElement(doc, "nav")
+	if nav == nil {
+		return
+	}
+	navDir := path.Dir(navHref)
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.Data == "a" {
+			href := resolveEPUBPath(navDir, htmlAttr(n, "href"))
+			if _, ok := titles[href]; !ok && htmlText(n) != "" {
+				titles[href] = htmlText(n)
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(nav)
+}
+
+// collectNCXTitles 从 EPUB 2 NCX 目录中读取章节标题
+func collectNCXTitles(baseDir string, points []epubNavPoint, titles map[string]string) {
+	for _, p := range points {
+		href := resolveEPUBPath(baseDir, p.Content.Src)
+		label := strings.TrimSpace(p.Label)
+		if _, ok := titles[href]; !ok && label != "" {
+			titles[href] = label
+		}
+		collectNCXTitles(baseDir, p.Children, titles)
+	}
+}
+
+// resolveEPUBPath 将相对引用解析为包内路径（去掉锚点和查询参数）
+func resolveEPUBPath(baseDir string, ref string) string {
+	if i := strings.IndexAny(ref, "#?"); i >= 0 {
+		ref = ref[:i]
+	}
+	if unescaped, err := url.PathUnescape(ref); err == nil {
+		ref = unescaped
+	}
+	if strings.HasPrefix(ref, "/") {
+		return strings.TrimPrefix(path.Clean(ref), "/")
+	}
+	return strings.TrimPrefix(path.Join(baseDir, ref), "./")
+}
+
+func readEPUBFile(files map[string]*zip.File, name string) ([]byte, error) {
+	f, ok := files[name]
+	if !ok {
+		return nil, fmt.Errorf("EPUB 中缺少文件: %s", name)
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return io.ReadAll(rc)
+}
+
+func readEPUBXML(files map[string]*zip.File, name string, v interface{}) error {
+	data, err := readEPUBFile(files, name)
+	if err != nil {
+		return err
+	}
+	if err := xml.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("解析 %s 失败: %v", name, err)
+	}
+	return nil
+}
+
+// ImportEPUB 导入 EPUB 文件
+// fileDataBase64: base64 编码的文件数据
+func (a *App) ImportEPUB(fileDataBase64 string, fileName string, categoryID uint) (*Note, error) {
+	fileData, err := base64.StdEncoding.DecodeString(fileDataBase64)
+	if err != nil {
+		log.Printf("Failed to decode base64 EPUB data: %v\n", err)
+		return nil, fmt.Errorf("解码 EPUB 数据失败: %v", err)
+	}
+
+	book, err := parseEPUB(fileData)
+	if err != nil {
+		log.Printf("Failed to parse EPUB: %v\n", err)
+		return nil, err
+	}
+
+	// 保存文件到 EPUB 存储目录
+	relativePath, nameWithoutExt := uniqueStorageName(fileName)
+	fullPath := GetEPUBFullPath(relativePath)
+	if err := os.WriteFile(fullPath, fileData, 0644); err != nil {
+		log.Printf("Failed to save EPUB file: %v\n", err)
+		removeEPUBImages(book.Images)
+		return nil, fmt.Errorf("保存 EPUB 文件失败: %v", err)
+	}
+
+	title := book.Title
+	if title == "" {
+		title = nameWithoutExt
+	}
+	note := &Note{
+		Title:      title,
+		Type:       3, // EPUB 类型
+		FilePath:   relativePath,
+		CategoryID: categoryID,
+	}
+
+	err = DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(note).Error; err != nil {
+			return err
+		}
+		for i, ch := range book.Chapters {
+			chapter := &BookChapter{
+				NoteID:      note.ID,
+				Seq:         uint(i),
+				Title:       ch.Title,
+				Href:        ch.Href,
+				ContentHTML: ch.ContentHTML,
+				ContentMD:   ch.ContentMD,
+			}
+			if err := tx.Create(chapter).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		os.Remove(fullPath)
+		removeEPUBImages(book.Images)
+		log.Printf("Failed to create EPUB note: %v\n", err)
+		return nil, fmt.Errorf("创建 EPUB 记录失败: %v", err)
+	}
+
+	log.Printf("EPUB imported successfully: %s (ID: %d, chapters: %d)\n", fileName, note.ID, len(book.Chapters))
+	a.enqueueIndex(note.ID)
+	return note, nil
+}
+
+// ListEPUBChapters 获取 EPUB 章节目录（不含正文）
+func (a *App) ListEPUBChapters(noteID uint) ([]BookChapter, error) {
+	var list []BookChapter
+	err := DB.Select("id", "note_id", "seq", "title", "href").
+		Where("note_id = ?", noteID).Order("seq asc").Find(&list).Error
+	return list, err
+}
+
+// GetEPUBChapter 获取 EPUB 指定章节的内容
+func (a *App) GetEPUBChapter(noteID uint, seq uint) (*BookChapter, error) {
+	var chapter BookChapter
+	if err := DB.Where("note_id = ? AND seq = ?", noteID, seq).First(&chapter).Error; err != nil {
+		return nil, fmt.Errorf("章节不存在: %v", err)
+	}
+	return &chapter, nil
+}
+
+// UpdateEPUBChapter 更新 EPUB 的当前阅读章节
+func (a *App) UpdateEPUBChapter(noteID uint, seq uint) error {
+	var note Note
+	if err := DB.First(&note, noteID).Error; err != nil {
+		return fmt.Errorf("笔记不存在: %v", err)
+	}
+
+	if note.Type != 3 {
+		return errors.New("该笔记不是 EPUB 类型")
+	}
+
+	return DB.Model(&Note{}).Where("id = ?", noteID).Update("epub_chapter", seq).Error
+}
+
+// getEPUBText 获取 EPUB 所有章节的 Markdown 文本，用于搜索和 AI 上下文
+func getEPUBText(noteID uint) (string, error) {
+	var chapters []BookChapter
+	if err := DB.Where("note_id = ?", noteID).Order("seq asc").Find(&chapters).Error; err != nil {
+		return "", err
+	}
+	var parts []string
+	for _, ch := range chapters {
+		parts = append(parts, ch.ContentMD)
+	}
+	return strings.Join(parts, "\n\n"), nil
+}
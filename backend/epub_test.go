@@ -0,0 +1,92 @@
+package backend
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/base64"
+	"os"
+	"testing"
+)
+
+// testEPUB 生成包含两个章节的 EPUB，两个章节引用同一张图片，第二章另有一张图片
+func testEPUB(t *testing.T) []byte {
+	t.Helper()
+	files := []struct{ name, content string }{
+		{"mimetype", "application/epub+zip"},
+		{"META-INF/container.xml", `<?xml version="1.0"?>
+<container version="1.0" xmlns="urn:oasis:names:tc:opendocument:xmlns:container">
+  <rootfiles><rootfile full-path="OEBPS/content.opf" media-type="application/oebps-package+xml"/></rootfiles>
+</container>`},
+		{"OEBPS/content.opf", `<?xml version="1.0"?>
+<package xmlns="http://www.idpf.org/2007/opf" version="3.0">
+  <metadata xmlns:dc="http://purl.org/dc/elements/1.1/"><dc:title>测试书籍</dc:title></metadata>
+  <manifest>
+    <item id="c1" href="text/c1.xhtml" media-type="application/xhtml+xml"/>
+    <item id="c2" href="text/c2.xhtml" media-type="application/xhtml+xml"/>
+    <item id="cover" href="images/cover.png" media-type="image/png"/>
+    <item id="fig" href="images/fig.png" media-type="image/png"/>
+  </manifest>
+  <spine><itemref idref="c1"/><itemref idref="c2"/></spine>
+</package>`},
+		{"OEBPS/text/c1.xhtml", `<html><body><h1>第一章</h1><p><img src="../images/cover.png"/></p></body></html>`},
+		{"OEBPS/text/c2.xhtml", `<html><body><h1>第二章</h1><img src="../images/cover.png"/><img src="../images/fig.png"/></body></html>`},
+		{"OEBPS/images/cover.png", "\x89PNG\r\n\x1a\ncover"},
+		{"OEBPS/images/fig.png", "\x89PNG\r\n\x1a\nfig"},
+	}
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, f := range files {
+		w, err := zw.Create(f.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(f.content))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestDeleteEPUBNoteRemovesImages(t *testing.T) {
+	useTestDB(t)
+	app := NewApp()
+	category := &Category{Name: "书籍"}
+	if err := DB.Create(category).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	note, err := app.ImportEPUB(base64.StdEncoding.EncodeToString(testEPUB(t)), "book.epub", category.ID)
+	if err != nil {
+		t.Fatalf("ImportEPUB: %v", err)
+	}
+	images, err := os.ReadDir(GetImageStorageDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(images) != 2 {
+		t.Fatalf("导入后图片目录中有 %d 个文件，期望 2 个", len(images))
+	}
+	if _, err := os.Stat(GetEPUBFullPath(note.FilePath)); err != nil {
+		t.Fatalf("EPUB 文件未保存: %v", err)
+	}
+
+	if err := app.DeleteNote(note.ID); err != nil {
+		t.Fatalf("DeleteNote: %v", err)
+	}
+	images, err = os.ReadDir(GetImageStorageDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, img := range images {
+		t.Errorf("删除笔记后图片仍然存在: %s", img.Name())
+	}
+	if _, err := os.Stat(GetEPUBFullPath(note.FilePath)); !os.IsNotExist(err) {
+		t.Errorf("删除笔记后 EPUB 文件仍然存在: %v", err)
+	}
+	var count int64
+	DB.Model(&BookChapter{}).Where("note_id = ?", note.ID).Count(&count)
+	if count != 0 {
+		t.Errorf("删除笔记后仍有 %d 个章节", count)
+	}
+}
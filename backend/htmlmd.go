@@ -0,0 +1,360 @@
+package backend
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// 清洗时整体丢弃（连同子节点）的标签
+var htmlDropTags = map[string]bool{
+	"script": true, "style": true, "iframe": true, "object": true, "embed": true,
+	"form": true, "input": true, "button": true, "select": true, "textarea": true,
+	"link": true, "meta": true, "head": true, "title": true, "svg": true, "math": true,
+	"noscript": true, "template": true,
+}
+
+// 清洗后保留的标签及其允许的属性，不在列表中的标签会被展开（保留子节点）
+var htmlAllowTags = map[string][]string{
+	"p": nil, "br": nil, "hr": nil, "div": nil, "span": nil,
+	"h1": {"id"}, "h2": {"id"}, "h3": {"id"}, "h4": {"id"}, "h5": {"id"}, "h6": {"id"},
+	"ul": nil, "ol": {"start"}, "li": nil, "dl": nil, "dt": nil, "dd": nil,
+	"strong": nil, "b": nil, "em": nil, "i": nil, "u": nil, "s": nil, "del": nil,
+	"sub": nil, "sup": nil, "small": nil, "mark": nil,
+	"code": nil, "pre": nil, "kbd": nil, "blockquote": nil,
+	"a": {"href", "title"}, "img": {"src", "alt", "title"},
+	"figure": nil, "figcaption": nil,
+	"table": nil, "thead": nil, "tbody": nil, "tfoot": nil, "tr": nil,
+	"th": {"colspan", "rowspan"}, "td": {"colspan", "rowspan"},
+}
+
+var mdBlankLinesRegex = regexp.MustCompile(`\n{3,}`)
+
+// sanitizeHTMLNode 原地清洗 HTML 节点树
+// rewriteImage: 图片地址改写函数，返回空字符串表示丢弃该图片
+func sanitizeHTMLNode(n *html.Node, rewriteImage func(src string) string) {
+	for c := n.FirstChild; c != nil; {
+		next := c.NextSibling
+		switch c.Type {
+		case html.CommentNode, html.DoctypeNode:
+			n.RemoveChild(c)
+		case html.ElementNode:
+			tag := c.Data
+			if htmlDropTags[tag] {
+				n.RemoveChild(c)
+				break
+			}
+			allowed, ok := htmlAllowTags[tag]
+			if !ok {
+				// 未知标签：先清洗子节点，再把子节点提升到当前位置
+				sanitizeHTMLNode(c, rewriteImage)
+				for gc := c.FirstChild; gc != nil; {
+					gnext := gc.NextSibling
+					c.RemoveChild(gc)
+					n.InsertBefore(gc, c)
+					gc = gnext
+				}
+				n.RemoveChild(c)
+				break
+			}
+			c.Attr = filterHTMLAttrs(c.Attr, allowed)
+			if tag == "img" {
+				src := htmlAttr(c, "src")
+				if rewriteImage != nil {
+					src = rewriteImage(src)
+				}
+				if src == "" {
+					n.RemoveChild(c)
+					break
+				}
+				setHTMLAttr(c, "src", src)
+			}
+			if tag == "a" && !isSafeLink(htmlAttr(c, "href")) {
+				c.Attr = filterHTMLAttrs(c.Attr, []string{"title"})
+			}
+			sanitizeHTMLNode(c, rewriteImage)
+		}
+		c = next
+	}
+}
+
+// isSafeLink 仅保留外部链接和页内锚点
+func isSafeLink(href string) bool {
+	lower := strings.ToLower(strings.TrimSpace(href))
+	return strings.HasPrefix(lower, "http://") ||
+		strings.HasPrefix(lower, "https://") ||
+		strings.HasPrefix(lower, "mailto:") ||
+		strings.HasPrefix(lower, "#")
+}
+
+func filterHTMLAttrs(attrs []html.Attribute, allowed []string) []html.Attribute {
+	var result []html.Attribute
+	for _, attr := range attrs {
+		for _, name := range allowed {
+			if attr.Namespace == "" && attr.Key == name {
+				result = append(result, attr)
+				break
+			}
+		}
+	}
+	return result
+}
+
+func htmlAttr(n *html.Node, key string) string {
+	for _, attr := range n.Attr {
+		if attr.Key == key {
+			return attr.Val
+		}
+	}
+	return ""
+}
+
+func setHTMLAttr(n *html.Node, key string, val string) {
+	for i, attr := range n.Attr {
+		if attr.Key == key {
+			n.Attr[i].Val = val
+			return
+		}
+	}
+	n.Attr = append(n.Attr, html.Attribute{Key: key, Val: val})
+}
+
+// findHTMLElement 深度优先查找第一个指定标签的元素
+func findHTMLElement(n *html.Node, tag string) *html.Node {
+	if n.Type == html.ElementNode && n.Data == tag {
+		return n
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if found := findHTMLElement(c, tag); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// htmlText 获取节点的纯文本内容（空白已折叠）
+func htmlText(n *html.Node) string {
+	var sb strings.Builder
+	var walk func(*html.Node)
+	walk = func(node *html.Node) {
+		if node.Type == html.TextNode {
+			sb.WriteString(node.Data)
+		}
+		for c := node.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return strings.Join(strings.Fields(sb.String()), " ")
+}
+
+// renderHTMLChildren 将节点的子节点渲染为 HTML 字符串
+func renderHTMLChildren(n *html.Node) (string, error) {
+	var buf bytes.Buffer
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if err := html.Render(&buf, c); err != nil {
+			return "", err
+		}
+	}
+	return strings.TrimSpace(buf.String()), nil
+}
+
+// htmlToMarkdown 将（已清洗的）HTML 节点的子节点转换为 Markdown
+func htmlToMarkdown(n *html.Node) string {
+	var sb strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		writeMarkdown(&sb, c)
+	}
+	md := mdBlankLinesRegex.ReplaceAllString(sb.String(), "\n\n")
+	return strings.TrimSpace(md)
+}
+
+func writeMarkdown(sb *strings.Builder, n *html.Node) {
+	switch n.Type {
+	case html.TextNode:
+		sb.WriteString(collapseSpace(n.Data))
+		return
+	case html.ElementNode:
+	default:
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			writeMarkdown(sb, c)
+		}
+		return
+	}
+
+	switch n.Data {
+	case "h1", "h2", "h3", "h4", "h5", "h6":
+		level := int(n.Data[1] - '0')
+		sb.WriteString("\n\n" + strings.Repeat("#", level) + " " + htmlText(n) + "\n\n")
+	case "p", "div", "figure", "figcaption", "dl", "dt", "dd":
+		var inner strings.Builder
+		writeMarkdownChildren(&inner, n)
+		sb.WriteString("\n\n" + strings.TrimSpace(inner.String()) + "\n\n")
+	case "br":
+		sb.WriteString("  \n")
+	case "hr":
+		sb.WriteString("\n\n---\n\n")
+	case "strong", "b":
+		writeMarkdownWrapped(sb, n, "**")
+	case "em", "i":
+		writeMarkdownWrapped(sb, n, "*")
+	case "del", "s":
+		writeMarkdownWrapped(sb, n, "~~")
+	case "code", "kbd":
+		if text := htmlRawText(n); text != "" {
+			sb.WriteString("`" + text + "`")
+		}
+	case "pre":
+		sb.WriteString("\n\n```\n" + strings.TrimRight(htmlRawText(n), "\n") + "\n```\n\n")
+	case "blockquote":
+		var inner strings.Builder
+		writeMarkdownChildren(&inner, n)
+		text := strings.TrimSpace(mdBlankLinesRegex.ReplaceAllString(inner.String(), "\n\n"))
+		sb.WriteString("\n\n")
+		for _, line := range strings.Split(text, "\n") {
+			sb.WriteString(strings.TrimRight("> "+line, " ") + "\n")
+		}
+		sb.WriteString("\n")
+	case "ul", "ol":
+		writeMarkdownList(sb, n)
+	case "a":
+		var inner strings.Builder
+		writeMarkdownChildren(&inner, n)
+		text := strings.TrimSpace(inner.String())
+		href := htmlAttr(n, "href")
+		if href == "" || text == "" {
+			sb.WriteString(text)
+		} else {
+			sb.WriteString("[" + text + "](" + href + ")")
+		}
+	case "img":
+		sb.WriteString("![" + htmlAttr(n, "alt") + "](" + htmlAttr(n, "src") + ")")
+	case "table":
+		writeMarkdownTable(sb, n)
+	default:
+		writeMarkdownChildren(sb, n)
+	}
+}
+
+func writeMarkdownChildren(sb *strings.Builder, n *html.Node) {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		writeMarkdown(sb, c)
+	}
+}
+
+func writeMarkdownWrapped(sb *strings.Builder, n *html.Node, mark string) {
+	var inner strings.Builder
+	writeMarkdownChildren(&inner, n)
+	text := strings.TrimSpace(inner.String())
+	if text != "" {
+		sb.WriteString(mark + text + mark)
+	}
+}
+
+func writeMarkdownList(sb *strings.Builder, n *html.Node) {
+	ordered := n.Data == "ol"
+	index := 1
+	if start := htmlAttr(n, "start"); start != "" {
+		fmt.Sscanf(start, "%d", &index)
+	}
+	sb.WriteString("\n\n")
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type != html.ElementNode || c.Data != "li" {
+			continue
+		}
+		marker := "- "
+		if ordered {
+			marker = fmt.Sprintf("%d. ", index)
+			index++
+		}
+		var inner strings.Builder
+		writeMarkdownChildren(&inner, c)
+		text := strings.TrimSpace(mdBlankLinesRegex.ReplaceAllString(inner.String(), "\n\n"))
+		indent := strings.Repeat(" ", len(marker))
+		for i, line := range strings.Split(text, "\n") {
+			if i == 0 {
+				sb.WriteString(marker + line + "\n")
+			} else if strings.TrimSpace(line) == "" {
+				sb.WriteString("\n")
+			} else {
+				sb.WriteString(indent + line + "\n")
+			}
+		}
+	}
+	sb.WriteString("\n")
+}
+
+func writeMarkdownTable(sb *strings.Builder, n *html.Node) {
+	var rows [][]string
+	var walk func(*html.Node)
+	walk = func(node *html.Node) {
+		for c := node.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type != html.ElementNode {
+				continue
+			}
+			if c.Data == "tr" {
+				var cells []string
+				for cell := c.FirstChild; cell != nil; cell = cell.NextSibling {
+					if cell.Type == html.ElementNode && (cell.Data == "td" || cell.Data == "th") {
+						cells = append(cells, strings.ReplaceAll(htmlText(cell), "|", "\\|"))
+					}
+				}
+				rows = append(rows, cells)
+				continue
+			}
+			walk(c)
+		}
+	}
+	walk(n)
+	if len(rows) == 0 {
+		return
+	}
+	sb.WriteString("\n\n")
+	for i, row := range rows {
+		sb.WriteString("| " + strings.Join(row, " | ") + " |\n")
+		if i == 0 {
+			sb.WriteString("|" + strings.Repeat(" --- |", len(row)) + "\n")
+		}
+	}
+	sb.WriteString("\n")
+}
+
+// htmlRawText 获取节点的原始文本（保留空白，用于代码块）
+func htmlRawText(n *html.Node) string {
+	var sb strings.Builder
+	var walk func(*html.Node)
+	walk = func(node *html.Node) {
+		if node.Type == html.TextNode {
+			sb.WriteString(node.Data)
+		}
+		if node.Type == html.ElementNode && node.Data == "br" {
+			sb.WriteString("\n")
+		}
+		for c := node.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return sb.String()
+}
+
+// collapseSpace 折叠连续空白为单个空格
+func collapseSpace(s string) string {
+	var sb strings.Builder
+	space := false
+	for _, r := range s {
+		if r == ' ' || r == '\n' || r == '\t' || r == '\r' {
+			if !space {
+				sb.WriteByte(' ')
+			}
+			space = true
+			continue
+		}
+		space = false
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
@@ -8,8 +8,10 @@ type Category struct {
 	ColorPresetID *uint        `json:"colorPresetId"`
 	ColorPreset   *ColorPreset `json:"colorPreset" gorm:"foreignKey:ColorPresetID"`
 	ParentID      *uint        `json:"parentId"`
-	CreatedAt     time.Time    `json:"createdAt"`
-	UpdatedAt     time.Time    `json:"updatedAt"`
+	// PromptTemplateID 目录的默认系统提示词，未设置时继承上级目录
+	PromptTemplateID *uint     `json:"promptTemplateId"`
+	CreatedAt        time.Time `json:"createdAt"`
+	UpdatedAt        time.Time `json:"updatedAt"`
 }
 
 type ColorPreset struct {
@@ -22,23 +24,236 @@ type ColorPreset struct {
 }
 
 type Note struct {
+	ID            uint      `json:"id" gorm:"primaryKey"`
+	Title         string    `json:"title" gorm:"size:200"`
+	Language      string    `json:"language" gorm:"size:50"`
+	Snippet       string    `json:"snippet" gorm:"type:text"`
+	Analysis      string    `json:"analysis" gorm:"type:text"`
+	ContentMD     string    `json:"contentMd" gorm:"type:longtext"`
+	Type          uint      `json:"type" gorm:"default:0"`          // 0: 正常笔记, 1: PDF, 2: 命令行工具, 3: EPUB
+	FilePath      string    `json:"filePath" gorm:"size:500"`       // PDF / EPUB 文件路径
+	PDFPage       uint      `json:"pdfPage" gorm:"default:1"`       // PDF 当前页码
+	EPUBChapter   uint      `json:"epubChapter" gorm:"default:0"`   // EPUB 当前章节序号
+	ScriptTimeout uint      `json:"scriptTimeout" gorm:"default:0"` // 脚本超时秒数，0 表示使用默认值
+	TrustedHash   string    `json:"trustedHash" gorm:"size:64"`     // 已确认执行的脚本内容哈希
+	SourceMessage uint      `json:"sourceMessage" gorm:"default:0"` // 由 AI 回答保存的笔记对应的助手消息 ID
+	CategoryID    uint      `json:"categoryId"`
+	CreatedAt     time.Time `json:"createdAt"`
+	UpdatedAt     time.Time `json:"updatedAt"`
+}
+
+// BookChapter EPUB 章节，导入时从书籍中提取
+type BookChapter struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	NoteID      uint      `json:"noteId" gorm:"index"`
+	Seq         uint      `json:"seq"` // 章节序号，从 0 开始
+	Title       string    `json:"title" gorm:"size:200"`
+	Href        string    `json:"href" gorm:"size:500"`             // 章节在 EPUB 包内的路径
+	ContentHTML string    `json:"contentHtml" gorm:"type:longtext"` // 清洗后的 HTML
+	ContentMD   string    `json:"contentMd" gorm:"type:longtext"`   // 转换后的 Markdown，用于搜索和 AI 上下文
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+// Attachment 笔记附件，文件按内容哈希存放在 attachments 目录
+type Attachment struct {
+	ID           uint      `json:"id" gorm:"primaryKey"`
+	NoteID       uint      `json:"noteId" gorm:"index"`
+	OriginalName string    `json:"originalName" gorm:"size:255"`
+	MimeType     string    `json:"mimeType" gorm:"size:100"`
+	Size         int64     `json:"size"`
+	Hash         string    `json:"hash" gorm:"size:64;index"`   // SHA-256
+	StoragePath  string    `json:"storagePath" gorm:"size:500"` // 相对 attachments 目录的路径
+	CreatedAt    time.Time `json:"createdAt"`
+}
+
+type ScriptResult struct {
+	Stdout    string `json:"stdout"`
+	Stderr    string `json:"stderr"`
+	Success   bool   `json:"success"`
+	Error     string `json:"error,omitempty"`
+	ExitCode  int    `json:"exitCode"`
+	HistoryID uint   `json:"historyId,omitempty"` // 对应的 ScriptRun 记录 ID
+}
+
+// ScriptRun 脚本运行记录，输出超过上限时只保留末尾部分
+type ScriptRun struct {
 	ID         uint      `json:"id" gorm:"primaryKey"`
-	Title      string    `json:"title" gorm:"size:200"`
-	Language   string    `json:"language" gorm:"size:50"`
-	Snippet    string    `json:"snippet" gorm:"type:text"`
-	Analysis   string    `json:"analysis" gorm:"type:text"`
-	ContentMD  string    `json:"contentMd" gorm:"type:longtext"`
-	Type       uint      `json:"type" gorm:"default:0"`    // 0: 正常笔记, 1: PDF, 2: 命令行工具
-	FilePath   string    `json:"filePath" gorm:"size:500"` // PDF 文件路径
-	PDFPage    uint      `json:"pdfPage" gorm:"default:1"` // PDF 当前页码
-	CategoryID uint      `json:"categoryId"`
+	NoteID     uint      `json:"noteId" gorm:"index"`
+	Trigger    string    `json:"trigger" gorm:"size:20"` // manual, stream, block, schedule
+	StartedAt  time.Time `json:"startedAt"`
+	DurationMs int64     `json:"durationMs"`
+	ExitCode   int       `json:"exitCode"`
+	Success    bool      `json:"success"`
+	Error      string    `json:"error" gorm:"type:text"`
+	Stdout     string    `json:"stdout" gorm:"type:text"`
+	Stderr     string    `json:"stderr" gorm:"type:text"`
 	CreatedAt  time.Time `json:"createdAt"`
-	UpdatedAt  time.Time `json:"updatedAt"`
 }
 
-type ScriptResult struct {
-	Stdout  string `json:"stdout"`
-	Stderr  string `json:"stderr"`
-	Success bool   `json:"success"`
-	Error   string `json:"error,omitempty"`
+// ScriptSchedule 命令行工具笔记的定时运行计划
+type ScriptSchedule struct {
+	ID          uint       `json:"id" gorm:"primaryKey"`
+	NoteID      uint       `json:"noteId" gorm:"index"`
+	Cron        string     `json:"cron" gorm:"size:100"` // 标准 5 段 cron 表达式，或 @daily、@every 1h 等描述符
+	Enabled     bool       `json:"enabled" gorm:"default:true"`
+	ProfileID   uint       `json:"profileId"`              // 运行时使用的环境配置，0 表示不使用
+	CatchUp     string     `json:"catchUp" gorm:"size:20"` // 应用关闭期间错过的运行：skip 跳过, once 启动后补跑一次
+	NextRunAt   *time.Time `json:"nextRunAt"`
+	LastRunAt   *time.Time `json:"lastRunAt"`
+	LastSuccess bool       `json:"lastSuccess"`
+	LastError   string     `json:"lastError" gorm:"type:text"`
+	LastRunID   uint       `json:"lastRunId"` // 最近一次运行记录 ID
+	CreatedAt   time.Time  `json:"createdAt"`
+	UpdatedAt   time.Time  `json:"updatedAt"`
+}
+
+// EnvProfile 脚本环境配置（如 dev、staging），提供变量取值和工作目录
+type EnvProfile struct {
+	ID        uint              `json:"id" gorm:"primaryKey"`
+	Name      string            `json:"name" gorm:"size:100;not null"`
+	Variables map[string]string `json:"variables" gorm:"serializer:json;type:text"` // 同时作为占位变量取值和环境变量
+	WorkDir   string            `json:"workDir" gorm:"size:500"`
+	CreatedAt time.Time         `json:"createdAt"`
+	UpdatedAt time.Time         `json:"updatedAt"`
+}
+
+// Secret 脚本密钥，值使用主密钥加密保存，永不返回给前端
+type Secret struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	Name        string    `json:"name" gorm:"size:100;uniqueIndex;not null"` // 同时作为注入的环境变量名
+	Description string    `json:"description" gorm:"size:500"`
+	Ciphertext  []byte    `json:"-"`
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+// CodeBlock Markdown 笔记中的围栏代码块
+type CodeBlock struct {
+	Index    int    `json:"index"`    // 代码块序号，从 0 开始
+	Language string `json:"language"` // 围栏上标注的语言
+	Code     string `json:"code"`
+	Line     int    `json:"line"` // 起始围栏所在行号
+}
+
+// Conversation AI 多轮对话
+type Conversation struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	Title     string    `json:"title" gorm:"size:200"`
+	Provider  string    `json:"provider" gorm:"size:100"` // 对话使用的 AI 服务商，空表示默认服务商
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// ContextRef 对话关联的目录或笔记
+type ContextRef struct {
+	Type string `json:"type"` // category 或 note
+	ID   uint   `json:"id"`
+	Name string `json:"name"`
+}
+
+// ChatMessage 对话中的一条消息
+type ChatMessage struct {
+	ID             uint         `json:"id" gorm:"primaryKey"`
+	ConversationID uint         `json:"conversationId" gorm:"index"`
+	Role           string       `json:"role" gorm:"size:20"` // user 或 assistant
+	Content        string       `json:"content" gorm:"type:longtext"`
+	ContextRefs    []ContextRef `json:"contextRefs" gorm:"serializer:json;type:text"`  // 用户消息关联的上下文
+	Images         []string     `json:"images" gorm:"serializer:json;type:text"`       // 用户消息附带的图片，为图片存储目录中的相对路径
+	Citations      []Citation   `json:"citations" gorm:"serializer:json;type:text"`    // 助手消息引用的笔记片段
+	ContextNotes   []uint       `json:"contextNotes" gorm:"serializer:json;type:text"` // 助手消息实际放入上下文的笔记
+	Model          string       `json:"model" gorm:"size:100"`                         // 生成助手消息的模型
+	CreatedAt      time.Time    `json:"createdAt"`
+}
+
+// NoteChunk 笔记按标题切分的片段及其向量，用于语义检索
+type NoteChunk struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	NoteID    uint      `json:"noteId" gorm:"index"`
+	Seq       uint      `json:"seq"` // 片段在笔记中的序号
+	Heading   string    `json:"heading" gorm:"size:300"`
+	Content   string    `json:"content" gorm:"type:longtext"`
+	Hash      string    `json:"hash" gorm:"size:64"`         // 内容和向量模型的哈希，未变化时复用向量
+	Model     string    `json:"model" gorm:"size:100;index"` // 计算向量使用的模型
+	Dim       int       `json:"dim"`
+	Vector    []byte    `json:"-"` // 小端 float32 序列
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// AIUsage 一次 AI 请求的用量记录
+type AIUsage struct {
+	ID               uint      `json:"id" gorm:"primaryKey"`
+	Provider         string    `json:"provider" gorm:"size:100;index"`
+	Model            string    `json:"model" gorm:"size:100;index"`
+	Source           string    `json:"source" gorm:"size:20"` // chat、stream、tool、embedding
+	PromptTokens     int       `json:"promptTokens"`
+	CompletionTokens int       `json:"completionTokens"`
+	Estimated        bool      `json:"estimated"` // 服务商未返回用量，token 数为本地估算
+	LatencyMs        int64     `json:"latencyMs"`
+	Status           string    `json:"status" gorm:"size:20"`    // ok、error、canceled
+	ErrorKind        string    `json:"errorKind" gorm:"size:30"` // 失败时的错误类型
+	Cost             float64   `json:"cost"`                     // 按记录时的模型价格估算的费用
+	CreatedAt        time.Time `json:"createdAt" gorm:"index"`
+}
+
+// PromptTemplate 提示词模板，可作为目录的默认系统提示词，也可在对话中插入
+// 可用变量：{{selection}} 选中的内容，{{note_title}} 笔记标题，{{category}} 目录名称
+type PromptTemplate struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	Name        string    `json:"name" gorm:"size:100;uniqueIndex"`
+	Description string    `json:"description" gorm:"size:300"`
+	Content     string    `json:"content" gorm:"type:longtext"`
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+// Quiz 由笔记生成的测验
+type Quiz struct {
+	ID         uint           `json:"id" gorm:"primaryKey"`
+	Title      string         `json:"title" gorm:"size:200"`
+	Style      string         `json:"style" gorm:"size:20"` // choice、short、mixed
+	CategoryID uint           `json:"categoryId" gorm:"index"`
+	NoteID     uint           `json:"noteId" gorm:"index"`      // 由单篇笔记生成时的笔记 ID
+	Provider   string         `json:"provider" gorm:"size:100"` // 生成测验的 AI 服务商，批改简答题时沿用
+	Model      string         `json:"model" gorm:"size:100"`
+	Questions  []QuizQuestion `json:"questions" gorm:"foreignKey:QuizID"`
+	CreatedAt  time.Time      `json:"createdAt"`
+}
+
+// QuizQuestion 测验中的一道题
+type QuizQuestion struct {
+	ID          uint     `json:"id" gorm:"primaryKey"`
+	QuizID      uint     `json:"quizId" gorm:"index"`
+	Seq         int      `json:"seq"`
+	Type        string   `json:"type" gorm:"size:20"` // choice 或 short
+	Question    string   `json:"question" gorm:"type:text"`
+	Options     []string `json:"options" gorm:"serializer:json;type:text"` // 选择题的选项
+	Correct     int      `json:"correct"`                                  // 选择题正确选项的序号，从 0 开始
+	Answer      string   `json:"answer" gorm:"type:text"`                  // 参考答案，选择题为正确选项的内容
+	Explanation string   `json:"explanation" gorm:"type:text"`
+	NoteID      uint     `json:"noteId" gorm:"index"` // 出题依据的笔记
+}
+
+// QuizAttempt 一次作答
+type QuizAttempt struct {
+	ID        uint         `json:"id" gorm:"primaryKey"`
+	QuizID    uint         `json:"quizId" gorm:"index"`
+	Score     float64      `json:"score"` // 百分制得分
+	Correct   int          `json:"correct"`
+	Total     int          `json:"total"`
+	Answers   []QuizAnswer `json:"answers" gorm:"foreignKey:AttemptID"`
+	CreatedAt time.Time    `json:"createdAt"`
+}
+
+// QuizAnswer 作答中一道题的答案和得分
+type QuizAnswer struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	AttemptID  uint      `json:"attemptId" gorm:"index"`
+	QuizID     uint      `json:"quizId" gorm:"index"`
+	QuestionID uint      `json:"questionId"`
+	NoteID     uint      `json:"noteId" gorm:"index"`
+	Response   string    `json:"response" gorm:"type:text"` // 作答内容，选择题为所选选项的内容
+	Score      float64   `json:"score"`                     // 0 到 1
+	IsCorrect  bool      `json:"isCorrect"`
+	Feedback   string    `json:"feedback" gorm:"type:text"` // 简答题的批改意见
+	CreatedAt  time.Time `json:"createdAt" gorm:"index"`
 }
@@ -0,0 +1,24 @@
+//go:build !windows
+
+package backend
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// setProcessGroup 让脚本在独立的进程组中运行，便于整体终止
+func setProcessGroup(cmd *exec.Cmd) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Setpgid = true
+}
+
+// killProcessGroup 终止脚本及其所有子进程
+func killProcessGroup(cmd *exec.Cmd) error {
+	if cmd.Process == nil {
+		return nil
+	}
+	return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+}
@@ -0,0 +1,15 @@
+//go:build windows
+
+package backend
+
+import "os/exec"
+
+// Windows 上没有进程组信号，直接终止主进程
+func setProcessGroup(cmd *exec.Cmd) {}
+
+func killProcessGroup(cmd *exec.Cmd) error {
+	if cmd.Process == nil {
+		return nil
+	}
+	return cmd.Process.Kill()
+}
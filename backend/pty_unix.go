@@ -0,0 +1,24 @@
+//go:build !windows
+
+package backend
+
+import (
+	"os"
+	"os/exec"
+
+	"github.com/creack/pty"
+)
+
+// startPTY 在伪终端中启动命令，返回伪终端主端
+func startPTY(cmd *exec.Cmd, cols uint16, rows uint16) (*os.File, error) {
+	// pty 会为子进程创建新会话，与 Setpgid 冲突；会话首进程的进程组号即其 PID，仍可整体终止
+	if cmd.SysProcAttr != nil {
+		cmd.SysProcAttr.Setpgid = false
+	}
+	return pty.StartWithSize(cmd, &pty.Winsize{Cols: cols, Rows: rows})
+}
+
+// resizePTY 调整伪终端窗口大小
+func resizePTY(f *os.File, cols uint16, rows uint16) error {
+	return pty.Setsize(f, &pty.Winsize{Cols: cols, Rows: rows})
+}
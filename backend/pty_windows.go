@@ -0,0 +1,19 @@
+//go:build windows
+
+package backend
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+)
+
+var errPTYUnsupported = errors.New("当前平台不支持终端会话")
+
+func startPTY(cmd *exec.Cmd, cols uint16, rows uint16) (*os.File, error) {
+	return nil, errPTYUnsupported
+}
+
+func resizePTY(f *os.File, cols uint16, rows uint16) error {
+	return errPTYUnsupported
+}
@@ -0,0 +1,617 @@
+package backend
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 测验题型
+const (
+	QuizChoice = "choice" // 单选题
+	QuizShort  = "short"  // 简答题
+	QuizMixed  = "mixed"  // 单选题和简答题混合，仅用于出题
+)
+
+const (
+	// defaultQuizCount 未指定题数时的题数
+	defaultQuizCount = 5
+	// maxQuizCount 一次最多生成的题数
+	maxQuizCount = 20
+	// maxQuizOptions 选择题最多的选项数
+	maxQuizOptions = 6
+	// quizTimeout 出题和批改的超时时间
+	quizTimeout = 3 * time.Minute
+	// quizJSONRetries 模型输出不符合格式时要求其修正的次数
+	quizJSONRetries = 1
+	// quizPassScore 简答题得分不低于该值视为答对
+	quizPassScore = 0.6
+)
+
+// quizSystemPrompt 出题的系统提示词，笔记内容附在其后
+const quizSystemPrompt = `你是一名出题老师，根据用户的学习笔记出题，帮助用户检验对笔记内容的掌握程度。
+题目的答案必须能在笔记中找到，不要考察笔记以外的知识。
+只输出一个 JSON 对象，不要输出其他文字或代码块标记，格式如下：
+{"questions":[
+  {"type":"choice","question":"题干","options":["选项","选项","选项","选项"],"answer":0,"explanation":"解析","note_id":12},
+  {"type":"short","question":"题干","answer":"参考答案","explanation":"解析","note_id":12}
+]}
+type 为 choice（单选题）或 short（简答题）。选择题有 2 到 6 个选项且只有一个正确，answer 为正确选项的序号（从 0 开始）；简答题的 answer 为简洁的参考答案。
+note_id 为出题依据的笔记编号，即上下文中 [笔记 编号: 标题] 里的编号。`
+
+// quizGradePrompt 批改简答题的系统提示词
+const quizGradePrompt = `你是一名阅卷老师，对照参考答案为用户的简答题作答打分。
+分数为 0 到 1 之间的小数，意思正确即可得分，不要求与参考答案措辞一致。
+只输出一个 JSON 对象，不要输出其他文字或代码块标记，格式如下：
+{"grades":[{"id":题目编号,"score":0.8,"feedback":"简短的批改意见"}]}
+每道题都需要给出分数。`
+
+// quizStyleHints 各出题方式对题型的要求
+var quizStyleHints = map[string]string{
+	QuizChoice: "全部为单选题",
+	QuizShort:  "全部为简答题",
+	QuizMixed:  "单选题和简答题大约各占一半",
+}
+
+// QuizResponse 一道题的作答
+type QuizResponse struct {
+	QuestionID uint   `json:"questionId"`
+	Choice     int    `json:"choice"` // 选择题所选选项的序号，-1 表示未作答
+	Text       string `json:"text"`   // 简答题的作答
+}
+
+// QuizSummary 测验列表中的一项
+type QuizSummary struct {
+	ID            uint       `json:"id"`
+	Title         string     `json:"title"`
+	Style         string     `json:"style"`
+	CategoryID    uint       `json:"categoryId"`
+	NoteID        uint       `json:"noteId"`
+	QuestionCount int        `json:"questionCount"`
+	AttemptCount  int        `json:"attemptCount"`
+	BestScore     float64    `json:"bestScore"`
+	LastScore     float64    `json:"lastScore"`
+	LastAttemptAt *time.Time `json:"lastAttemptAt"`
+	CreatedAt     time.Time  `json:"createdAt"`
+}
+
+// QuizNoteStat 按笔记统计的作答情况
+type QuizNoteStat struct {
+	NoteID      uint       `json:"noteId"`
+	Title       string     `json:"title"`
+	CategoryID  uint       `json:"categoryId"`
+	Answered    int        `json:"answered"`
+	Wrong       int        `json:"wrong"`
+	WrongRate   float64    `json:"wrongRate"`
+	LastWrongAt *time.Time `json:"lastWrongAt"`
+}
+
+// extractJSONObject 取出模型输出中的 JSON 对象，兼容包在代码块中或前后带有说明文字的输出
+func extractJSONObject(output string) (string, error) {
+	output = cleanActionOutput(output)
+	start := strings.Index(output, "{")
+	end := strings.LastIndex(output, "}")
+	if start < 0 || end < start {
+		return "", errors.New("输出中没有 JSON 对象")
+	}
+	return output[start : end+1], nil
+}
+
+// completeJSON 请求模型输出 JSON 并交给 parse 校验，不符合要求时把错误反馈给模型重新生成
+func completeJSON(ctx context.Context, p AIProvider, messages []chatMessage, parse func(data []byte) error) error {
+	for attempt := 0; ; attempt++ {
+		output, err := completeChat(ctx, p, messages)
+		if err != nil {
+			return err
+		}
+		data, err := extractJSONObject(output)
+		if err == nil {
+			err = parse([]byte(data))
+		}
+		if err == nil {
+			return nil
+		}
+		if attempt >= quizJSONRetries {
+			return fmt.Errorf("AI 输出的格式不正确: %v", err)
+		}
+		log.Printf("[Quiz] 输出不符合要求，要求重新生成: %v", err)
+		messages = append(messages,
+			chatMessage{Role: "assistant", Content: output},
+			chatMessage{Role: "user", Content: "上面的输出不符合要求：" + err.Error() + "。请按要求的格式重新输出完整的 JSON 对象。"},
+		)
+	}
+}
+
+// quizDraft 模型输出的题目
+type quizDraft struct {
+	Questions []struct {
+		Type        string          `json:"type"`
+		Question    string          `json:"question"`
+		Options     []string        `json:"options"`
+		Answer      json.RawMessage `json:"answer"`
+		Explanation string          `json:"explanation"`
+		NoteID      uint            `json:"note_id"`
+	} `json:"questions"`
+}
+
+// choiceIndex 解析选择题的答案，接受序号、字母（A、B...）或选项内容
+func choiceIndex(raw json.RawMessage, options []string) (int, error) {
+	var n int
+	if err := json.Unmarshal(raw, &n); err == nil {
+		if n < 0 || n >= len(options) {
+			return 0, fmt.Errorf("answer 序号 %d 超出选项范围", n)
+		}
+		return n, nil
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return 0, errors.New("answer 应为正确选项的序号")
+	}
+	s = strings.TrimSpace(s)
+	if n, err := strconv.Atoi(s); err == nil && n >= 0 && n < len(options) {
+		return n, nil
+	}
+	if len(s) == 1 && s[0] >= 'A' && int(s[0]-'A') < len(options) {
+		return int(s[0] - 'A'), nil
+	}
+	for i, opt := range options {
+		if opt == s {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("answer %q 不是有效的选项", s)
+}
+
+// parseQuizQuestions 按格式要求校验模型输出的题目
+// 题型需符合出题方式，note_id 需为提供的笔记之一；只提供了一篇笔记时可省略 note_id
+func parseQuizQuestions(data []byte, style string, count int, notes map[uint]bool) ([]QuizQuestion, error) {
+	var draft quizDraft
+	if err := json.Unmarshal(data, &draft); err != nil {
+		return nil, fmt.Errorf("JSON 解析失败: %v", err)
+	}
+	if len(draft.Questions) == 0 {
+		return nil, errors.New("questions 为空")
+	}
+	var onlyNote uint
+	if len(notes) == 1 {
+		for id := range notes {
+			onlyNote = id
+		}
+	}
+	var list []QuizQuestion
+	var problems []string
+	for i, d := range draft.Questions {
+		if len(list) >= count {
+			break
+		}
+		q := QuizQuestion{
+			Seq:         len(list) + 1,
+			Type:        strings.TrimSpace(d.Type),
+			Question:    strings.TrimSpace(d.Question),
+			Explanation: strings.TrimSpace(d.Explanation),
+			NoteID:      d.NoteID,
+		}
+		if q.NoteID == 0 {
+			q.NoteID = onlyNote
+		}
+		var err error
+		switch {
+		case q.Type != QuizChoice && q.Type != QuizShort:
+			err = fmt.Errorf("type 应为 choice 或 short，而不是 %q", q.Type)
+		case style != QuizMixed && q.Type != style:
+			err = fmt.Errorf("题型应为 %s", style)
+		case q.Question == "":
+			err = errors.New("question 为空")
+		case !notes[q.NoteID]:
+			err = fmt.Errorf("note_id %d 不是提供的笔记编号", d.NoteID)
+		case q.Type == QuizChoice:
+			err = validateChoiceQuestion(&q, d.Options, d.Answer)
+		default:
+			var answer interface{}
+			if json.Unmarshal(d.Answer, &answer) == nil && answer != nil {
+				q.Answer = strings.TrimSpace(fmt.Sprint(answer))
+			}
+			if q.Answer == "" {
+				err = errors.New("简答题缺少参考答案")
+			}
+		}
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("第 %d 题 %v", i+1, err))
+			continue
+		}
+		list = append(list, q)
+	}
+	if len(problems) > 0 {
+		if len(problems) > 3 {
+			problems = append(problems[:3], fmt.Sprintf("等 %d 处问题", len(problems)))
+		}
+		return nil, errors.New(strings.Join(problems, "；"))
+	}
+	return list, nil
+}
+
+// validateChoiceQuestion 校验选择题的选项和答案
+func validateChoiceQuestion(q *QuizQuestion, options []string, answer json.RawMessage) error {
+	seen := map[string]bool{}
+	for _, opt := range options {
+		opt = strings.TrimSpace(opt)
+		if opt == "" {
+			return errors.New("选项为空")
+		}
+		if seen[opt] {
+			return fmt.Errorf("选项 %q 重复", opt)
+		}
+		seen[opt] = true
+		q.Options = append(q.Options, opt)
+	}
+	if len(q.Options) < 2 || len(q.Options) > maxQuizOptions {
+		return fmt.Errorf("选项应为 2 到 %d 个", maxQuizOptions)
+	}
+	correct, err := choiceIndex(answer, q.Options)
+	if err != nil {
+		return err
+	}
+	q.Correct = correct
+	q.Answer = q.Options[correct]
+	return nil
+}
+
+// GenerateQuiz 根据笔记或目录（含子目录）中的笔记生成测验
+// noteID 非 0 时只根据该笔记出题，否则根据 categoryID 对应的目录出题
+// count: 题数，0 表示默认 5 道，最多 20 道
+// style: choice 单选题、short 简答题、mixed 混合，为空时为 mixed
+// provider: AI 服务商名称，为空时使用默认服务商
+func (a *App) GenerateQuiz(categoryID uint, noteID uint, count int, style string, provider string) (*Quiz, error) {
+	if style == "" {
+		style = QuizMixed
+	}
+	hint, ok := quizStyleHints[style]
+	if !ok {
+		return nil, fmt.Errorf("不支持的出题方式: %s", style)
+	}
+	if count <= 0 {
+		count = defaultQuizCount
+	}
+	count = min(count, maxQuizCount)
+
+	quiz := &Quiz{Style: style, CategoryID: categoryID, NoteID: noteID, Provider: provider}
+	var refs []ContextRef
+	switch {
+	case noteID != 0:
+		var note Note
+		if err := DB.Select("id", "title", "category_id").First(&note, noteID).Error; err != nil {
+			return nil, fmt.Errorf("笔记不存在: %v", err)
+		}
+		quiz.Title = note.Title
+		quiz.CategoryID = note.CategoryID
+		refs = []ContextRef{{Type: "note", ID: noteID}}
+	case categoryID != 0:
+		var cat Category
+		if err := DB.Select("id", "name").First(&cat, categoryID).Error; err != nil {
+			return nil, fmt.Errorf("目录不存在: %v", err)
+		}
+		quiz.Title = cat.Name
+		refs = []ContextRef{{Type: "category", ID: categoryID}}
+	default:
+		return nil, errors.New("请选择出题的目录或笔记")
+	}
+
+	p, err := resolveAIProvider(provider)
+	if err != nil {
+		return nil, err
+	}
+	quiz.Model = p.Model
+
+	items := a.collectContextItems(refs)
+	if len(items) == 0 {
+		return nil, errors.New("没有可以出题的笔记内容")
+	}
+	// 上下文中带上笔记编号，供模型在 note_id 中引用
+	for i := range items {
+		items[i].Label = fmt.Sprintf("笔记 %d", items[i].NoteID)
+	}
+	userTurn := chatMessage{Role: "user", Content: fmt.Sprintf("请出 %d 道题，%s。题目尽量覆盖不同的笔记和知识点。", count, hint)}
+	budget := contextBudget(p, quizSystemPrompt, []chatMessage{userTurn})
+	texts, report := fitContext(items, "", currentContextStrategy(), budget)
+	notes := map[uint]bool{}
+	for _, entry := range report.Included {
+		notes[entry.NoteID] = true
+	}
+	if len(notes) == 0 {
+		return nil, errors.New("笔记内容超出模型上下文窗口")
+	}
+	messages := []chatMessage{{Role: "system", Content: buildSystemPrompt(quizSystemPrompt, texts)}, userTurn}
+
+	ctx, cancel := context.WithTimeout(context.Background(), quizTimeout)
+	defer cancel()
+	startTime := time.Now()
+	err = completeJSON(ctx, p, messages, func(data []byte) error {
+		questions, err := parseQuizQuestions(data, style, count, notes)
+		quiz.Questions = questions
+		return err
+	})
+	if err != nil {
+		log.Printf("[Quiz] 出题失败 (笔记: %d 篇, 耗时: %v): %v", len(notes), time.Since(startTime), err)
+		return nil, err
+	}
+	quiz.Title += " 测验"
+	if err := DB.Create(quiz).Error; err != nil {
+		return nil, fmt.Errorf("保存测验失败: %v", err)
+	}
+	log.Printf("[Quiz] 测验已生成 (Quiz ID: %d, 题数: %d/%d, 笔记: %d 篇, 略过: %d 段, 耗时: %v)",
+		quiz.ID, len(quiz.Questions), count, len(notes), len(report.Omitted), time.Since(startTime))
+	return quiz, nil
+}
+
+// ListQuizzes 获取测验列表及作答情况，按创建时间倒序
+func (a *App) ListQuizzes() ([]QuizSummary, error) {
+	var quizzes []Quiz
+	if err := DB.Order("created_at desc").Find(&quizzes).Error; err != nil {
+		return nil, err
+	}
+	var counts []struct {
+		QuizID uint
+		Count  int
+	}
+	DB.Model(&QuizQuestion{}).Select("quiz_id, count(*) as count").Group("quiz_id").Scan(&counts)
+	questionCount := map[uint]int{}
+	for _, c := range counts {
+		questionCount[c.QuizID] = c.Count
+	}
+	var attempts []QuizAttempt
+	if err := DB.Order("id asc").Find(&attempts).Error; err != nil {
+		return nil, err
+	}
+
+	list := make([]QuizSummary, 0, len(quizzes))
+	index := map[uint]int{}
+	for _, q := range quizzes {
+		index[q.ID] = len(list)
+		list = append(list, QuizSummary{
+			ID:            q.ID,
+			Title:         q.Title,
+			Style:         q.Style,
+			CategoryID:    q.CategoryID,
+			NoteID:        q.NoteID,
+			QuestionCount: questionCount[q.ID],
+			CreatedAt:     q.CreatedAt,
+		})
+	}
+	for _, at := range attempts {
+		i, ok := index[at.QuizID]
+		if !ok {
+			continue
+		}
+		s := &list[i]
+		s.AttemptCount++
+		s.BestScore = max(s.BestScore, at.Score)
+		s.LastScore = at.Score
+		createdAt := at.CreatedAt
+		s.LastAttemptAt = &createdAt
+	}
+	return list, nil
+}
+
+// GetQuiz 获取测验及其题目
+func (a *App) GetQuiz(id uint) (*Quiz, error) {
+	var quiz Quiz
+	err := DB.Preload("Questions", func(db *gorm.DB) *gorm.DB { return db.Order("seq asc") }).First(&quiz, id).Error
+	if err != nil {
+		return nil, fmt.Errorf("测验不存在: %v", err)
+	}
+	return &quiz, nil
+}
+
+// DeleteQuiz 删除测验及其作答记录
+func (a *App) DeleteQuiz(id uint) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		for _, model := range []interface{}{&QuizAnswer{}, &QuizAttempt{}, &QuizQuestion{}} {
+			if err := tx.Where("quiz_id = ?", id).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Delete(&Quiz{}, id).Error
+	})
+}
+
+// gradeShortAnswers 请模型批改简答题，返回各题的得分和批改意见，key 为题目 ID
+func gradeShortAnswers(p AIProvider, questions []QuizQuestion, responses map[uint]string) (map[uint]QuizAnswer, error) {
+	var sb strings.Builder
+	for _, q := range questions {
+		fmt.Fprintf(&sb, "题目编号: %d\n题目: %s\n参考答案: %s\n用户作答: %s\n\n", q.ID, q.Question, q.Answer, responses[q.ID])
+	}
+	messages := []chatMessage{
+		{Role: "system", Content: quizGradePrompt},
+		{Role: "user", Content: strings.TrimSpace(sb.String())},
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), quizTimeout)
+	defer cancel()
+
+	var grades map[uint]QuizAnswer
+	err := completeJSON(ctx, p, messages, func(data []byte) error {
+		var resp struct {
+			Grades []struct {
+				ID       uint     `json:"id"`
+				Score    *float64 `json:"score"`
+				Feedback string   `json:"feedback"`
+			} `json:"grades"`
+		}
+		if err := json.Unmarshal(data, &resp); err != nil {
+			return fmt.Errorf("JSON 解析失败: %v", err)
+		}
+		grades = map[uint]QuizAnswer{}
+		for _, g := range resp.Grades {
+			if _, ok := responses[g.ID]; !ok {
+				return fmt.Errorf("题目编号 %d 不存在", g.ID)
+			}
+			if g.Score == nil || *g.Score < 0 || *g.Score > 1 {
+				return fmt.Errorf("题目 %d 的 score 应为 0 到 1 之间的数", g.ID)
+			}
+			grades[g.ID] = QuizAnswer{Score: *g.Score, Feedback: strings.TrimSpace(g.Feedback)}
+		}
+		for _, q := range questions {
+			if _, ok := grades[q.ID]; !ok {
+				return fmt.Errorf("缺少题目 %d 的分数", q.ID)
+			}
+		}
+		return nil
+	})
+	return grades, err
+}
+
+// SubmitQuizAttempt 提交一次作答并评分，选择题直接判分，简答题由 AI 对照参考答案批改
+// 返回保存的作答记录，包含每道题的得分
+func (a *App) SubmitQuizAttempt(quizID uint, responses []QuizResponse) (*QuizAttempt, error) {
+	quiz, err := a.GetQuiz(quizID)
+	if err != nil {
+		return nil, err
+	}
+	if len(quiz.Questions) == 0 {
+		return nil, errors.New("测验没有题目")
+	}
+	byQuestion := map[uint]QuizResponse{}
+	for _, r := range responses {
+		byQuestion[r.QuestionID] = r
+	}
+
+	attempt := &QuizAttempt{QuizID: quiz.ID, Total: len(quiz.Questions)}
+	var toGrade []QuizQuestion
+	shortAnswers := map[uint]string{}
+	for _, q := range quiz.Questions {
+		r, ok := byQuestion[q.ID]
+		answer := QuizAnswer{QuizID: quiz.ID, QuestionID: q.ID, NoteID: q.NoteID}
+		if q.Type == QuizChoice {
+			if ok && r.Choice >= 0 && r.Choice < len(q.Options) {
+				answer.Response = q.Options[r.Choice]
+				if r.Choice == q.Correct {
+					answer.Score = 1
+				}
+			}
+		} else if text := strings.TrimSpace(r.Text); ok && text != "" {
+			answer.Response = text
+			toGrade = append(toGrade, q)
+			shortAnswers[q.ID] = text
+		}
+		attempt.Answers = append(attempt.Answers, answer)
+	}
+
+	if len(toGrade) > 0 {
+		p, err := resolveAIProvider(quiz.Provider)
+		if err != nil && quiz.Provider != "" {
+			// 出题的服务商已被删除时使用默认服务商
+			p, err = resolveAIProvider("")
+		}
+		if err != nil {
+			return nil, err
+		}
+		grades, err := gradeShortAnswers(p, toGrade, shortAnswers)
+		if err != nil {
+			log.Printf("[Quiz] 批改失败 (Quiz ID: %d): %v", quiz.ID, err)
+			return nil, fmt.Errorf("批改简答题失败: %v", err)
+		}
+		for i := range attempt.Answers {
+			if g, ok := grades[attempt.Answers[i].QuestionID]; ok {
+				attempt.Answers[i].Score = g.Score
+				attempt.Answers[i].Feedback = g.Feedback
+			}
+		}
+	}
+
+	total := 0.0
+	for i := range attempt.Answers {
+		ans := &attempt.Answers[i]
+		ans.IsCorrect = ans.Score >= quizPassScore
+		if ans.IsCorrect {
+			attempt.Correct++
+		}
+		total += ans.Score
+	}
+	attempt.Score = math.Round(total*1000/float64(attempt.Total)) / 10
+	if err := DB.Create(attempt).Error; err != nil {
+		return nil, fmt.Errorf("保存作答失败: %v", err)
+	}
+	log.Printf("[Quiz] 作答已提交 (Quiz ID: %d, 得分: %.1f, 答对: %d/%d)", quiz.ID, attempt.Score, attempt.Correct, attempt.Total)
+	return attempt, nil
+}
+
+// ListQuizAttempts 获取测验的作答记录，按时间倒序
+func (a *App) ListQuizAttempts(quizID uint) ([]QuizAttempt, error) {
+	var list []QuizAttempt
+	err := DB.Preload("Answers").Where("quiz_id = ?", quizID).Order("id desc").Find(&list).Error
+	return list, err
+}
+
+// GetQuizNoteStats 按笔记统计所有测验的作答情况，答错次数多的排在前面
+// categoryID 非 0 时只统计该目录（含子目录）中的笔记
+func (a *App) GetQuizNoteStats(categoryID uint) ([]QuizNoteStat, error) {
+	q := DB.Model(&QuizAnswer{}).Select("note_id", "is_correct", "created_at").Where("note_id <> 0")
+	if categoryID != 0 {
+		notes, err := a.ListNotes(&categoryID)
+		if err != nil {
+			return nil, err
+		}
+		ids := []uint{}
+		for _, n := range notes {
+			ids = append(ids, n.ID)
+		}
+		q = q.Where("note_id IN ?", ids)
+	}
+	var answers []QuizAnswer
+	if err := q.Find(&answers).Error; err != nil {
+		return nil, err
+	}
+
+	stats := map[uint]*QuizNoteStat{}
+	for _, ans := range answers {
+		s := stats[ans.NoteID]
+		if s == nil {
+			s = &QuizNoteStat{NoteID: ans.NoteID}
+			stats[ans.NoteID] = s
+		}
+		s.Answered++
+		if !ans.IsCorrect {
+			s.Wrong++
+			if s.LastWrongAt == nil || ans.CreatedAt.After(*s.LastWrongAt) {
+				createdAt := ans.CreatedAt
+				s.LastWrongAt = &createdAt
+			}
+		}
+	}
+	ids := make([]uint, 0, len(stats))
+	for id := range stats {
+		ids = append(ids, id)
+	}
+	var notes []Note
+	if err := DB.Select("id", "title", "category_id").Where("id IN ?", ids).Find(&notes).Error; err != nil {
+		return nil, err
+	}
+
+	// 已删除的笔记不再统计
+	list := make([]QuizNoteStat, 0, len(notes))
+	for _, n := range notes {
+		s := stats[n.ID]
+		s.Title = n.Title
+		s.CategoryID = n.CategoryID
+		s.WrongRate = float64(s.Wrong) / float64(s.Answered)
+		list = append(list, *s)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Wrong != list[j].Wrong {
+			return list[i].Wrong > list[j].Wrong
+		}
+		if list[i].WrongRate != list[j].WrongRate {
+			return list[i].WrongRate > list[j].WrongRate
+		}
+		return list[i].NoteID < list[j].NoteID
+	})
+	return list, nil
+}
@@ -0,0 +1,35 @@
+//go:build !windows
+
+package backend
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// applyResourceLimits 通过 sh 的 ulimit 设置 CPU 时间和地址空间上限后 exec 原命令
+// ulimit -v 限制的是地址空间（RLIMIT_AS）而不是实际占用的内存，预留大量地址空间的运行时（如 Node.js）需要设置得足够大
+// 进程号不变，进程组终止仍然有效；系统不支持的限制（如 macOS 的 -v）会被忽略
+func applyResourceLimits(cmd *exec.Cmd, cpuSeconds uint64, memoryMB uint64) {
+	if cmd.Err != nil || (cpuSeconds == 0 && memoryMB == 0) {
+		return
+	}
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		return
+	}
+
+	var limits []string
+	if cpuSeconds > 0 {
+		limits = append(limits, fmt.Sprintf("ulimit -t %d 2>/dev/null;", cpuSeconds))
+	}
+	if memoryMB > 0 {
+		limits = append(limits, fmt.Sprintf("ulimit -v %d 2>/dev/null;", memoryMB*1024))
+	}
+	script := strings.Join(limits, " ") + ` exec "$@"`
+
+	args := []string{"sh", "-c", script, "eaiser-script", cmd.Path}
+	cmd.Args = append(args, cmd.Args[1:]...)
+	cmd.Path = sh
+}
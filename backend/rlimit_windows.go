@@ -0,0 +1,8 @@
+//go:build windows
+
+package backend
+
+import "os/exec"
+
+// applyResourceLimits Windows 下暂不支持资源限制
+func applyResourceLimits(cmd *exec.Cmd, cpuSeconds uint64, memoryMB uint64) {}
@@ -0,0 +1,374 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// EventScheduleFailed 定时运行失败事件，数据为 ScheduleFailure
+const EventScheduleFailed = "script-schedule-failed"
+
+// 错过运行的补跑策略
+const (
+	CatchUpSkip = "skip" // 跳过，从当前时间计算下次运行
+	CatchUpOnce = "once" // 启动后补跑一次
+)
+
+// schedulerMaxSleep 调度循环的最长休眠时间，避免系统休眠后长时间不检查
+const schedulerMaxSleep = time.Minute
+
+// ScheduleFailure 定时运行失败事件
+type ScheduleFailure struct {
+	ScheduleID uint   `json:"scheduleId"`
+	NoteID     uint   `json:"noteId"`
+	Title      string `json:"title"`
+	Error      string `json:"error"`
+	HistoryID  uint   `json:"historyId"`
+}
+
+func parseCron(expr string) (cron.Schedule, error) {
+	sched, err := cron.ParseStandard(strings.TrimSpace(expr))
+	if err != nil {
+		return nil, fmt.Errorf("cron 表达式无效: %v", err)
+	}
+	return sched, nil
+}
+
+func normalizeCatchUp(policy string) (string, error) {
+	switch policy {
+	case "", CatchUpSkip:
+		return CatchUpSkip, nil
+	case CatchUpOnce:
+		return CatchUpOnce, nil
+	}
+	return "", fmt.Errorf("未知的补跑策略: %s", policy)
+}
+
+// startScheduler 启动调度协程，先按补跑策略处理应用关闭期间错过的运行
+func (a *App) startScheduler() {
+	ctx, cancel := context.WithCancel(context.Background())
+	a.schedMu.Lock()
+	a.schedCancel = cancel
+	a.schedMu.Unlock()
+
+	a.catchUpSchedules(ctx)
+
+	a.schedWG.Add(1)
+	go func() {
+		defer a.schedWG.Done()
+		a.schedulerLoop(ctx)
+	}()
+	log.Printf("[Schedule] 调度器已启动")
+}
+
+// stopScheduler 停止调度协程，取消并等待正在进行的定时运行
+func (a *App) stopScheduler() {
+	a.schedMu.Lock()
+	cancel := a.schedCancel
+	a.schedCancel = nil
+	a.schedMu.Unlock()
+	if cancel == nil {
+		return
+	}
+	cancel()
+	a.schedWG.Wait()
+	log.Printf("[Schedule] 调度器已停止")
+}
+
+// wakeScheduler 计划变更后通知调度协程重新计算
+func (a *App) wakeScheduler() {
+	select {
+	case a.schedWake <- struct{}{}:
+	default:
+	}
+}
+
+// catchUpSchedules 处理应用关闭期间错过的运行
+func (a *App) catchUpSchedules(ctx context.Context) {
+	now := time.Now()
+	list, err := enabledSchedules()
+	if err != nil {
+		log.Printf("[Schedule] 查询定时计划失败: %v", err)
+		return
+	}
+	for i := range list {
+		s := &list[i]
+		if s.NextRunAt != nil && !s.NextRunAt.Before(now) {
+			continue
+		}
+		if s.CatchUp == CatchUpOnce {
+			log.Printf("[Schedule] 补跑错过的运行 (Schedule ID: %d, Note ID: %d, 原定 %s)",
+				s.ID, s.NoteID, formatRunTime(s.NextRunAt))
+			a.dispatchSchedule(ctx, s, now)
+			continue
+		}
+		log.Printf("[Schedule] 跳过错过的运行 (Schedule ID: %d, Note ID: %d)", s.ID, s.NoteID)
+		advanceSchedule(s, now)
+	}
+}
+
+// schedulerLoop 休眠到最近的运行时间，到期后运行计划
+func (a *App) schedulerLoop(ctx context.Context) {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-a.schedWake:
+		case <-timer.C:
+		}
+
+		now := time.Now()
+		list, err := enabledSchedules()
+		if err != nil {
+			log.Printf("[Schedule] 查询定时计划失败: %v", err)
+		}
+		sleep := schedulerMaxSleep
+		for i := range list {
+			s := &list[i]
+			if s.NextRunAt == nil || !s.NextRunAt.After(now) {
+				a.dispatchSchedule(ctx, s, now)
+			}
+			if s.NextRunAt == nil {
+				continue
+			}
+			if d := time.Until(*s.NextRunAt); d < sleep {
+				sleep = max(d, time.Second)
+			}
+		}
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(sleep)
+	}
+}
+
+func formatRunTime(t *time.Time) string {
+	if t == nil {
+		return "-"
+	}
+	return t.Format(time.DateTime)
+}
+
+// enabledSchedules 查询所有启用的计划，计划数量很少，到期判断在内存中完成
+func enabledSchedules() ([]ScriptSchedule, error) {
+	var list []ScriptSchedule
+	err := DB.Where("enabled = ?", true).Find(&list).Error
+	return list, err
+}
+
+// advanceSchedule 从 from 开始计算并保存下次运行时间
+func advanceSchedule(s *ScriptSchedule, from time.Time) {
+	sched, err := parseCron(s.Cron)
+	if err != nil {
+		log.Printf("[Schedule] 计划 %d %v，已停用", s.ID, err)
+		DB.Model(&ScriptSchedule{}).Where("id = ?", s.ID).Updates(map[string]interface{}{"enabled": false, "next_run_at": nil})
+		s.NextRunAt = nil
+		return
+	}
+	next := sched.Next(from)
+	s.NextRunAt = &next
+	DB.Model(&ScriptSchedule{}).Where("id = ?", s.ID).UpdateColumn("next_run_at", next)
+}
+
+// dispatchSchedule 推进下次运行时间并在后台运行计划，同一计划上一次未结束时跳过本次
+func (a *App) dispatchSchedule(ctx context.Context, s *ScriptSchedule, now time.Time) {
+	advanceSchedule(s, now)
+	if s.NextRunAt == nil {
+		return
+	}
+
+	a.schedMu.Lock()
+	if a.schedRunning[s.ID] {
+		a.schedMu.Unlock()
+		log.Printf("[Schedule] 上一次运行尚未结束，跳过 (Schedule ID: %d)", s.ID)
+		return
+	}
+	a.schedRunning[s.ID] = true
+	a.schedMu.Unlock()
+
+	a.schedWG.Add(1)
+	go func() {
+		defer a.schedWG.Done()
+		defer func() {
+			a.schedMu.Lock()
+			delete(a.schedRunning, s.ID)
+			a.schedMu.Unlock()
+		}()
+		a.runSchedule(ctx, s)
+	}()
+}
+
+// runSchedule 运行计划对应的脚本，结果写入运行记录，失败时发送事件
+func (a *App) runSchedule(ctx context.Context, s *ScriptSchedule) {
+	startedAt := time.Now()
+	title := ""
+	result := func() *ScriptResult {
+		note, err := loadScriptNote(s.NoteID)
+		if err != nil {
+			return &ScriptResult{Error: err.Error(), ExitCode: -1}
+		}
+		title = note.Title
+		log.Printf("[Schedule] 定时运行脚本 (Schedule ID: %d, Note ID: %d): %s", s.ID, s.NoteID, note.Title)
+
+		runCtx, cancel := context.WithTimeout(ctx, scriptTimeout(note))
+		defer cancel()
+		script, err := a.prepareNoteScript(runCtx, note, &ScriptRunOptions{ProfileID: s.ProfileID})
+		if err != nil {
+			return &ScriptResult{Error: err.Error(), ExitCode: -1}
+		}
+		defer script.cleanup()
+
+		result := runScriptCommand(script.cmd)
+		script.masker.maskResult(result)
+		if result.Success {
+			return result
+		}
+		if ctx.Err() != nil {
+			result.Error = "应用退出，定时运行已取消"
+		} else if runCtx.Err() == context.DeadlineExceeded {
+			result.Error = fmt.Sprintf("脚本执行超时 (%v)", scriptTimeout(note))
+		}
+		return result
+	}()
+
+	recordScriptRun(s.NoteID, "schedule", startedAt, result)
+	DB.Model(&ScriptSchedule{}).Where("id = ?", s.ID).Updates(map[string]interface{}{
+		"last_run_at":  startedAt,
+		"last_success": result.Success,
+		"last_error":   result.Error,
+		"last_run_id":  result.HistoryID,
+	})
+
+	if result.Success {
+		log.Printf("[Schedule] 定时运行成功 (Schedule ID: %d, Note ID: %d)", s.ID, s.NoteID)
+		return
+	}
+	log.Printf("[Schedule] 定时运行失败 (Schedule ID: %d, Note ID: %d): %s", s.ID, s.NoteID, result.Error)
+	a.emit(EventScheduleFailed, ScheduleFailure{
+		ScheduleID: s.ID,
+		NoteID:     s.NoteID,
+		Title:      title,
+		Error:      result.Error,
+		HistoryID:  result.HistoryID,
+	})
+}
+
+// CreateScriptSchedule 为命令行工具笔记创建定时运行计划
+// cronExpr: 标准 5 段 cron 表达式，或 @hourly、@daily、@every 30m 等描述符
+// catchUp: 错过运行的补跑策略，skip 或 once，空值为 skip
+func (a *App) CreateScriptSchedule(noteID uint, cronExpr string, profileID uint, catchUp string) (*ScriptSchedule, error) {
+	if _, err := loadScriptNote(noteID); err != nil {
+		return nil, err
+	}
+	sched, err := parseCron(cronExpr)
+	if err != nil {
+		return nil, err
+	}
+	catchUp, err = normalizeCatchUp(catchUp)
+	if err != nil {
+		return nil, err
+	}
+
+	next := sched.Next(time.Now())
+	s := &ScriptSchedule{
+		NoteID:    noteID,
+		Cron:      strings.TrimSpace(cronExpr),
+		Enabled:   true,
+		ProfileID: profileID,
+		CatchUp:   catchUp,
+		NextRunAt: &next,
+	}
+	if err := DB.Create(s).Error; err != nil {
+		return nil, fmt.Errorf("创建定时计划失败: %v", err)
+	}
+	log.Printf("[Schedule] 计划已创建 (Schedule ID: %d, Note ID: %d, cron: %s)", s.ID, noteID, s.Cron)
+	a.wakeScheduler()
+	return s, nil
+}
+
+// ListScriptSchedules 获取定时运行计划，noteID 为 0 时返回全部
+func (a *App) ListScriptSchedules(noteID uint) ([]ScriptSchedule, error) {
+	var list []ScriptSchedule
+	q := DB.Order("id asc")
+	if noteID != 0 {
+		q = q.Where("note_id = ?", noteID)
+	}
+	err := q.Find(&list).Error
+	return list, err
+}
+
+// UpdateScriptSchedule 修改定时运行计划，重新计算下次运行时间
+func (a *App) UpdateScriptSchedule(id uint, cronExpr string, profileID uint, catchUp string, enabled bool) error {
+	var s ScriptSchedule
+	if err := DB.First(&s, id).Error; err != nil {
+		return fmt.Errorf("定时计划不存在: %v", err)
+	}
+	sched, err := parseCron(cronExpr)
+	if err != nil {
+		return err
+	}
+	catchUp, err = normalizeCatchUp(catchUp)
+	if err != nil {
+		return err
+	}
+
+	updates := map[string]interface{}{
+		"cron":        strings.TrimSpace(cronExpr),
+		"profile_id":  profileID,
+		"catch_up":    catchUp,
+		"enabled":     enabled,
+		"next_run_at": nil,
+	}
+	if enabled {
+		updates["next_run_at"] = sched.Next(time.Now())
+	}
+	if err := DB.Model(&ScriptSchedule{}).Where("id = ?", id).Updates(updates).Error; err != nil {
+		return err
+	}
+	a.wakeScheduler()
+	return nil
+}
+
+// DeleteScriptSchedule 删除定时运行计划
+func (a *App) DeleteScriptSchedule(id uint) error {
+	if err := DB.Delete(&ScriptSchedule{}, id).Error; err != nil {
+		return err
+	}
+	a.wakeScheduler()
+	return nil
+}
+
+// PreviewCronSchedule 预览 cron 表达式接下来的运行时间
+func (a *App) PreviewCronSchedule(cronExpr string, count int) ([]time.Time, error) {
+	sched, err := parseCron(cronExpr)
+	if err != nil {
+		return nil, err
+	}
+	if count <= 0 || count > 20 {
+		count = 5
+	}
+	times := make([]time.Time, 0, count)
+	t := time.Now()
+	for i := 0; i < count; i++ {
+		t = sched.Next(t)
+		if t.IsZero() {
+			break
+		}
+		times = append(times, t)
+	}
+	if len(times) == 0 {
+		return nil, errors.New("cron 表达式不会再触发")
+	}
+	return times, nil
+}
@@ -0,0 +1,330 @@
+package backend
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// defaultScriptTimeout 笔记未设置超时时间时的默认脚本超时
+const defaultScriptTimeout = 30 * time.Second
+
+// scriptTimeout 获取笔记的脚本超时时间
+func scriptTimeout(note *Note) time.Duration {
+	if note.ScriptTimeout > 0 {
+		return time.Duration(note.ScriptTimeout) * time.Second
+	}
+	return defaultScriptTimeout
+}
+
+// Interpreter 脚本解释器配置
+type Interpreter struct {
+	Command []string `json:"command"` // 命令及参数，{file} 替换为脚本文件路径，未出现时追加在末尾
+	Ext     string   `json:"ext"`     // 脚本文件扩展名，.go 会在临时模块中以 go run 执行
+}
+
+// defaultInterpreters 默认的语言到解释器映射
+func defaultInterpreters() map[string]Interpreter {
+	sh := Interpreter{Command: []string{"sh"}, Ext: ".sh"}
+	bash := Interpreter{Command: []string{"bash"}, Ext: ".sh"}
+	python := Interpreter{Command: []string{"python3"}, Ext: ".py"}
+	node := Interpreter{Command: []string{"node"}, Ext: ".js"}
+	return map[string]Interpreter{
+		"sh":         sh,
+		"shell":      sh,
+		"bash":       bash,
+		"zsh":        {Command: []string{"zsh"}, Ext: ".zsh"},
+		"python":     python,
+		"python3":    python,
+		"py":         python,
+		"node":       node,
+		"javascript": node,
+		"js":         node,
+		"ruby":       {Command: []string{"ruby"}, Ext: ".rb"},
+		"perl":       {Command: []string{"perl"}, Ext: ".pl"},
+		"php":        {Command: []string{"php"}, Ext: ".php"},
+		"lua":        {Command: []string{"lua"}, Ext: ".lua"},
+		"go":         {Command: []string{"go", "run", "."}, Ext: ".go"},
+	}
+}
+
+func copyInterpreters(src map[string]Interpreter) map[string]Interpreter {
+	dst := make(map[string]Interpreter, len(src))
+	for k, v := range src {
+		dst[k] = Interpreter{Command: append([]string(nil), v.Command...), Ext: v.Ext}
+	}
+	return dst
+}
+
+// resolveInterpreter 根据语言查找解释器
+// strict 为 false 时未知语言回退到 sh（命令行工具笔记的历史行为）
+func resolveInterpreter(language string, strict bool) (Interpreter, error) {
+	lang := strings.ToLower(strings.TrimSpace(language))
+	cfgMu.RLock()
+	interp, ok := Cfg.Interpreters[lang]
+	if !ok && (lang == "" || !strict) {
+		interp, ok = Cfg.Interpreters["sh"]
+	}
+	cfgMu.RUnlock()
+	if !ok || len(interp.Command) == 0 {
+		return Interpreter{}, fmt.Errorf("不支持的脚本语言: %s，请在配置文件 interpreters 中添加", language)
+	}
+	return interp, nil
+}
+
+// prepareScript 将脚本写入临时目录并构造执行命令
+// 首行为 shebang 时使用 shebang 指定的解释器，否则按 language 查找
+// 返回的 cleanup 用于删除临时目录
+func prepareScript(ctx context.Context, language string, content string, strict bool) (*exec.Cmd, func(), error) {
+	var argv []string
+	var ext string
+	if strings.HasPrefix(content, "#!") {
+		firstLine, _, _ := strings.Cut(content, "\n")
+		argv = strings.Fields(strings.TrimPrefix(firstLine, "#!"))
+		if len(argv) == 0 {
+			return nil, nil, errors.New("无效的 shebang 行")
+		}
+	} else {
+		interp, err := resolveInterpreter(language, strict)
+		if err != nil {
+			return nil, nil, err
+		}
+		argv = append([]string(nil), interp.Command...)
+		ext = interp.Ext
+	}
+
+	dir, err := os.MkdirTemp("", "eaiser-script-*")
+	if err != nil {
+		return nil, nil, fmt.Errorf("创建临时目录失败: %v", err)
+	}
+	cleanup := func() { os.RemoveAll(dir) }
+
+	fileName := "script" + ext
+	goModule := ext == ".go"
+	if goModule {
+		// Go 脚本放到临时模块中，以 go run 执行
+		fileName = "main.go"
+		if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module eaiserscript\n\ngo 1.21\n"), 0644); err != nil {
+			cleanup()
+			return nil, nil, fmt.Errorf("写入 go.mod 失败: %v", err)
+		}
+	}
+	filePath := filepath.Join(dir, fileName)
+	if err := os.WriteFile(filePath, []byte(content), 0700); err != nil {
+		cleanup()
+		return nil, nil, fmt.Errorf("写入脚本文件失败: %v", err)
+	}
+
+	replaced := false
+	for i, arg := range argv {
+		if strings.Contains(arg, "{file}") {
+			argv[i] = strings.ReplaceAll(arg, "{file}", filePath)
+			replaced = true
+		}
+	}
+	if !replaced && !goModule {
+		argv = append(argv, filePath)
+	}
+
+	cmd := exec.CommandContext(ctx, argv[0], argv[1:]...)
+	if goModule {
+		cmd.Dir = dir
+	}
+	// 超时或取消时终止整个进程组，避免子进程占用输出管道导致 Wait 无法返回
+	setProcessGroup(cmd)
+	cmd.Cancel = func() error { return killProcessGroup(cmd) }
+	cmd.WaitDelay = 2 * time.Second
+	return cmd, cleanup, nil
+}
+
+// runScriptCommand 执行命令并收集输出
+func runScriptCommand(cmd *exec.Cmd) *ScriptResult {
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+
+	result := &ScriptResult{
+		Stdout:  stdout.String(),
+		Stderr:  stderr.String(),
+		Success: err == nil,
+	}
+	if cmd.ProcessState != nil {
+		result.ExitCode = cmd.ProcessState.ExitCode()
+	}
+	if err != nil {
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// ExecuteScript 执行命令行工具脚本
+// noteID: 笔记 ID
+// opts: 占位变量取值和环境配置，可为 nil
+// 解释器由首行 shebang 或笔记的 Language 决定
+// 返回: ScriptResult
+func (a *App) ExecuteScript(noteID uint, opts *ScriptRunOptions) (*ScriptResult, error) {
+	// 查询并校验笔记
+	note, err := loadScriptNote(noteID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 记录执行日志
+	log.Printf("执行脚本 (Note ID: %d, Language: %s): %s", noteID, note.Language, note.Title)
+
+	// 创建执行上下文，设置超时
+	ctx, cancel := context.WithTimeout(context.Background(), scriptTimeout(note))
+	defer cancel()
+
+	script, err := a.prepareNoteScript(ctx, note, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer script.cleanup()
+
+	startedAt := time.Now()
+	result := runScriptCommand(script.cmd)
+	script.masker.maskResult(result)
+	recordScriptRun(noteID, "manual", startedAt, result)
+
+	// 如果命令执行失败，记录错误信息
+	if !result.Success {
+		log.Printf("脚本执行失败 (Note ID: %d): %s, stderr: %s", noteID, result.Error, result.Stderr)
+	} else {
+		log.Printf("脚本执行成功 (Note ID: %d), stdout: %s", noteID, result.Stdout)
+	}
+
+	return result, nil
+}
+
+var codeFenceRegex = regexp.MustCompile("^ {0,3}(`{3,}|~{3,})\\s*([^`\\s]*)")
+
+// parseCodeBlocks 解析 Markdown 中的围栏代码块
+func parseCodeBlocks(md string) []CodeBlock {
+	var blocks []CodeBlock
+	var current *CodeBlock
+	var fence string
+	var lines []string
+	for i, line := range strings.Split(md, "\n") {
+		if current == nil {
+			if m := codeFenceRegex.FindStringSubmatch(line); m != nil {
+				fence = m[1]
+				current = &CodeBlock{Index: len(blocks), Language: m[2], Line: i + 1}
+				lines = nil
+			}
+			continue
+		}
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, fence) && strings.Trim(trimmed, fence[:1]) == "" {
+			current.Code = strings.Join(lines, "\n")
+			blocks = append(blocks, *current)
+			current = nil
+			continue
+		}
+		lines = append(lines, line)
+	}
+	// 未闭合的代码块延续到文末
+	if current != nil {
+		current.Code = strings.Join(lines, "\n")
+		blocks = append(blocks, *current)
+	}
+	return blocks
+}
+
+// ListCodeBlocks 获取笔记中的围栏代码块
+func (a *App) ListCodeBlocks(noteID uint) ([]CodeBlock, error) {
+	var note Note
+	if err := DB.First(&note, noteID).Error; err != nil {
+		return nil, fmt.Errorf("笔记不存在: %v", err)
+	}
+	blocks := parseCodeBlocks(note.ContentMD)
+	if blocks == nil {
+		blocks = []CodeBlock{}
+	}
+	return blocks, nil
+}
+
+// ExecuteCodeBlock 执行 Markdown 笔记中指定序号的代码块
+// blockIndex: 代码块序号，从 0 开始
+func (a *App) ExecuteCodeBlock(noteID uint, blockIndex int) (*ScriptResult, error) {
+	var note Note
+	if err := DB.First(&note, noteID).Error; err != nil {
+		return nil, fmt.Errorf("笔记不存在: %v", err)
+	}
+	policy := currentScriptPolicy()
+	if err := checkScriptTrust(&note, policy); err != nil {
+		return nil, err
+	}
+	blocks := parseCodeBlocks(note.ContentMD)
+	if blockIndex < 0 || blockIndex >= len(blocks) {
+		return nil, fmt.Errorf("代码块不存在: %d", blockIndex)
+	}
+	block := blocks[blockIndex]
+	if strings.TrimSpace(block.Code) == "" {
+		return nil, errors.New("代码块内容为空")
+	}
+	if err := checkDangerousContent(block.Code, policy); err != nil {
+		return nil, err
+	}
+
+	log.Printf("执行代码块 (Note ID: %d, Block: %d, Language: %s)", noteID, blockIndex, block.Language)
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultScriptTimeout)
+	defer cancel()
+
+	cmd, cleanup, err := prepareScript(ctx, block.Language, block.Code, true)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+	if err := applyScriptSandbox(cmd, policy, nil, ""); err != nil {
+		return nil, err
+	}
+
+	startedAt := time.Now()
+	result := runScriptCommand(cmd)
+	recordScriptRun(noteID, "block", startedAt, result)
+	if !result.Success {
+		log.Printf("代码块执行失败 (Note ID: %d, Block: %d): %s, stderr: %s", noteID, blockIndex, result.Error, result.Stderr)
+	}
+	return result, nil
+}
+
+// GetInterpreters 获取语言到解释器的映射
+func (a *App) GetInterpreters() map[string]Interpreter {
+	cfgMu.RLock()
+	defer cfgMu.RUnlock()
+	return copyInterpreters(Cfg.Interpreters)
+}
+
+// UpdateInterpreters 整体替换解释器映射并保存到配置文件，传入空映射时恢复默认值
+func (a *App) UpdateInterpreters(interpreters map[string]Interpreter) error {
+	cfgMu.Lock()
+	if len(interpreters) == 0 {
+		Cfg.Interpreters = defaultInterpreters()
+	} else {
+		Cfg.Interpreters = map[string]Interpreter{}
+		for lang, interp := range interpreters {
+			Cfg.Interpreters[strings.ToLower(strings.TrimSpace(lang))] = interp
+		}
+	}
+	saveConfig := snapshotConfigFile()
+	filePath := configFilePath
+	cfgMu.Unlock()
+
+	if err := writeConfigFile(filePath, saveConfig); err != nil {
+		return fmt.Errorf("保存配置文件失败: %v", err)
+	}
+	log.Printf("[Config] 解释器映射已更新，共 %d 项", len(saveConfig.Interpreters))
+	return nil
+}
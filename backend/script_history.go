@@ -0,0 +1,132 @@
+package backend
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// scriptRunOutputLimit 每条运行记录保存的 stdout / stderr 上限（字节）
+	scriptRunOutputLimit = 64 * 1024
+	// scriptRunKeepPerNote 每个笔记保留的运行记录条数
+	scriptRunKeepPerNote = 200
+)
+
+// truncateOutput 截断输出，只保留末尾部分
+func truncateOutput(s string, limit int) string {
+	if len(s) <= limit {
+		return s
+	}
+	cut := len(s) - limit
+	for cut < len(s) && !utf8.RuneStart(s[cut]) {
+		cut++
+	}
+	return fmt.Sprintf("...(已截断前 %d 字节)\n", cut) + s[cut:]
+}
+
+// recordScriptRun 保存脚本运行记录，并把记录 ID 写回 result
+func recordScriptRun(noteID uint, trigger string, startedAt time.Time, result *ScriptResult) {
+	run := &ScriptRun{
+		NoteID:     noteID,
+		Trigger:    trigger,
+		StartedAt:  startedAt,
+		DurationMs: time.Since(startedAt).Milliseconds(),
+		ExitCode:   result.ExitCode,
+		Success:    result.Success,
+		Error:      result.Error,
+		Stdout:     truncateOutput(result.Stdout, scriptRunOutputLimit),
+		Stderr:     truncateOutput(result.Stderr, scriptRunOutputLimit),
+	}
+	if err := DB.Create(run).Error; err != nil {
+		log.Printf("保存脚本运行记录失败 (Note ID: %d): %v", noteID, err)
+		return
+	}
+	result.HistoryID = run.ID
+
+	// 清理超出保留条数的旧记录
+	var staleIDs []uint
+	DB.Model(&ScriptRun{}).Where("note_id = ?", noteID).Order("id desc").
+		Offset(scriptRunKeepPerNote).Pluck("id", &staleIDs)
+	if len(staleIDs) > 0 {
+		DB.Delete(&ScriptRun{}, staleIDs)
+	}
+}
+
+// ListScriptRuns 获取笔记的脚本运行记录（不含输出内容），按时间倒序
+// limit: 返回条数，0 表示全部
+func (a *App) ListScriptRuns(noteID uint, limit int) ([]ScriptRun, error) {
+	var list []ScriptRun
+	q := DB.Omit("stdout", "stderr").Where("note_id = ?", noteID).Order("id desc")
+	if limit > 0 {
+		q = q.Limit(limit)
+	}
+	err := q.Find(&list).Error
+	return list, err
+}
+
+// GetScriptRun 获取单条脚本运行记录
+func (a *App) GetScriptRun(id uint) (*ScriptRun, error) {
+	var run ScriptRun
+	if err := DB.First(&run, id).Error; err != nil {
+		return nil, fmt.Errorf("运行记录不存在: %v", err)
+	}
+	return &run, nil
+}
+
+// DeleteScriptRun 删除单条脚本运行记录
+func (a *App) DeleteScriptRun(id uint) error {
+	return DB.Delete(&ScriptRun{}, id).Error
+}
+
+// AppendScriptRunToNote 将运行记录的输出以代码块形式追加到 Markdown 笔记末尾
+func (a *App) AppendScriptRunToNote(runID uint, noteID uint) error {
+	run, err := a.GetScriptRun(runID)
+	if err != nil {
+		return err
+	}
+	var note Note
+	if err := DB.First(&note, noteID).Error; err != nil {
+		return fmt.Errorf("笔记不存在: %v", err)
+	}
+	if note.Type != 0 {
+		return errors.New("只能追加到 Markdown 笔记")
+	}
+
+	var sb strings.Builder
+	sb.WriteString(strings.TrimRight(note.ContentMD, "\n"))
+	if sb.Len() > 0 {
+		sb.WriteString("\n\n")
+	}
+	sb.WriteString(fmt.Sprintf("**运行结果** %s · 退出码 %d · 耗时 %dms\n\n",
+		run.StartedAt.Format("2006-01-02 15:04:05"), run.ExitCode, run.DurationMs))
+	writeFencedBlock(&sb, "text", run.Stdout)
+	if strings.TrimSpace(run.Stderr) != "" {
+		sb.WriteString("\nstderr:\n\n")
+		writeFencedBlock(&sb, "text", run.Stderr)
+	}
+
+	return a.UpdateNoteMD(note.ID, note.Title, note.Language, sb.String(), note.CategoryID)
+}
+
+// writeFencedBlock 写入围栏代码块，围栏长度大于内容中最长的连续反引号
+func writeFencedBlock(sb *strings.Builder, lang string, content string) {
+	longest, current := 0, 0
+	for _, r := range content {
+		if r == '`' {
+			current++
+			if current > longest {
+				longest = current
+			}
+		} else {
+			current = 0
+		}
+	}
+	fence := strings.Repeat("`", max(3, longest+1))
+	sb.WriteString(fence + lang + "\n")
+	sb.WriteString(strings.TrimRight(content, "\n"))
+	sb.WriteString("\n" + fence + "\n")
+}
@@ -0,0 +1,280 @@
+package backend
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// 脚本策略错误码
+const (
+	PolicyUntrusted = "untrusted" // 脚本内容未经确认（新建、导入或修改后）
+	PolicyDangerous = "dangerous" // 脚本包含危险命令且策略要求拦截
+	PolicyWorkDir   = "workdir"   // 工作目录无效
+)
+
+// ScriptPolicy 脚本执行策略
+type ScriptPolicy struct {
+	RequireTrust   bool     `json:"requireTrust"`   // 运行前需要确认脚本内容，内容变化后需重新确认
+	BlockDangerous bool     `json:"blockDangerous"` // 已确认的脚本包含危险命令时仍拒绝执行
+	ScrubEnv       bool     `json:"scrubEnv"`       // 只向脚本传递白名单中的环境变量
+	EnvAllowlist   []string `json:"envAllowlist"`   // 环境变量白名单，以 _ 结尾的项按前缀匹配，如 LC_
+	CPUSeconds     uint64   `json:"cpuSeconds"`     // CPU 时间上限（秒），0 表示不限制，仅 Linux / macOS
+	MemoryMB       uint64   `json:"memoryMB"`       // 地址空间上限（MB，RLIMIT_AS），0 表示不限制，仅 Linux / macOS
+	WorkDir        string   `json:"workDir"`        // 默认工作目录，为空时继承应用的工作目录，环境配置中的目录优先
+}
+
+func defaultScriptPolicy() ScriptPolicy {
+	return ScriptPolicy{
+		RequireTrust:   true,
+		BlockDangerous: false,
+		ScrubEnv:       true,
+		EnvAllowlist: []string{
+			"PATH", "HOME", "USER", "LOGNAME", "SHELL", "LANG", "LC_", "TZ", "TMPDIR",
+			"GOPATH", "GOROOT", "GOCACHE", "GOPROXY",
+			// Windows 下程序运行所需的基础变量
+			"SYSTEMROOT", "WINDIR", "COMSPEC", "PATHEXT", "TEMP", "TMP", "USERPROFILE", "APPDATA", "LOCALAPPDATA",
+		},
+		CPUSeconds: 600,
+		// 地址空间上限默认不启用：Node.js 等 JIT / GC 运行时启动时就会预留数 GB 地址空间
+		MemoryMB: 0,
+	}
+}
+
+func currentScriptPolicy() ScriptPolicy {
+	cfgMu.RLock()
+	defer cfgMu.RUnlock()
+	policy := Cfg.ScriptPolicy
+	policy.EnvAllowlist = append([]string(nil), policy.EnvAllowlist...)
+	return policy
+}
+
+// PolicyViolation 策略检查发现的问题
+type PolicyViolation struct {
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+	Line    int    `json:"line"` // 所在行号，从 1 开始，0 表示与具体行无关
+	Snippet string `json:"snippet,omitempty"`
+}
+
+// ScriptPolicyError 策略拒绝执行时返回的错误，Error() 为 JSON，前端可解析后展示
+type ScriptPolicyError struct {
+	Code       string            `json:"code"`
+	Message    string            `json:"message"`
+	Violations []PolicyViolation `json:"violations,omitempty"`
+}
+
+func (e *ScriptPolicyError) Error() string {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return e.Message
+	}
+	return string(data)
+}
+
+// dangerousRule 危险命令规则
+type dangerousRule struct {
+	name    string
+	message string
+	re      *regexp.Regexp
+}
+
+var dangerousRules = []dangerousRule{
+	{"rm-root", "递归删除根目录、主目录或通配符路径", regexp.MustCompile(`\brm\s+(?:-\S+\s+)*-[a-zA-Z]*[rR][a-zA-Z]*\s+(?:-\S+\s+)*(?:/\*?|~/?\*?|\$HOME/?\*?|\*)(?:\s|;|&|\||$)`)},
+	{"pipe-shell", "下载内容直接交给 shell 执行", regexp.MustCompile(`\b(?:curl|wget)\b[^\n|]*\|\s*(?:sudo\s+)?(?:ba|z|k|da)?sh\b`)},
+	{"mkfs", "格式化文件系统", regexp.MustCompile(`\bmkfs(?:\.\w+)?\b`)},
+	{"dd-device", "直接写入磁盘设备", regexp.MustCompile(`\bdd\b[^\n]*\bof=/dev/(?:sd|hd|nvme|disk|mmcblk|xvd)`)},
+	{"redirect-device", "重定向输出到磁盘设备", regexp.MustCompile(`>\s*/dev/(?:sd|hd|nvme|disk|mmcblk|xvd)`)},
+	{"fork-bomb", "fork 炸弹", regexp.MustCompile(`:\(\)\s*\{\s*:\s*\|\s*:\s*&\s*\}\s*;\s*:`)},
+	{"chmod-root", "递归修改根目录权限", regexp.MustCompile(`\bch(?:mod|own)\s+(?:-\S+\s+)*-R\s+(?:-\S+\s+)*\S+\s+/(?:\s|;|&|$)`)},
+	{"format-drive", "格式化磁盘分区", regexp.MustCompile(`(?i)\bformat(?:\.com)?\s+[a-z]:`)},
+}
+
+// detectDangerous 逐行检查脚本中的危险命令
+func detectDangerous(content string) []PolicyViolation {
+	var list []PolicyViolation
+	for i, line := range strings.Split(content, "\n") {
+		for _, rule := range dangerousRules {
+			if loc := rule.re.FindStringIndex(line); loc != nil {
+				list = append(list, PolicyViolation{
+					Rule:    rule.name,
+					Message: rule.message,
+					Line:    i + 1,
+					Snippet: strings.TrimSpace(line[loc[0]:loc[1]]),
+				})
+			}
+		}
+	}
+	return list
+}
+
+// scriptContentHash 脚本内容的信任哈希，语言变化同样需要重新确认
+func scriptContentHash(note *Note) string {
+	sum := sha256.Sum256([]byte(note.Language + "\x00" + strings.TrimSpace(note.ContentMD)))
+	return hex.EncodeToString(sum[:])
+}
+
+// checkScriptTrust 检查笔记内容是否已确认
+func checkScriptTrust(note *Note, policy ScriptPolicy) error {
+	if !policy.RequireTrust || note.TrustedHash == scriptContentHash(note) {
+		return nil
+	}
+	return &ScriptPolicyError{
+		Code:       PolicyUntrusted,
+		Message:    "脚本内容尚未确认，请检查后确认执行",
+		Violations: detectDangerous(note.ContentMD),
+	}
+}
+
+// checkDangerousContent 按策略拦截包含危险命令的脚本，content 为替换变量后的实际内容
+func checkDangerousContent(content string, policy ScriptPolicy) error {
+	if !policy.BlockDangerous {
+		return nil
+	}
+	if list := detectDangerous(content); len(list) > 0 {
+		return &ScriptPolicyError{Code: PolicyDangerous, Message: "脚本包含危险命令，已拒绝执行", Violations: list}
+	}
+	return nil
+}
+
+// scriptBaseEnv 脚本的基础环境变量，开启清理时只保留白名单中的变量
+func scriptBaseEnv(policy ScriptPolicy) []string {
+	if !policy.ScrubEnv {
+		return os.Environ()
+	}
+	var env []string
+	for _, kv := range os.Environ() {
+		name, _, _ := strings.Cut(kv, "=")
+		upper := strings.ToUpper(name)
+		for _, allowed := range policy.EnvAllowlist {
+			allowed = strings.ToUpper(allowed)
+			if upper == allowed || (strings.HasSuffix(allowed, "_") && strings.HasPrefix(upper, allowed)) {
+				env = append(env, kv)
+				break
+			}
+		}
+	}
+	return env
+}
+
+// applyScriptSandbox 为脚本命令设置环境变量、工作目录和资源限制
+// extraEnv 追加在基础环境之后；workDir 为空时使用策略中的默认目录
+func applyScriptSandbox(cmd *exec.Cmd, policy ScriptPolicy, extraEnv []string, workDir string) error {
+	cmd.Env = append(scriptBaseEnv(policy), extraEnv...)
+
+	if workDir == "" {
+		workDir = policy.WorkDir
+	}
+	// Go 脚本需要在临时模块目录中运行，不应用工作目录
+	if workDir != "" && cmd.Dir == "" {
+		dir, err := expandHome(workDir)
+		if err == nil {
+			var info os.FileInfo
+			if info, err = os.Stat(dir); err == nil && !info.IsDir() {
+				err = errors.New("不是目录")
+			}
+		}
+		if err != nil {
+			return &ScriptPolicyError{Code: PolicyWorkDir, Message: fmt.Sprintf("工作目录无效: %s (%v)", workDir, err)}
+		}
+		cmd.Dir = dir
+	}
+
+	applyResourceLimits(cmd, policy.CPUSeconds, policy.MemoryMB)
+	return nil
+}
+
+func expandHome(path string) (string, error) {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, strings.TrimPrefix(path, "~")), nil
+}
+
+// ScriptPolicyReport 运行前的策略检查结果
+type ScriptPolicyReport struct {
+	Trusted    bool              `json:"trusted"`
+	Violations []PolicyViolation `json:"violations"`
+}
+
+// CheckScriptPolicy 检查笔记脚本是否已确认以及包含的危险命令，供运行前提示
+func (a *App) CheckScriptPolicy(noteID uint) (*ScriptPolicyReport, error) {
+	var note Note
+	if err := DB.First(&note, noteID).Error; err != nil {
+		return nil, fmt.Errorf("笔记不存在: %v", err)
+	}
+	policy := currentScriptPolicy()
+	report := &ScriptPolicyReport{
+		Trusted:    checkScriptTrust(&note, policy) == nil,
+		Violations: detectDangerous(note.ContentMD),
+	}
+	if report.Violations == nil {
+		report.Violations = []PolicyViolation{}
+	}
+	return report, nil
+}
+
+// TrustScript 确认笔记当前的脚本内容，内容或语言修改后需要重新确认
+func (a *App) TrustScript(noteID uint) error {
+	var note Note
+	if err := DB.First(&note, noteID).Error; err != nil {
+		return fmt.Errorf("笔记不存在: %v", err)
+	}
+	hash := scriptContentHash(&note)
+	if err := DB.Model(&Note{}).Where("id = ?", noteID).UpdateColumn("trusted_hash", hash).Error; err != nil {
+		return fmt.Errorf("保存确认状态失败: %v", err)
+	}
+	log.Printf("[Policy] 脚本已确认 (Note ID: %d, hash: %s)", noteID, hash[:12])
+	return nil
+}
+
+// UntrustScript 撤销笔记脚本的确认
+func (a *App) UntrustScript(noteID uint) error {
+	return DB.Model(&Note{}).Where("id = ?", noteID).UpdateColumn("trusted_hash", "").Error
+}
+
+// GetScriptPolicy 获取脚本执行策略
+func (a *App) GetScriptPolicy() ScriptPolicy {
+	return currentScriptPolicy()
+}
+
+// UpdateScriptPolicy 更新脚本执行策略并保存到配置文件
+func (a *App) UpdateScriptPolicy(policy ScriptPolicy) error {
+	if policy.WorkDir != "" {
+		dir, err := expandHome(policy.WorkDir)
+		if err != nil {
+			return err
+		}
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+			return fmt.Errorf("工作目录不存在: %s", policy.WorkDir)
+		}
+	}
+	if policy.EnvAllowlist == nil {
+		policy.EnvAllowlist = []string{}
+	}
+
+	cfgMu.Lock()
+	Cfg.ScriptPolicy = policy
+	saveConfig := snapshotConfigFile()
+	filePath := configFilePath
+	cfgMu.Unlock()
+
+	if err := writeConfigFile(filePath, saveConfig); err != nil {
+		return fmt.Errorf("保存配置文件失败: %v", err)
+	}
+	log.Printf("[Config] 脚本执行策略已更新: requireTrust=%v, blockDangerous=%v, scrubEnv=%v, cpu=%ds, memory=%dMB",
+		policy.RequireTrust, policy.BlockDangerous, policy.ScrubEnv, policy.CPUSeconds, policy.MemoryMB)
+	return nil
+}
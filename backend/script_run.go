@@ -0,0 +1,190 @@
+package backend
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// 脚本运行事件
+const (
+	EventScriptOutput = "script-output" // 输出片段，数据为 ScriptOutputChunk
+	EventScriptExit   = "script-exit"   // 运行结束，数据为 ScriptExit
+)
+
+// ScriptOutputChunk 脚本输出片段
+type ScriptOutputChunk struct {
+	RunID  string `json:"runId"`
+	Stream string `json:"stream"` // stdout 或 stderr
+	Data   string `json:"data"`
+}
+
+// ScriptExit 脚本运行结束事件
+type ScriptExit struct {
+	RunID    string        `json:"runId"`
+	NoteID   uint          `json:"noteId"`
+	Canceled bool          `json:"canceled"`
+	Result   *ScriptResult `json:"result"`
+}
+
+// scriptRun 正在运行的脚本
+type scriptRun struct {
+	id       string
+	noteID   uint
+	cancel   context.CancelFunc
+	canceled bool
+}
+
+// scriptStreamWriter 将输出写入缓冲区，同时以事件形式推送给前端
+type scriptStreamWriter struct {
+	app    *App
+	runID  string
+	stream string
+	mu     *sync.Mutex
+	buf    bytes.Buffer
+	masker *secretMasker
+}
+
+func (w *scriptStreamWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	w.buf.Write(p)
+	w.mu.Unlock()
+	// 按片段遮盖密钥；跨片段的密钥值在最终结果中再次遮盖
+	w.app.emit(EventScriptOutput, ScriptOutputChunk{RunID: w.runID, Stream: w.stream, Data: w.masker.mask(string(p))})
+	return len(p), nil
+}
+
+func (w *scriptStreamWriter) String() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.masker.mask(w.buf.String())
+}
+
+func newRunID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// StartScript 异步执行命令行工具脚本
+// 输出通过 script-output 事件推送，结束时发送 script-exit 事件
+// opts: 占位变量取值和环境配置，可为 nil
+// 返回: runID，可用于 CancelScript
+func (a *App) StartScript(noteID uint, opts *ScriptRunOptions) (string, error) {
+	note, err := loadScriptNote(noteID)
+	if err != nil {
+		return "", err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), scriptTimeout(note))
+	script, err := a.prepareNoteScript(ctx, note, opts)
+	if err != nil {
+		cancel()
+		return "", err
+	}
+	cmd, cleanup := script.cmd, script.cleanup
+
+	runID := newRunID()
+	var mu sync.Mutex
+	stdout := &scriptStreamWriter{app: a, runID: runID, stream: "stdout", mu: &mu, masker: script.masker}
+	stderr := &scriptStreamWriter{app: a, runID: runID, stream: "stderr", mu: &mu, masker: script.masker}
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	startedAt := time.Now()
+	if err := cmd.Start(); err != nil {
+		cancel()
+		cleanup()
+		log.Printf("脚本启动失败 (Note ID: %d): %v", noteID, err)
+		return "", fmt.Errorf("脚本启动失败: %v", err)
+	}
+
+	run := &scriptRun{id: runID, noteID: noteID, cancel: cancel}
+	a.scriptMu.Lock()
+	a.scriptRuns[runID] = run
+	a.scriptMu.Unlock()
+	log.Printf("脚本已启动 (Note ID: %d, Run ID: %s): %s", noteID, runID, note.Title)
+
+	go func() {
+		err := cmd.Wait()
+		cancel()
+		cleanup()
+
+		a.scriptMu.Lock()
+		delete(a.scriptRuns, runID)
+		canceled := run.canceled
+		a.scriptMu.Unlock()
+
+		result := &ScriptResult{
+			Stdout:  stdout.String(),
+			Stderr:  stderr.String(),
+			Success: err == nil,
+		}
+		if cmd.ProcessState != nil {
+			result.ExitCode = cmd.ProcessState.ExitCode()
+		}
+		if err != nil {
+			result.Error = err.Error()
+			if ctx.Err() == context.DeadlineExceeded {
+				result.Error = fmt.Sprintf("脚本执行超时 (%v)", scriptTimeout(note))
+			} else if canceled {
+				result.Error = "脚本已取消"
+			}
+			log.Printf("脚本执行失败 (Note ID: %d, Run ID: %s): %s", noteID, runID, result.Error)
+		} else {
+			log.Printf("脚本执行成功 (Note ID: %d, Run ID: %s)", noteID, runID)
+		}
+		recordScriptRun(noteID, "stream", startedAt, result)
+		a.emit(EventScriptExit, ScriptExit{RunID: runID, NoteID: noteID, Canceled: canceled, Result: result})
+	}()
+
+	return runID, nil
+}
+
+// CancelScript 取消正在运行的脚本，终止其整个进程组
+func (a *App) CancelScript(runID string) error {
+	a.scriptMu.Lock()
+	run, ok := a.scriptRuns[runID]
+	if ok {
+		run.canceled = true
+	}
+	a.scriptMu.Unlock()
+	if !ok {
+		return errors.New("脚本未在运行")
+	}
+
+	log.Printf("取消脚本 (Note ID: %d, Run ID: %s)", run.noteID, runID)
+	run.cancel()
+	return nil
+}
+
+// cancelAllScripts 取消所有正在运行的脚本，应用退出时调用
+func (a *App) cancelAllScripts() {
+	a.scriptMu.Lock()
+	defer a.scriptMu.Unlock()
+	for _, run := range a.scriptRuns {
+		run.canceled = true
+		run.cancel()
+	}
+}
+
+// UpdateScriptTimeout 设置命令行工具笔记的超时时间
+// seconds: 超时秒数，0 表示使用默认值
+func (a *App) UpdateScriptTimeout(noteID uint, seconds uint) error {
+	var note Note
+	if err := DB.First(&note, noteID).Error; err != nil {
+		return fmt.Errorf("笔记不存在: %v", err)
+	}
+
+	if note.Type != 2 {
+		return errors.New("该笔记不是命令行工具类型")
+	}
+
+	return DB.Model(&Note{}).Where("id = ?", noteID).Update("script_timeout", seconds).Error
+}
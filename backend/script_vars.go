@@ -0,0 +1,211 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// 脚本占位变量需要先在注释行中用 @var 声明，= 后为默认值，只有声明过的 {{name}} 会被替换：
+//
+//	# @var host=localhost
+//	# @var port
+//	ssh -p {{port}} {{host}} "docker ps --format '{{.Names}}'"
+//
+// 未声明的 {{...}}（如 docker、kubectl --format 中的 Go 模板）原样保留
+// 声明行以 #、//、--、;、:: 或 REM 开头，对常见的脚本语言都是注释
+
+// scriptVarDeclRegex 变量声明行，如 # @var host=localhost
+var scriptVarDeclRegex = regexp.MustCompile(`(?mi)^[ \t]*(?:#|//|--|;|::|rem\b)[ \t]*@var[ \t]+([a-z_][a-z0-9_]*)[ \t]*(?:=(.*))?$`)
+
+// scriptVarRegex 脚本中的占位变量 {{name}}
+var scriptVarRegex = regexp.MustCompile(`\{\{\s*([A-Za-z_][A-Za-z0-9_]*)\s*\}\}`)
+
+// ScriptVariable 脚本中声明的占位变量
+type ScriptVariable struct {
+	Name       string `json:"name"`
+	Default    string `json:"default"`
+	HasDefault bool   `json:"hasDefault"`
+}
+
+// ScriptRunOptions 运行脚本时的可选参数
+type ScriptRunOptions struct {
+	// Variables 占位变量取值，优先于环境配置和默认值
+	// 取值原样插入脚本，不做 shell 转义：含空格或引号的取值需要脚本自己加引号（如 "{{path}}"），不要传入不可信的内容
+	Variables map[string]string `json:"variables"`
+	ProfileID uint              `json:"profileId"` // 环境配置 ID，0 表示不使用
+	Secrets   []string          `json:"secrets"`   // 以环境变量注入的密钥名称，输出中会遮盖其值
+}
+
+// noteScript 准备好的笔记脚本
+type noteScript struct {
+	cmd     *exec.Cmd
+	cleanup func()
+	masker  *secretMasker
+}
+
+// parseScriptVariables 解析脚本中用 @var 声明的变量，按声明顺序返回
+func parseScriptVariables(content string) []ScriptVariable {
+	var vars []ScriptVariable
+	seen := map[string]int{}
+	for _, m := range scriptVarDeclRegex.FindAllStringSubmatchIndex(content, -1) {
+		name := content[m[2]:m[3]]
+		hasDefault := m[4] >= 0
+		def := ""
+		if hasDefault {
+			def = strings.TrimSpace(content[m[4]:m[5]])
+		}
+		if i, ok := seen[name]; ok {
+			// 重复声明时以第一个给出的默认值为准
+			if !vars[i].HasDefault && hasDefault {
+				vars[i].Default, vars[i].HasDefault = def, true
+			}
+			continue
+		}
+		seen[name] = len(vars)
+		vars = append(vars, ScriptVariable{Name: name, Default: def, HasDefault: hasDefault})
+	}
+	return vars
+}
+
+// renderScriptVariables 用取值替换脚本中已声明的占位变量，取值原样插入，不做转义
+// 未声明的 {{...}} 保持不变
+func renderScriptVariables(content string, values map[string]string) (string, error) {
+	var missing []string
+	declared := map[string]bool{}
+	for _, v := range parseScriptVariables(content) {
+		declared[v.Name] = true
+		if _, ok := values[v.Name]; ok {
+			continue
+		}
+		if v.HasDefault {
+			values[v.Name] = v.Default
+			continue
+		}
+		missing = append(missing, v.Name)
+	}
+	if len(missing) > 0 {
+		return "", fmt.Errorf("缺少变量值: %s", strings.Join(missing, ", "))
+	}
+	return scriptVarRegex.ReplaceAllStringFunc(content, func(match string) string {
+		name := scriptVarRegex.FindStringSubmatch(match)[1]
+		if !declared[name] {
+			return match
+		}
+		return values[name]
+	}), nil
+}
+
+// loadScriptNote 查询命令行工具笔记并校验类型
+func loadScriptNote(noteID uint) (*Note, error) {
+	var note Note
+	if err := DB.First(&note, noteID).Error; err != nil {
+		return nil, fmt.Errorf("笔记不存在: %v", err)
+	}
+	if note.Type != 2 {
+		return nil, errors.New("该笔记不是命令行工具类型")
+	}
+	if strings.TrimSpace(note.ContentMD) == "" {
+		return nil, errors.New("脚本内容为空")
+	}
+	return &note, nil
+}
+
+// prepareNoteScript 根据运行参数替换变量、应用环境配置和密钥，并构造笔记脚本的执行命令
+// 执行策略（内容确认、危险命令、环境清理、资源限制）在此统一应用
+func (a *App) prepareNoteScript(ctx context.Context, note *Note, opts *ScriptRunOptions) (*noteScript, error) {
+	if opts == nil {
+		opts = &ScriptRunOptions{}
+	}
+	policy := currentScriptPolicy()
+	if err := checkScriptTrust(note, policy); err != nil {
+		return nil, err
+	}
+
+	values := map[string]string{}
+	var env []string
+	workDir := ""
+	if opts.ProfileID != 0 {
+		var profile EnvProfile
+		if err := DB.First(&profile, opts.ProfileID).Error; err != nil {
+			return nil, fmt.Errorf("环境配置不存在: %v", err)
+		}
+		for k, v := range profile.Variables {
+			values[k] = v
+			env = append(env, k+"="+v)
+		}
+		workDir = profile.WorkDir
+	}
+	for k, v := range opts.Variables {
+		values[k] = v
+	}
+
+	content, err := renderScriptVariables(strings.TrimSpace(note.ContentMD), values)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkDangerousContent(content, policy); err != nil {
+		return nil, err
+	}
+
+	secretEnv, masker, err := a.loadSecretEnv(opts.Secrets)
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(env)
+	env = append(env, secretEnv...)
+
+	cmd, cleanup, err := prepareScript(ctx, note.Language, content, false)
+	if err != nil {
+		return nil, err
+	}
+	if err := applyScriptSandbox(cmd, policy, env, workDir); err != nil {
+		cleanup()
+		return nil, err
+	}
+	return &noteScript{cmd: cmd, cleanup: cleanup, masker: masker}, nil
+}
+
+// GetScriptVariables 获取命令行工具笔记中用 @var 声明的占位变量
+func (a *App) GetScriptVariables(noteID uint) ([]ScriptVariable, error) {
+	var note Note
+	if err := DB.First(&note, noteID).Error; err != nil {
+		return nil, fmt.Errorf("笔记不存在: %v", err)
+	}
+	vars := parseScriptVariables(note.ContentMD)
+	if vars == nil {
+		vars = []ScriptVariable{}
+	}
+	return vars, nil
+}
+
+func (a *App) CreateEnvProfile(name string, variables map[string]string, workDir string) (*EnvProfile, error) {
+	p := &EnvProfile{Name: name, Variables: variables, WorkDir: workDir}
+	err := DB.Create(p).Error
+	return p, err
+}
+
+func (a *App) ListEnvProfiles() ([]EnvProfile, error) {
+	var list []EnvProfile
+	err := DB.Order("name asc").Find(&list).Error
+	return list, err
+}
+
+func (a *App) UpdateEnvProfile(id uint, name string, variables map[string]string, workDir string) error {
+	var profile EnvProfile
+	if err := DB.First(&profile, id).Error; err != nil {
+		return fmt.Errorf("环境配置不存在: %v", err)
+	}
+	profile.Name = name
+	profile.Variables = variables
+	profile.WorkDir = workDir
+	return DB.Save(&profile).Error
+}
+
+func (a *App) DeleteEnvProfile(id uint) error {
+	return DB.Delete(&EnvProfile{}, id).Error
+}
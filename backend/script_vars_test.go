@@ -0,0 +1,86 @@
+package backend
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseScriptVariables(t *testing.T) {
+	content := "#!/bin/bash\n" +
+		"# @var host=localhost\n" +
+		"#@var port\n" +
+		"// @var path = /var/log \r\n" +
+		"REM @var drive=C:\n" +
+		"# @var port=22\n" +
+		"# @variable ignored\n" +
+		"echo @var notdeclared\n" +
+		"docker ps --format '{{.Names}}' {{undeclared}}\n"
+	want := []ScriptVariable{
+		{Name: "host", Default: "localhost", HasDefault: true},
+		{Name: "port", Default: "22", HasDefault: true},
+		{Name: "path", Default: "/var/log", HasDefault: true},
+		{Name: "drive", Default: "C:", HasDefault: true},
+	}
+	if got := parseScriptVariables(content); !reflect.DeepEqual(got, want) {
+		t.Errorf("parseScriptVariables = %+v，期望 %+v", got, want)
+	}
+}
+
+func TestRenderScriptVariables(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		values  map[string]string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:    "declared with default",
+			content: "# @var host=localhost\nping {{host}} {{ host }}",
+			want:    "# @var host=localhost\nping localhost localhost",
+		},
+		{
+			name:    "value overrides default",
+			content: "# @var host=localhost\nping {{host}}",
+			values:  map[string]string{"host": "example.com"},
+			want:    "# @var host=localhost\nping example.com",
+		},
+		{
+			name:    "go templates are kept",
+			content: "# @var host\ndocker -H {{host}} ps --format '{{.Names}}\\t{{.Status}}'\nkubectl get pods -o go-template='{{range .items}}{{.metadata.name}}{{else}}none{{end}}'",
+			values:  map[string]string{"host": "tcp://10.0.0.1"},
+			want:    "# @var host\ndocker -H tcp://10.0.0.1 ps --format '{{.Names}}\\t{{.Status}}'\nkubectl get pods -o go-template='{{range .items}}{{.metadata.name}}{{else}}none{{end}}'",
+		},
+		{
+			name:    "undeclared names are kept",
+			content: "docker inspect -f '{{end}}' {{name}}",
+			want:    "docker inspect -f '{{end}}' {{name}}",
+		},
+		{
+			name:    "missing value",
+			content: "# @var host\nping {{host}}",
+			wantErr: true,
+		},
+		{
+			name:    "values are inserted verbatim",
+			content: "# @var path\nls \"{{path}}\"",
+			values:  map[string]string{"path": "my dir"},
+			want:    "# @var path\nls \"my dir\"",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values := map[string]string{}
+			for k, v := range tt.values {
+				values[k] = v
+			}
+			got, err := renderScriptVariables(tt.content, values)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("错误为 %v，期望出错: %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("renderScriptVariables = %q，期望 %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,146 @@
+package backend
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// secretNameRegex 密钥名称同时作为环境变量名
+var secretNameRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// secretMask 输出中替换密钥值的文本
+const secretMask = "******"
+
+// secretMasker 在脚本输出中遮盖密钥值，nil 表示无需遮盖
+type secretMasker struct {
+	values []string
+}
+
+func newSecretMasker(values []string) *secretMasker {
+	var list []string
+	for _, v := range values {
+		if v != "" {
+			list = append(list, v)
+		}
+	}
+	if len(list) == 0 {
+		return nil
+	}
+	// 先替换较长的值，避免包含关系导致遮盖不完整
+	sort.Slice(list, func(i, j int) bool { return len(list[i]) > len(list[j]) })
+	return &secretMasker{values: list}
+}
+
+func (m *secretMasker) mask(s string) string {
+	if m == nil {
+		return s
+	}
+	for _, v := range m.values {
+		s = strings.ReplaceAll(s, v, secretMask)
+	}
+	return s
+}
+
+func (m *secretMasker) maskResult(result *ScriptResult) {
+	if m == nil {
+		return
+	}
+	result.Stdout = m.mask(result.Stdout)
+	result.Stderr = m.mask(result.Stderr)
+	result.Error = m.mask(result.Error)
+}
+
+// loadSecretEnv 解密指定的密钥，返回环境变量列表和对应的遮盖器
+func (a *App) loadSecretEnv(names []string) ([]string, *secretMasker, error) {
+	if len(names) == 0 {
+		return nil, nil, nil
+	}
+	key, err := a.unlockVault("运行脚本需要访问密钥")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var env, values []string
+	for _, name := range names {
+		var secret Secret
+		if err := DB.Where("name = ?", name).First(&secret).Error; err != nil {
+			return nil, nil, fmt.Errorf("密钥不存在: %s", name)
+		}
+		plaintext, err := openWithKey(key, secret.Ciphertext)
+		if err != nil {
+			return nil, nil, fmt.Errorf("密钥 %s %v", name, err)
+		}
+		env = append(env, secret.Name+"="+string(plaintext))
+		values = append(values, string(plaintext))
+	}
+	return env, newSecretMasker(values), nil
+}
+
+// CreateSecret 创建密钥，值加密保存且不会返回给前端
+func (a *App) CreateSecret(name string, value string, description string) (*Secret, error) {
+	if !secretNameRegex.MatchString(name) {
+		return nil, errors.New("密钥名称只能包含字母、数字和下划线，且不能以数字开头")
+	}
+	if value == "" {
+		return nil, errors.New("密钥值为空")
+	}
+	key, err := a.unlockVault("创建脚本密钥")
+	if err != nil {
+		return nil, err
+	}
+	sealed, err := sealWithKey(key, []byte(value))
+	if err != nil {
+		return nil, fmt.Errorf("加密密钥失败: %v", err)
+	}
+
+	s := &Secret{Name: name, Description: description, Ciphertext: sealed}
+	if err := DB.Create(s).Error; err != nil {
+		log.Printf("[Secret] 创建密钥失败 name=%s: %v", name, err)
+		return nil, fmt.Errorf("创建密钥失败: %v", err)
+	}
+	log.Printf("[Secret] 密钥已创建 name=%s", name)
+	return s, nil
+}
+
+// ListSecrets 获取密钥列表（不含值）
+func (a *App) ListSecrets() ([]Secret, error) {
+	var list []Secret
+	err := DB.Omit("ciphertext").Order("name asc").Find(&list).Error
+	return list, err
+}
+
+// UpdateSecret 更新密钥，value 为空时保留原值
+func (a *App) UpdateSecret(id uint, name string, value string, description string) error {
+	if !secretNameRegex.MatchString(name) {
+		return errors.New("密钥名称只能包含字母、数字和下划线，且不能以数字开头")
+	}
+	updates := map[string]interface{}{
+		"name":        name,
+		"description": description,
+	}
+	if value != "" {
+		key, err := a.unlockVault("修改脚本密钥")
+		if err != nil {
+			return err
+		}
+		sealed, err := sealWithKey(key, []byte(value))
+		if err != nil {
+			return fmt.Errorf("加密密钥失败: %v", err)
+		}
+		updates["ciphertext"] = sealed
+	}
+	log.Printf("[Secret] 更新密钥 id=%d, name=%s, 值已修改: %v", id, name, value != "")
+	return DB.Model(&Secret{}).Where("id = ?", id).Updates(updates).Error
+}
+
+// DeleteSecret 删除密钥
+func (a *App) DeleteSecret(id uint) error {
+	if err := a.RequireBiometric("删除脚本密钥"); err != nil {
+		return err
+	}
+	return DB.Delete(&Secret{}, id).Error
+}
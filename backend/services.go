@@ -1,17 +1,12 @@
 package backend
 
 import (
-	"bytes"
 	"context"
 	"encoding/base64"
-	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
 	"log"
-	"net/http"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"regexp"
 	"strings"
@@ -85,7 +80,7 @@ func (a *App) ListNotes(categoryID *uint) ([]Note, error) {
 		log.Printf("ListNotes categoryID=<nil>")
 	}
 	q := DB.Order("updated_at desc")
-	q = q.Where("(content_md <> '' OR type = 1)")
+	q = q.Where("(content_md <> '' OR type IN (1, 3))")
 	if categoryID != nil {
 		// 查询包含子目录的所有分类 ID
 		var cats []Category
@@ -137,8 +132,11 @@ func (a *App) CreateNoteMD(title string, language string, contentMD string, cate
 
 func (a *App) CreateNoteMDWithType(title string, language string, contentMD string, categoryID uint, noteType uint) (*Note, error) {
 	n := &Note{Title: title, Language: language, ContentMD: contentMD, CategoryID: categoryID, Type: noteType}
-	err := DB.Create(n).Error
-	return n, err
+	if err := DB.Create(n).Error; err != nil {
+		return n, err
+	}
+	a.enqueueIndex(n.ID)
+	return n, nil
 }
 
 func (a *App) UpdateNoteMD(id uint, title string, language string, contentMD string, categoryID uint) error {
@@ -147,16 +145,99 @@ func (a *App) UpdateNoteMD(id uint, title string, language string, contentMD str
 		return err
 	}
 	// 保持原有的 Type，不修改
-	return DB.Model(&Note{}).Where("id = ?", id).Updates(map[string]interface{}{
+	if err := DB.Model(&Note{}).Where("id = ?", id).Updates(map[string]interface{}{
 		"title":       title,
 		"language":    language,
 		"content_md":  contentMD,
 		"category_id": categoryID,
-	}).Error
+	}).Error; err != nil {
+		return err
+	}
+	// 向量索引在后台增量更新，只重新计算内容变化的片段
+	a.enqueueIndex(id)
+	return nil
 }
 
 func (a *App) DeleteNote(id uint) error {
-	return DB.Delete(&Note{}, id).Error
+	var note Note
+	if err := DB.Select("id", "type", "file_path").First(&note, id).Error; err != nil {
+		return fmt.Errorf("笔记不存在: %v", err)
+	}
+	// EPUB 章节中的图片在删除章节前收集，笔记删除后一并删除
+	var images []string
+	if note.Type == 3 {
+		var err error
+		if images, err = epubChapterImages(id); err != nil {
+			return err
+		}
+	}
+	if err := DB.Where("note_id = ?", id).Delete(&BookChapter{}).Error; err != nil {
+		return err
+	}
+	if err := deleteNoteAttachments(id); err != nil {
+		return err
+	}
+	if err := DB.Where("note_id = ?", id).Delete(&ScriptRun{}).Error; err != nil {
+		return err
+	}
+	if err := DB.Where("note_id = ?", id).Delete(&ScriptSchedule{}).Error; err != nil {
+		return err
+	}
+	if err := DB.Where("note_id = ?", id).Delete(&NoteChunk{}).Error; err != nil {
+		return err
+	}
+	a.wakeScheduler()
+	if err := DB.Delete(&Note{}, id).Error; err != nil {
+		return err
+	}
+	removeNoteFile(note)
+	removeEPUBImages(images)
+	return nil
+}
+
+// removeNoteFile 删除 PDF / EPUB 笔记在存储目录中的文件
+func removeNoteFile(note Note) {
+	if note.FilePath == "" {
+		return
+	}
+	var fullPath string
+	switch note.Type {
+	case 1:
+		fullPath = GetPDFFullPath(note.FilePath)
+	case 3:
+		fullPath = GetEPUBFullPath(note.FilePath)
+	default:
+		return
+	}
+	if err := os.Remove(fullPath); err != nil && !os.IsNotExist(err) {
+		log.Printf("Failed to remove note file %s: %v\n", note.FilePath, err)
+	}
+}
+
+// SearchNotes 按关键字搜索笔记标题、内容以及 EPUB 章节正文
+func (a *App) SearchNotes(keyword string) ([]Note, error) {
+	keyword = strings.TrimSpace(keyword)
+	if keyword == "" {
+		return []Note{}, nil
+	}
+	like := "%" + keyword + "%"
+
+	var chapterNoteIDs []uint
+	if err := DB.Model(&BookChapter{}).Distinct("note_id").
+		Where("title LIKE ? OR content_md LIKE ?", like, like).
+		Pluck("note_id", &chapterNoteIDs).Error; err != nil {
+		return nil, err
+	}
+
+	var list []Note
+	q := DB.Order("updated_at desc").Where("(content_md <> '' OR type IN (1, 3))")
+	if len(chapterNoteIDs) > 0 {
+		q = q.Where("title LIKE ? OR content_md LIKE ? OR snippet LIKE ? OR id IN ?", like, like, like, chapterNoteIDs)
+	} else {
+		q = q.Where("title LIKE ? OR content_md LIKE ? OR snippet LIKE ?", like, like, like)
+	}
+	err := q.Find(&list).Error
+	return list, err
 }
 
 // ImportPDF 导入 PDF 文件
@@ -170,13 +251,7 @@ func (a *App) ImportPDF(fileDataBase64 string, fileName string, categoryID uint)
 	}
 
 	// 生成唯一文件名
-	timestamp := time.Now().Unix()
-	ext := filepath.Ext(fileName)
-	nameWithoutExt := strings.TrimSuffix(fileName, ext)
-	safeName := strings.ReplaceAll(nameWithoutExt, " ", "_")
-	safeName = strings.ReplaceAll(safeName, "/", "_")
-	safeName = strings.ReplaceAll(safeName, "\\", "_")
-	uniqueFileName := fmt.Sprintf("%d_%s%s", timestamp, safeName, ext)
+	uniqueFileName, nameWithoutExt := uniqueStorageName(fileName)
 
 	// 保存文件到 PDF 存储目录
 	relativePath := uniqueFileName
@@ -206,6 +281,18 @@ func (a *App) ImportPDF(fileDataBase64 string, fileName string, categoryID uint)
 	return note, nil
 }
 
+// uniqueStorageName 根据原始文件名生成带时间戳的存储文件名
+// 返回: 存储文件名, 去掉扩展名的原始文件名
+func uniqueStorageName(fileName string) (string, string) {
+	timestamp := time.Now().Unix()
+	ext := filepath.Ext(fileName)
+	nameWithoutExt := strings.TrimSuffix(fileName, ext)
+	safeName := strings.ReplaceAll(nameWithoutExt, " ", "_")
+	safeName = strings.ReplaceAll(safeName, "/", "_")
+	safeName = strings.ReplaceAll(safeName, "\\", "_")
+	return fmt.Sprintf("%d_%s%s", timestamp, safeName, ext), nameWithoutExt
+}
+
 // GetPDFPath 获取 PDF 文件的完整路径
 func (a *App) GetPDFPath(noteID uint) (string, error) {
 	var note Note
@@ -262,64 +349,6 @@ func (a *App) UpdatePDFPage(noteID uint, page uint) error {
 	return DB.Model(&Note{}).Where("id = ?", noteID).Update("pdf_page", page).Error
 }
 
-// ExecuteScript 执行命令行工具脚本
-// noteID: 笔记 ID
-// 返回: ScriptResult
-func (a *App) ExecuteScript(noteID uint) (*ScriptResult, error) {
-	// 查询笔记
-	var note Note
-	if err := DB.First(&note, noteID).Error; err != nil {
-		return nil, fmt.Errorf("笔记不存在: %v", err)
-	}
-
-	// 验证类型
-	if note.Type != 2 {
-		return nil, errors.New("该笔记不是命令行工具类型")
-	}
-
-	// 验证脚本内容
-	scriptContent := strings.TrimSpace(note.ContentMD)
-	if scriptContent == "" {
-		return nil, errors.New("脚本内容为空")
-	}
-
-	// 记录执行日志
-	log.Printf("执行脚本 (Note ID: %d): %s", noteID, note.Title)
-
-	// 创建执行上下文，设置超时 30 秒
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
-
-	// 执行脚本（使用 sh -c）
-	cmd := exec.CommandContext(ctx, "sh", "-c", scriptContent)
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
-
-	// 执行命令
-	err := cmd.Run()
-
-	// 获取输出
-	stdoutStr := stdout.String()
-	stderrStr := stderr.String()
-
-	result := &ScriptResult{
-		Stdout:  stdoutStr,
-		Stderr:  stderrStr,
-		Success: err == nil,
-	}
-
-	// 如果命令执行失败，记录错误信息
-	if err != nil {
-		log.Printf("脚本执行失败 (Note ID: %d): %v, stderr: %s", noteID, err, stderrStr)
-		result.Error = err.Error()
-	} else {
-		log.Printf("脚本执行成功 (Note ID: %d), stdout: %s", noteID, stdoutStr)
-	}
-
-	return result, nil
-}
-
 // LogFrontend prints frontend logs to backend stdout for easy debugging
 func (a *App) LogFrontend(message string) {
 	log.Printf("[Frontend] %s", message)
@@ -352,6 +381,17 @@ func (a *App) GetCategoryContent(categoryID uint) (string, error) {
 			log.Printf("[GetCategoryContent] 跳过 PDF 笔记: id=%d, title=%s", note.ID, note.Title)
 			continue
 		}
+		if note.Type == 3 {
+			// EPUB 类型，使用章节正文
+			text, err := getEPUBText(note.ID)
+			if err != nil || text == "" {
+				log.Printf("[GetCategoryContent] 跳过 EPUB 笔记: id=%d, title=%s, error=%v", note.ID, note.Title, err)
+				continue
+			}
+			log.Printf("[GetCategoryContent] 添加 EPUB 笔记: id=%d, title=%s, contentLength=%d", note.ID, note.Title, len(text))
+			contents = append(contents, fmt.Sprintf("标题: %s\n%s", note.Title, text))
+			continue
+		}
 		if note.ContentMD != "" {
 			contentLen := len(note.ContentMD)
 			log.Printf("[GetCategoryContent] 添加笔记: id=%d, title=%s, contentLength=%d, categoryId=%d", 
@@ -383,136 +423,106 @@ func (a *App) GetNoteContent(noteID uint) (string, error) {
 	if note.Type == 1 {
 		return "", errors.New("PDF 类型笔记暂不支持作为上下文")
 	}
+	if note.Type == 3 {
+		return getEPUBText(note.ID)
+	}
 
 	return note.ContentMD, nil
 }
 
-// ChatWithAI 与 OpenAI API 进行对话
-func (a *App) ChatWithAI(prompt string, contextTexts []string) (string, error) {
+// AIChatResult ChatWithAI 的返回值
+type AIChatResult struct {
+	Content        string           `json:"content"`
+	Context        *ContextReport   `json:"context"`
+	Citations      []Citation       `json:"citations"`                // 检索模式下引用的笔记片段，指向笔记 ID 和小节标题
+	ToolCalls      []ToolCallRecord `json:"toolCalls"`                // 工具模式下执行的工具调用
+	BudgetWarning  string           `json:"budgetWarning,omitempty"`  // 本月费用超出预算时的提示
+	PromptTemplate string           `json:"promptTemplate,omitempty"` // 使用的目录系统提示词模板名称
+}
+
+// ChatWithAI 进行单轮对话
+// opts.Retrieval 为 true 时自动检索与提问最相似的笔记片段作为上下文，并在结果中返回引用
+// opts.Tools 为 true 时允许 AI 调用笔记库工具，写入操作需要用户通过 ai-tool-confirm 事件确认
+// opts.CategoryID 非 0 时使用该目录（含上级目录）设置的系统提示词模板
+// opts.Images 为随提问发送的图片，模型支持图片输入时提问和上下文中引用的图片也会附带
+func (a *App) ChatWithAI(prompt string, contextTexts []string, opts ChatOptions) (*AIChatResult, error) {
+	// 隐私模式下只记录长度和耗时，不记录提示词、上下文和回复内容
+	privacy := privacyMode()
 	log.Printf("[AI Chat] 开始 AI 对话请求")
-	log.Printf("[AI Chat] 用户提示词: %s", prompt)
+	log.Printf("[AI Chat] 用户提示词长度: %d 字符", len(prompt))
 	log.Printf("[AI Chat] 关联上下文数量: %d", len(contextTexts))
-	log.Printf("[AI Chat] 关联上下文: %v", contextTexts)
+	if !privacy {
+		log.Printf("[AI Chat] 用户提示词: %s", prompt)
+		log.Printf("[AI Chat] 关联上下文: %v", contextTexts)
+	}
 	
-	apiKey := Cfg.OpenAIAPIKey
-	if apiKey == "" {
-		log.Printf("[AI Chat] 错误: 未配置 OpenAI API Key")
-		return "", errors.New("未配置 OpenAI API Key，请设置 OPENAI_API_KEY 环境变量")
+	provider, err := resolveAIProvider(opts.Provider)
+	if err != nil {
+		log.Printf("[AI Chat] 错误: %v", err)
+		return nil, err
 	}
 
-	// 构建系统提示词
-	systemPrompt := "你是一个有用的 AI 助手。"
-	if len(contextTexts) > 0 {
-		systemPrompt += "以下是用户提供的上下文内容：\n\n"
-		systemPrompt += strings.Join(contextTexts, "\n\n---\n\n")
-		systemPrompt += "\n\n请基于以上上下文内容回答用户的问题。"
-		log.Printf("[AI Chat] 系统提示词长度: %d 字符", len(systemPrompt))
-		for i, ctx := range contextTexts {
-			log.Printf("[AI Chat] 上下文 %d 长度: %d 字符", i+1, len(ctx))
-		}
+	// 超时按网络设置中的重试次数和各项超时计算，工具模式还需要留出等待确认的时间
+	timeout := aiRequestTimeout(currentAIClientConfig())
+	if opts.Tools {
+		timeout = max(timeout, toolChatTimeout)
 	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
 
-	// 构建请求体
-	requestBody := map[string]interface{}{
-		"model": Cfg.OpenAIModel,
-		"messages": []map[string]string{
-			{
-				"role":    "system",
-				"content": systemPrompt,
-			},
-			{
-				"role":    "user",
-				"content": prompt,
-			},
-		},
-		"max_tokens": 2000,
-		"temperature": 0.7,
-	}
-
-	jsonData, err := json.Marshal(requestBody)
+	// 构建系统提示词
+	systemPrompt, templateName := systemPromptFor(opts.CategoryID, "")
+	messages, report, citations, err := fitChatMessages(ctx, provider, systemPrompt, prompt, contextTexts, opts.Retrieval, opts.Images)
 	if err != nil {
-		log.Printf("[AI Chat] 序列化请求失败: %v", err)
-		return "", fmt.Errorf("序列化请求失败: %v", err)
+		log.Printf("[AI Chat] 错误: %v", err)
+		return nil, err
 	}
-	log.Printf("[AI Chat] 请求体大小: %d 字节", len(jsonData))
-
-	// 创建 HTTP 请求
-	apiURL := Cfg.OpenAIAPIURL
-	log.Printf("[AI Chat] 请求 URL: %s", apiURL)
-	req, err := http.NewRequest("POST", apiURL, bytes.NewBuffer(jsonData))
-	if err != nil {
-		log.Printf("[AI Chat] 创建请求失败: %v", err)
-		return "", fmt.Errorf("创建请求失败: %v", err)
+	if opts.Retrieval {
+		log.Printf("[AI Chat] 语义检索引用片段: %d", len(citations))
 	}
-
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+apiKey)
-	log.Printf("[AI Chat] 请求头已设置，API Key 长度: %d", len(apiKey))
+	if len(contextTexts) > 0 || opts.Retrieval {
+		log.Printf("[AI Chat] 系统提示词长度: %d 字符", len(messages[0].Content))
+		for i, text := range contextTexts {
+			log.Printf("[AI Chat] 上下文 %d 长度: %d 字符", i+1, len(text))
+		}
+		log.Printf("[AI Chat] 上下文预算: %d/%d tokens, 放入 %d 段, 略过 %d 段", report.Used, report.Budget, len(report.Included), len(report.Omitted))
+	}
+	if len(report.Images) > 0 || len(report.OmittedImages) > 0 {
+		log.Printf("[AI Chat] 附带图片: %d 张, 略过 %d 张", len(report.Images), len(report.OmittedImages))
+	}
+	log.Printf("[AI Chat] 服务商: %s (%s), 模型: %s, 请求 URL: %s", provider.Name, provider.Kind, provider.Model, provider.APIURL)
 
 	// 发送请求
 	startTime := time.Now()
-	client := &http.Client{
-		Timeout: 60 * time.Second,
-	}
 	log.Printf("[AI Chat] 开始发送 HTTP 请求...")
-	resp, err := client.Do(req)
-	requestDuration := time.Since(startTime)
-	if err != nil {
-		log.Printf("[AI Chat] HTTP 请求失败 (耗时: %v): %v", requestDuration, err)
-		return "", fmt.Errorf("请求失败: %v", err)
+	var responseContent string
+	var toolCalls []ToolCallRecord
+	if opts.Tools {
+		messages[0].Content += toolSystemPrompt
+		var box *noteToolbox
+		if box, err = a.newNoteToolbox(newRunID()); err != nil {
+			return nil, err
+		}
+		responseContent, err = runToolLoop(ctx, provider, messages, box)
+		toolCalls = box.records
+		log.Printf("[AI Chat] 工具调用次数: %d", len(toolCalls))
+	} else {
+		responseContent, err = completeChat(ctx, provider, messages)
 	}
-	defer resp.Body.Close()
-	log.Printf("[AI Chat] HTTP 响应状态码: %d (耗时: %v)", resp.StatusCode, requestDuration)
-
-	// 读取响应
-	body, err := io.ReadAll(resp.Body)
+	requestDuration := time.Since(startTime)
 	if err != nil {
-		log.Printf("[AI Chat] 读取响应失败: %v", err)
-		return "", fmt.Errorf("读取响应失败: %v", err)
-	}
-	log.Printf("[AI Chat] 响应体大小: %d 字节", len(body))
-
-	// 检查 HTTP 状态码
-	if resp.StatusCode != http.StatusOK {
-		log.Printf("[AI Chat] API 请求失败 (状态码: %d): %s", resp.StatusCode, string(body))
-		return "", fmt.Errorf("API 请求失败 (状态码: %d): %s", resp.StatusCode, string(body))
-	}
-
-	// 解析响应
-	var apiResponse struct {
-		Choices []struct {
-			Message struct {
-				Content string `json:"content"`
-			} `json:"message"`
-		} `json:"choices"`
-		Error struct {
-			Message string `json:"message"`
-		} `json:"error"`
+		log.Printf("[AI Chat] 请求失败 (耗时: %v): %v", requestDuration, err)
+		return nil, err
 	}
+	log.Printf("[AI Chat] 请求完成 (耗时: %v)", requestDuration)
 
-	if err := json.Unmarshal(body, &apiResponse); err != nil {
-		log.Printf("[AI Chat] 解析响应失败: %v, 响应内容: %s", err, string(body))
-		return "", fmt.Errorf("解析响应失败: %v", err)
-	}
-
-	// 检查是否有错误
-	if apiResponse.Error.Message != "" {
-		log.Printf("[AI Chat] API 返回错误: %s", apiResponse.Error.Message)
-		return "", fmt.Errorf("API 错误: %s", apiResponse.Error.Message)
-	}
-
-	// 检查是否有回复
-	if len(apiResponse.Choices) == 0 {
-		log.Printf("[AI Chat] API 未返回任何回复")
-		return "", errors.New("API 未返回任何回复")
-	}
-
-	responseContent := apiResponse.Choices[0].Message.Content
 	log.Printf("[AI Chat] 收到 AI 回复，长度: %d 字符", len(responseContent))
-	log.Printf("[AI Chat] AI 回复内容: %s", responseContent)
+	if !privacy {
+		log.Printf("[AI Chat] AI 回复内容: %s", responseContent)
+	}
 	log.Printf("[AI Chat] AI 对话请求完成")
 
-	return responseContent, nil
+	return &AIChatResult{Content: responseContent, Context: report, Citations: citations, ToolCalls: toolCalls, BudgetWarning: budgetWarning(), PromptTemplate: templateName}, nil
 }
 
 // GetAIConfig 获取 AI 配置
@@ -520,21 +530,32 @@ func (a *App) GetAIConfig() (*AIConfig, error) {
 	cfgMu.RLock()
 	defer cfgMu.RUnlock()
 	
+	privacy := Cfg.PrivacyMode
 	return &AIConfig{
-		APIKey: Cfg.OpenAIAPIKey,
-		APIURL: Cfg.OpenAIAPIURL,
-		Model:  Cfg.OpenAIModel,
+		APIKey:    maskAPIKey(Cfg.OpenAIAPIKey),
+		APIURL:    Cfg.OpenAIAPIURL,
+		Model:     Cfg.OpenAIModel,
+		MaxTokens: Cfg.OpenAIMaxTokens,
+
+		ContextTokens:   Cfg.OpenAIContextTokens,
+		ContextStrategy: Cfg.ContextStrategy,
+		Vision:          Cfg.OpenAIVision,
+		PrivacyMode:     &privacy,
 	}, nil
 }
 
 // UpdateAIConfig 更新 AI 配置
 func (a *App) UpdateAIConfig(config *AIConfig) error {
 	log.Printf("[Config] 更新 AI 配置")
+	if err := validateVision(config.Vision); err != nil {
+		return err
+	}
 	
 	cfgMu.Lock()
 	
 	// 更新配置值
-	if config.APIKey != "" {
+	// 前端原样提交的脱敏值表示不修改
+	if config.APIKey != "" && !isMaskedAPIKey(config.APIKey) {
 		Cfg.OpenAIAPIKey = config.APIKey
 		log.Printf("[Config] API Key 已更新 (长度: %d)", len(config.APIKey))
 	}
@@ -546,28 +567,31 @@ func (a *App) UpdateAIConfig(config *AIConfig) error {
 		Cfg.OpenAIModel = config.Model
 		log.Printf("[Config] Model 已更新: %s", config.Model)
 	}
+	if config.MaxTokens >= 0 {
+		Cfg.OpenAIMaxTokens = config.MaxTokens
+	}
+	if config.ContextTokens >= 0 {
+		Cfg.OpenAIContextTokens = config.ContextTokens
+	}
+	if config.ContextStrategy != "" {
+		Cfg.ContextStrategy = config.ContextStrategy
+	}
+	Cfg.OpenAIVision = config.Vision
+	if config.PrivacyMode != nil {
+		Cfg.PrivacyMode = *config.PrivacyMode
+		log.Printf("[Config] 隐私模式: %v", Cfg.PrivacyMode)
+	}
 	
 	// 准备保存的数据
-	saveConfig := AIConfig{
-		APIKey: Cfg.OpenAIAPIKey,
-		APIURL: Cfg.OpenAIAPIURL,
-		Model:  Cfg.OpenAIModel,
-	}
+	saveConfig := snapshotConfigFile()
 	
 	// 获取配置文件路径（需要在锁内获取，因为 configFilePath 可能被修改）
 	filePath := configFilePath
 	
 	cfgMu.Unlock() // 释放锁，避免在文件 I/O 时持有锁
 	
-	// 序列化配置
-	data, err := json.MarshalIndent(saveConfig, "", "  ")
-	if err != nil {
-		log.Printf("[Config] 序列化配置失败: %v", err)
-		return fmt.Errorf("序列化配置失败: %v", err)
-	}
-	
 	// 写入文件
-	if err := os.WriteFile(filePath, data, 0644); err != nil {
+	if err := writeConfigFile(filePath, saveConfig); err != nil {
 		log.Printf("[Config] 保存配置文件失败: %v", err)
 		return fmt.Errorf("保存配置文件失败: %v", err)
 	}
@@ -581,6 +605,11 @@ func (a *App) GetConfigFilePath() string {
 	return GetConfigFilePath()
 }
 
+// GetConfigKeyError 配置文件中的 API Key 无法解密时返回错误信息，此时保存配置会被拒绝
+func (a *App) GetConfigKeyError() string {
+	return ConfigKeyError()
+}
+
 // GetLogFilePath 获取日志文件路径
 func (a *App) GetLogFilePath() (string, error) {
 	exe, err := os.Executable()
@@ -662,6 +691,12 @@ func (a *App) SaveImage(imageDataBase64 string) (string, error) {
 		ext = ".png" // 默认扩展名
 	}
 
+	// 返回相对路径，前端可以使用 file:// 协议或相对路径引用
+	return saveImageBytes(imageData, ext)
+}
+
+// saveImageBytes 将图片数据写入图片存储目录，返回相对路径
+func saveImageBytes(imageData []byte, ext string) (string, error) {
 	// 生成唯一文件名
 	timestamp := time.Now().UnixNano()
 	uniqueFileName := fmt.Sprintf("%d%s", timestamp, ext)
@@ -676,8 +711,6 @@ func (a *App) SaveImage(imageDataBase64 string) (string, error) {
 	}
 
 	log.Printf("Image saved successfully: %s (size: %d bytes)\n", uniqueFileName, len(imageData))
-	
-	// 返回相对路径，前端可以使用 file:// 协议或相对路径引用
 	return relativePath, nil
 }
 
@@ -9,6 +9,8 @@ import (
 var (
 	pdfStorageDir   string
 	imageStorageDir string
+	epubStorageDir  string
+	attachmentDir   string
 )
 
 // InitPDFStorage 初始化 PDF 存储目录
@@ -49,6 +51,44 @@ func InitImageStorage() {
 	log.Printf("Image storage directory initialized: %s\n", imageStorageDir)
 }
 
+// InitEPUBStorage 初始化 EPUB 存储目录
+func InitEPUBStorage() {
+	exe, err := os.Executable()
+	if err != nil {
+		log.Printf("Failed to get executable path: %v\n", err)
+		return
+	}
+	exeDir := filepath.Dir(exe)
+	epubDir := filepath.Join(exeDir, "epub")
+
+	if err := os.MkdirAll(epubDir, 0755); err != nil {
+		log.Printf("Failed to create EPUB storage directory: %v\n", err)
+		return
+	}
+
+	epubStorageDir = epubDir
+	log.Printf("EPUB storage directory initialized: %s\n", epubStorageDir)
+}
+
+// InitAttachmentStorage 初始化附件存储目录
+func InitAttachmentStorage() {
+	exe, err := os.Executable()
+	if err != nil {
+		log.Printf("Failed to get executable path: %v\n", err)
+		return
+	}
+	exeDir := filepath.Dir(exe)
+	dir := filepath.Join(exeDir, "attachments")
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		log.Printf("Failed to create attachment storage directory: %v\n", err)
+		return
+	}
+
+	attachmentDir = dir
+	log.Printf("Attachment storage directory initialized: %s\n", attachmentDir)
+}
+
 // GetPDFStorageDir 获取 PDF 存储目录路径
 func GetPDFStorageDir() string {
 	return pdfStorageDir
@@ -74,3 +114,19 @@ func GetImageFullPath(relativePath string) string {
 	}
 	return filepath.Join(imageStorageDir, relativePath)
 }
+
+// GetEPUBFullPath 根据相对路径获取 EPUB 完整路径
+func GetEPUBFullPath(relativePath string) string {
+	if relativePath == "" {
+		return ""
+	}
+	return filepath.Join(epubStorageDir, relativePath)
+}
+
+// GetAttachmentFullPath 根据相对路径获取附件完整路径
+func GetAttachmentFullPath(relativePath string) string {
+	if relativePath == "" {
+		return ""
+	}
+	return filepath.Join(attachmentDir, relativePath)
+}
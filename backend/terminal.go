@@ -0,0 +1,201 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"time"
+	"unicode/utf8"
+)
+
+// 终端会话事件
+const (
+	EventTerminalOutput = "terminal-output" // 终端输出，数据为 TerminalOutput
+	EventTerminalExit   = "terminal-exit"   // 会话结束，数据为 TerminalExit
+)
+
+// terminalDrainTimeout 脚本退出后等待读完剩余输出的时间
+// 后台运行的子进程（如 sleep 100 &）会继续持有伪终端从端，读取不会结束，超时后终止这些进程
+const terminalDrainTimeout = 500 * time.Millisecond
+
+// TerminalOutput 终端输出片段
+type TerminalOutput struct {
+	SessionID string `json:"sessionId"`
+	Data      string `json:"data"`
+}
+
+// TerminalExit 终端会话结束事件
+type TerminalExit struct {
+	SessionID string `json:"sessionId"`
+	NoteID    uint   `json:"noteId"`
+	ExitCode  int    `json:"exitCode"`
+	Error     string `json:"error,omitempty"`
+}
+
+// terminalSession 基于伪终端的交互式脚本会话
+type terminalSession struct {
+	id     string
+	noteID uint
+	pty    *os.File
+	cancel context.CancelFunc
+	masker *secretMasker
+}
+
+// StartTerminal 在伪终端中运行命令行工具脚本，支持交互输入
+// 输出通过 terminal-output 事件推送，结束时发送 terminal-exit 事件
+// opts: 占位变量取值和环境配置，可为 nil
+// 返回: sessionID
+func (a *App) StartTerminal(noteID uint, opts *ScriptRunOptions, cols uint16, rows uint16) (string, error) {
+	note, err := loadScriptNote(noteID)
+	if err != nil {
+		return "", err
+	}
+
+	// 交互式会话不设置超时，由用户关闭
+	ctx, cancel := context.WithCancel(context.Background())
+	script, err := a.prepareNoteScript(ctx, note, opts)
+	if err != nil {
+		cancel()
+		return "", err
+	}
+	cmd, cleanup := script.cmd, script.cleanup
+	cmd.Env = append(cmd.Env, "TERM=xterm-256color")
+
+	ptmx, err := startPTY(cmd, cols, rows)
+	if err != nil {
+		cancel()
+		cleanup()
+		log.Printf("终端会话启动失败 (Note ID: %d): %v", noteID, err)
+		return "", fmt.Errorf("终端会话启动失败: %v", err)
+	}
+
+	session := &terminalSession{id: newRunID(), noteID: noteID, pty: ptmx, cancel: cancel, masker: script.masker}
+	a.termMu.Lock()
+	a.terminals[session.id] = session
+	a.termMu.Unlock()
+	log.Printf("终端会话已启动 (Note ID: %d, Session ID: %s): %s", noteID, session.id, note.Title)
+
+	pumpDone := make(chan struct{})
+	go func() {
+		a.pumpTerminalOutput(session)
+		close(pumpDone)
+	}()
+	go func() {
+		err := cmd.Wait()
+		select {
+		case <-pumpDone:
+		case <-time.After(terminalDrainTimeout):
+			// 脚本已退出，终止仍在后台运行的子进程，伪终端从端随之关闭
+			killProcessGroup(cmd)
+			select {
+			case <-pumpDone:
+			case <-time.After(terminalDrainTimeout):
+			}
+		}
+		cancel()
+		cleanup()
+
+		a.termMu.Lock()
+		delete(a.terminals, session.id)
+		a.termMu.Unlock()
+
+		exit := TerminalExit{SessionID: session.id, NoteID: noteID}
+		if cmd.ProcessState != nil {
+			exit.ExitCode = cmd.ProcessState.ExitCode()
+		}
+		if err != nil {
+			exit.Error = err.Error()
+		}
+		log.Printf("终端会话结束 (Note ID: %d, Session ID: %s, exit: %d)", noteID, session.id, exit.ExitCode)
+		a.emit(EventTerminalExit, exit)
+		ptmx.Close()
+	}()
+
+	return session.id, nil
+}
+
+// pumpTerminalOutput 持续读取伪终端输出并推送，直到伪终端关闭
+func (a *App) pumpTerminalOutput(session *terminalSession) {
+	buf := make([]byte, 4096)
+	var pending []byte
+	for {
+		n, err := session.pty.Read(buf)
+		if n > 0 {
+			data := append(pending, buf[:n]...)
+			// 保留末尾不完整的 UTF-8 字符，与下一次读取合并
+			cut := len(data)
+			for i := len(data) - 1; i >= 0 && i >= len(data)-utf8.UTFMax; i-- {
+				if utf8.RuneStart(data[i]) {
+					if !utf8.FullRune(data[i:]) {
+						cut = i
+					}
+					break
+				}
+			}
+			pending = append([]byte(nil), data[cut:]...)
+			if cut > 0 {
+				a.emit(EventTerminalOutput, TerminalOutput{SessionID: session.id, Data: session.masker.mask(string(data[:cut]))})
+			}
+		}
+		if err != nil {
+			// 进程退出后读取伪终端会返回 EIO 或 EOF
+			if len(pending) > 0 {
+				a.emit(EventTerminalOutput, TerminalOutput{SessionID: session.id, Data: session.masker.mask(string(pending))})
+			}
+			return
+		}
+	}
+}
+
+func (a *App) getTerminal(sessionID string) (*terminalSession, error) {
+	a.termMu.Lock()
+	defer a.termMu.Unlock()
+	session, ok := a.terminals[sessionID]
+	if !ok {
+		return nil, errors.New("终端会话不存在或已结束")
+	}
+	return session, nil
+}
+
+// WriteTerminal 向终端会话写入输入
+func (a *App) WriteTerminal(sessionID string, data string) error {
+	session, err := a.getTerminal(sessionID)
+	if err != nil {
+		return err
+	}
+	if _, err := session.pty.Write([]byte(data)); err != nil {
+		return fmt.Errorf("写入终端失败: %v", err)
+	}
+	return nil
+}
+
+// ResizeTerminal 调整终端会话的窗口大小
+func (a *App) ResizeTerminal(sessionID string, cols uint16, rows uint16) error {
+	session, err := a.getTerminal(sessionID)
+	if err != nil {
+		return err
+	}
+	return resizePTY(session.pty, cols, rows)
+}
+
+// CloseTerminal 关闭终端会话并终止其进程组
+func (a *App) CloseTerminal(sessionID string) error {
+	session, err := a.getTerminal(sessionID)
+	if err != nil {
+		return err
+	}
+	log.Printf("关闭终端会话 (Note ID: %d, Session ID: %s)", session.noteID, sessionID)
+	session.cancel()
+	return nil
+}
+
+// closeAllTerminals 关闭所有终端会话，应用退出时调用
+func (a *App) closeAllTerminals() {
+	a.termMu.Lock()
+	defer a.termMu.Unlock()
+	for _, session := range a.terminals {
+		session.cancel()
+	}
+}
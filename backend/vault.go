@@ -0,0 +1,134 @@
+package backend
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/zalando/go-keyring"
+)
+
+const (
+	keyringService = "Eaiser"
+	vaultKeyName   = "vault-key"
+	vaultKeyFile   = "eaiser.vault.key"
+)
+
+// loadVaultKey 读取加密主密钥，不存在时生成
+// 优先保存在系统钥匙串中，钥匙串不可用（如 Linux 上没有 Secret Service）时退回到可执行文件旁的 0600 文件
+// 钥匙串中存在密钥但无效时不会生成新密钥，否则用原密钥加密的数据将无法解密
+func loadVaultKey() ([]byte, error) {
+	var invalidErr error
+	if encoded, err := keyring.Get(keyringService, vaultKeyName); err == nil {
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err == nil && len(key) == 32 {
+			return key, nil
+		}
+		invalidErr = fmt.Errorf("钥匙串中的主密钥无效: %v", err)
+		log.Printf("[Vault] %v", invalidErr)
+	} else if !errors.Is(err, keyring.ErrNotFound) {
+		log.Printf("[Vault] 读取系统钥匙串失败，使用本地密钥文件: %v", err)
+	}
+
+	keyPath := vaultKeyFilePath()
+	data, err := os.ReadFile(keyPath)
+	if err == nil {
+		key, err := base64.StdEncoding.DecodeString(string(data))
+		if err != nil || len(key) != 32 {
+			return nil, fmt.Errorf("本地密钥文件无效: %s", keyPath)
+		}
+		return key, nil
+	}
+	if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("读取本地密钥文件失败: %v", err)
+	}
+	if invalidErr != nil {
+		return nil, invalidErr
+	}
+
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("生成主密钥失败: %v", err)
+	}
+	encoded := base64.StdEncoding.EncodeToString(key)
+	err = keyring.Set(keyringService, vaultKeyName, encoded)
+	if err == nil {
+		log.Printf("[Vault] 主密钥已保存到系统钥匙串")
+		return key, nil
+	}
+	log.Printf("[Vault] 写入系统钥匙串失败，保存到本地密钥文件: %v", err)
+	if err := os.WriteFile(keyPath, []byte(encoded), 0600); err != nil {
+		return nil, fmt.Errorf("保存主密钥失败: %v", err)
+	}
+	return key, nil
+}
+
+func vaultKeyFilePath() string {
+	exe, err := os.Executable()
+	if err != nil {
+		return vaultKeyFile
+	}
+	return filepath.Join(filepath.Dir(exe), vaultKeyFile)
+}
+
+// unlockVault 通过与 RequireBiometric 相同的认证流程解锁主密钥
+func (a *App) unlockVault(reason string) ([]byte, error) {
+	if err := a.RequireBiometric(reason); err != nil {
+		return nil, err
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.vaultKey == nil {
+		key, err := loadVaultKey()
+		if err != nil {
+			log.Printf("[Vault] 加载主密钥失败: %v", err)
+			return nil, err
+		}
+		a.vaultKey = key
+	}
+	return a.vaultKey, nil
+}
+
+// sealWithKey 使用 AES-256-GCM 加密，返回 nonce + 密文
+func sealWithKey(key []byte, plaintext []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+// openWithKey 解密 sealWithKey 的输出
+func openWithKey(key []byte, sealed []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return nil, errors.New("密文长度无效")
+	}
+	nonce, ciphertext := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, fmt.Errorf("解密失败: %v", err)
+	}
+	return plaintext, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,109 @@
+package backend
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/zalando/go-keyring"
+)
+
+// useTestKeyring 在测试期间使用内存中的钥匙串，err 不为空时钥匙串的所有操作都返回该错误
+// 缓存的主密钥和本地密钥文件在测试前后清除
+func useTestKeyring(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		keyring.MockInitWithError(err)
+	} else {
+		keyring.MockInit()
+	}
+	reset := func() {
+		secretMu.Lock()
+		secretKey = nil
+		secretMu.Unlock()
+		os.Remove(vaultKeyFilePath())
+	}
+	reset()
+	t.Cleanup(reset)
+}
+
+func TestSaveConfigWithoutKeyring(t *testing.T) {
+	useTestKeyring(t, errors.New("org.freedesktop.secrets was not provided by any .service files"))
+
+	cfgMu.Lock()
+	savedPath, savedKey := configFilePath, Cfg.OpenAIAPIKey
+	configFilePath = filepath.Join(t.TempDir(), "eaiser.config.json")
+	Cfg.OpenAIAPIKey = "sk-test-key"
+	cfgMu.Unlock()
+	t.Cleanup(func() {
+		cfgMu.Lock()
+		configFilePath, Cfg.OpenAIAPIKey = savedPath, savedKey
+		cfgMu.Unlock()
+	})
+
+	if err := SaveConfig(); err != nil {
+		t.Fatalf("SaveConfig: %v", err)
+	}
+	info, err := os.Stat(vaultKeyFilePath())
+	if err != nil {
+		t.Fatalf("钥匙串不可用时没有生成本地密钥文件: %v", err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("本地密钥文件权限为 %o，期望 600", perm)
+	}
+
+	data, err := os.ReadFile(configFilePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var saved configFile
+	if err := json.Unmarshal(data, &saved); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(saved.APIKey, sealedKeyPrefix) {
+		t.Fatalf("配置文件中的 API Key 未加密: %q", saved.APIKey)
+	}
+
+	// 重新启动后从本地密钥文件读取主密钥
+	secretMu.Lock()
+	secretKey = nil
+	secretMu.Unlock()
+	apiKey, plaintext, err := openAPIKey(saved.APIKey)
+	if err != nil || plaintext || apiKey != "sk-test-key" {
+		t.Errorf("openAPIKey = %q, %v, %v，期望 sk-test-key", apiKey, plaintext, err)
+	}
+}
+
+func TestLoadVaultKeyKeepsInvalidKeyringKey(t *testing.T) {
+	useTestKeyring(t, nil)
+	if err := keyring.Set(keyringService, vaultKeyName, "not-a-key"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadVaultKey(); err == nil {
+		t.Fatal("钥匙串中的主密钥无效时 loadVaultKey 应返回错误")
+	}
+	if _, err := os.Stat(vaultKeyFilePath()); !os.IsNotExist(err) {
+		t.Errorf("钥匙串中的主密钥无效时不应生成本地密钥文件: %v", err)
+	}
+	if encoded, _ := keyring.Get(keyringService, vaultKeyName); encoded != "not-a-key" {
+		t.Errorf("钥匙串中的主密钥被覆盖为 %q", encoded)
+	}
+}
+
+func TestLoadVaultKeyStoresNewKeyInKeyring(t *testing.T) {
+	useTestKeyring(t, nil)
+	key, err := loadVaultKey()
+	if err != nil {
+		t.Fatalf("loadVaultKey: %v", err)
+	}
+	again, err := loadVaultKey()
+	if err != nil || string(again) != string(key) {
+		t.Errorf("再次读取的主密钥不一致: %v", err)
+	}
+	if _, err := os.Stat(vaultKeyFilePath()); !os.IsNotExist(err) {
+		t.Errorf("钥匙串可用时不应生成本地密钥文件: %v", err)
+	}
+}
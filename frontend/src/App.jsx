@@ -7,6 +7,7 @@ import CategoryView from './components/CategoryView.jsx'
 import NotesTab from './components/NotesTab.jsx'
 import TOCViewer from './components/TOCViewer.jsx'
 import AIChatTab from './components/AIChatTab.jsx'
+import QuizTab from './components/QuizTab.jsx'
 import AIConfigPanel from './components/AIConfigPanel.jsx'
 
 const { Sider, Content } = Layout
@@ -25,7 +26,7 @@ export default function App() {
       id: 'pane-1',
       activeCategory: null,
       selectedItem: null,
-      currentView: 'category', // 'category' | 'notes' | 'blank' | 'toc' | 'ai'
+      currentView: 'category', // 'category' | 'notes' | 'blank' | 'toc' | 'ai' | 'quiz'
       editingNote: null,
       tocData: null, // { headings, onHeadingClick, sourcePaneId } 用于目录 pane
     },
@@ -74,10 +75,27 @@ export default function App() {
       window.runtime.EventsOn('menu-refresh', () => {
         window.location.reload()
       })
+      // 定时运行的脚本失败时提示
+      window.runtime.EventsOn('script-schedule-failed', (failure) => {
+        message.error(`定时脚本「${failure.title || failure.noteId}」运行失败: ${failure.error}`)
+      })
+      // AI 工具需要写入数据时请求确认
+      window.runtime.EventsOn('ai-tool-confirm', (req) => {
+        Modal.confirm({
+          title: 'AI 请求执行操作',
+          content: req.summary,
+          okText: '允许',
+          cancelText: '拒绝',
+          onOk: () => window.go.backend.App.ConfirmAIToolCall(req.confirmId, true).catch(() => {}),
+          onCancel: () => window.go.backend.App.ConfirmAIToolCall(req.confirmId, false).catch(() => {}),
+        })
+      })
     }
     return () => {
       if (window.runtime) {
         window.runtime.EventsOff('menu-refresh')
+        window.runtime.EventsOff('script-schedule-failed')
+        window.runtime.EventsOff('ai-tool-confirm')
       }
     }
   }, [])
@@ -195,9 +213,9 @@ export default function App() {
       prev.map(p => {
         if (p.id !== paneId) return p
         const next = { ...p }
-        // 处理 AI 视图
-        if (view === 'ai' || (item && item.type === 'ai')) {
-          next.currentView = 'ai'
+        // 处理 AI 视图和测验视图
+        if (view === 'ai' || view === 'quiz' || (item && (item.type === 'ai' || item.type === 'quiz'))) {
+          next.currentView = view === 'quiz' || item?.type === 'quiz' ? 'quiz' : 'ai'
           next.selectedItem = null
           next.editingNote = null
           next.activeCategory = item?.categoryId ?? next.activeCategory
@@ -214,7 +232,7 @@ export default function App() {
         if (view) {
           next.currentView = view
           if (view !== 'notes') next.editingNote = null
-          if (view !== 'category' && view !== 'ai') next.selectedItem = null
+          if (view !== 'category' && view !== 'ai' && view !== 'quiz') next.selectedItem = null
         }
         if (item) {
           next.selectedItem = item
@@ -468,6 +486,15 @@ export default function App() {
                           mode: 'edit',
                         })
                       }}
+                      onOpenNote={async (note) => {
+                        if (!(await ensureUnlocked(note.categoryId, '内容'))) return
+                        handleNavigate(pane.id, 'category', {
+                          type: 'note',
+                          id: note.id,
+                          categoryId: note.categoryId,
+                          title: note.title,
+                        })
+                      }}
                       onSplitPane={() => splitPane(pane.id)}
                       onClosePane={() => closePane(pane.id)}
                       canClose={canClosePane}
@@ -668,6 +695,18 @@ export default function App() {
                     />
                   )
                 }
+                if (pane.currentView === 'quiz') {
+                  return (
+                    <QuizTab
+                      activeCategory={pane.activeCategory}
+                      categories={categories}
+                      onSplitPane={() => splitPane(pane.id)}
+                      onClosePane={() => closePane(pane.id)}
+                      canClose={canClosePane}
+                      ensureUnlocked={ensureUnlocked}
+                    />
+                  )
+                }
                 return <Empty description="请选择左侧目录或文件以在此面板展示" />
               })()
 
@@ -0,0 +1,100 @@
+import React, { useState } from 'react'
+import { Button, Dropdown, Modal, Spin, Typography, message, theme } from 'antd'
+import { RobotOutlined } from '@ant-design/icons'
+
+const ACTIONS = [
+  { key: 'summarize', label: '生成摘要' },
+  { key: 'retitle', label: '建议标题' },
+  {
+    key: 'translate',
+    label: '翻译',
+    children: [
+      { key: 'translate:英文', label: '翻译为英文' },
+      { key: 'translate:中文', label: '翻译为中文' },
+      { key: 'translate:日文', label: '翻译为日文' },
+    ],
+  },
+  { key: 'fix_grammar', label: '修正语法' },
+  { key: 'explain_code', label: '解释代码' },
+]
+
+// 取当前选中的文本，只有能在笔记原文中找到时才作为操作范围
+function currentSelection(contentMd) {
+  const text = window.getSelection()?.toString() || ''
+  if (text.trim() && (contentMd || '').includes(text)) {
+    return text
+  }
+  return ''
+}
+
+// 笔记 AI 操作：生成建议后预览差异，确认后写入笔记
+export default function AIActionMenu({ note, onApplied }) {
+  const { token } = theme.useToken()
+  const [running, setRunning] = useState(false)
+  const [result, setResult] = useState(null)
+  const [applying, setApplying] = useState(false)
+
+  async function run({ key }) {
+    const [action, language] = key.split(':')
+    const selection = currentSelection(note.contentMd)
+    setRunning(true)
+    setResult(null)
+    try {
+      const res = await window.go.backend.App.RunAIAction(note.id, action, selection, language || '', '')
+      setResult(res)
+    } catch (e) {
+      message.error('AI 操作失败: ' + (e.message || e))
+    } finally {
+      setRunning(false)
+    }
+  }
+
+  async function apply() {
+    setApplying(true)
+    try {
+      await window.go.backend.App.ApplyAIAction(result.noteId, result.title, result.contentMd, result.baseHash)
+      message.success('已应用到笔记')
+      setResult(null)
+      onApplied && onApplied()
+    } catch (e) {
+      message.error('应用失败: ' + (e.message || e))
+    } finally {
+      setApplying(false)
+    }
+  }
+
+  const lineColor = (line) => {
+    if (line.startsWith('+') && !line.startsWith('+++')) return token.colorSuccessText
+    if (line.startsWith('-') && !line.startsWith('---')) return token.colorErrorText
+    if (line.startsWith('@@')) return token.colorTextTertiary
+    return token.colorText
+  }
+
+  return (
+    <>
+      <Dropdown menu={{ items: ACTIONS, onClick: run }} trigger={['click']} disabled={running}>
+        <Button type="text" size="small" icon={running ? <Spin size="small" /> : <RobotOutlined />} title="AI 操作（选中文字时只处理选中部分）" />
+      </Dropdown>
+      <Modal
+        title="AI 建议"
+        open={!!result}
+        width={760}
+        onCancel={() => setResult(null)}
+        onOk={apply}
+        okText="应用到笔记"
+        confirmLoading={applying}
+        okButtonProps={{ disabled: !result?.diff }}
+      >
+        {result && (result.diff ? (
+          <pre style={{ maxHeight: '60vh', overflow: 'auto', fontSize: 12, background: token.colorFillTertiary, padding: 12, borderRadius: 6 }}>
+            {result.diff.split('\n').map((line, i) => (
+              <div key={i} style={{ color: lineColor(line) }}>{line || ' '}</div>
+            ))}
+          </pre>
+        ) : (
+          <Typography.Text type="secondary">建议与当前内容相同，无需修改。</Typography.Text>
+        ))}
+      </Modal>
+    </>
+  )
+}
@@ -0,0 +1,106 @@
+import React, { useEffect, useState } from 'react'
+import { Button, Input, List, Modal, Progress, Tag, Typography, message } from 'antd'
+import { CodeOutlined } from '@ant-design/icons'
+
+// AI 笔记操作的提示词模板，留空保存即恢复默认模板
+export default function AIActionPromptSettings() {
+  const [prompts, setPrompts] = useState([])
+  const [editing, setEditing] = useState(null)
+  const [template, setTemplate] = useState('')
+  const [progress, setProgress] = useState(null) // 批量生成代码片段分析的进度 { done, total, title }
+
+  async function load() {
+    try {
+      setPrompts(await window.go.backend.App.ListAIActionPrompts() || [])
+    } catch (e) {
+      message.error('加载提示词模板失败: ' + (e.message || '未知错误'))
+    }
+  }
+
+  useEffect(() => {
+    load()
+    const handler = (p) => setProgress({ done: p.done, total: p.total, title: p.title })
+    window.runtime.EventsOn('snippet-analysis-progress', handler)
+    return () => window.runtime.EventsOff('snippet-analysis-progress')
+  }, [])
+
+  // 为所有分析为空的代码片段笔记生成分析
+  async function generateAnalyses() {
+    try {
+      setProgress({ done: 0, total: 0 })
+      const failed = await window.go.backend.App.GenerateMissingSnippetAnalyses('')
+      if (failed > 0) {
+        message.warning(`代码片段分析生成完成，${failed} 篇笔记失败`)
+      } else {
+        message.success('代码片段分析生成完成')
+      }
+    } catch (e) {
+      message.error('生成代码片段分析失败: ' + (e.message || e))
+    } finally {
+      setProgress(null)
+    }
+  }
+
+  function openEditor(item) {
+    setEditing(item)
+    setTemplate(item.template)
+  }
+
+  async function save(value) {
+    try {
+      await window.go.backend.App.UpdateAIActionPrompt(editing.action, value)
+      message.success('提示词模板已保存')
+      setEditing(null)
+      load()
+    } catch (e) {
+      message.error('保存提示词模板失败: ' + (e.message || e))
+    }
+  }
+
+  return (
+    <div style={{ marginTop: 24, paddingTop: 24, borderTop: '1px solid #f0f0f0' }}>
+      <Typography.Text strong style={{ display: 'block', marginBottom: 12 }}>AI 笔记操作提示词</Typography.Text>
+      <List
+        size="small"
+        bordered
+        dataSource={prompts}
+        renderItem={(item) => (
+          <List.Item actions={[<a key="edit" onClick={() => openEditor(item)}>编辑</a>]}>
+            <span>{item.name}</span>
+            {!item.isDefault && <Tag color="blue" style={{ marginLeft: 8 }}>已自定义</Tag>}
+          </List.Item>
+        )}
+      />
+      <div style={{ marginTop: 12 }}>
+        <Button icon={<CodeOutlined />} onClick={generateAnalyses} loading={!!progress}>
+          为缺少分析的代码片段生成分析
+        </Button>
+        {progress && progress.total > 0 && (
+          <>
+            <Progress percent={Math.round(progress.done * 100 / progress.total)} style={{ marginTop: 12 }} />
+            <Typography.Text type="secondary" style={{ fontSize: 12 }}>{progress.title}</Typography.Text>
+          </>
+        )}
+      </div>
+      <Modal
+        title={editing ? `编辑提示词：${editing.name}` : ''}
+        open={!!editing}
+        width={640}
+        onCancel={() => setEditing(null)}
+        onOk={() => save(template)}
+        footer={(_, { OkBtn, CancelBtn }) => (
+          <>
+            <a style={{ float: 'left', lineHeight: '32px' }} onClick={() => save('')}>恢复默认</a>
+            <CancelBtn />
+            <OkBtn />
+          </>
+        )}
+      >
+        <Typography.Paragraph type="secondary" style={{ fontSize: 12 }}>
+          可用变量：<code>{'{{selection}}'}</code> 操作的内容（必填），<code>{'{{note_title}}'}</code> 笔记标题，<code>{'{{target_language}}'}</code> 翻译的目标语言，<code>{'{{language}}'}</code> 代码片段的语言
+        </Typography.Paragraph>
+        <Input.TextArea rows={8} value={template} onChange={e => setTemplate(e.target.value)} />
+      </Modal>
+    </div>
+  )
+}
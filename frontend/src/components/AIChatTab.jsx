@@ -1,9 +1,46 @@
 import React, { useState, useEffect, useRef, useMemo } from 'react'
-import { Button, Input, List, Typography, Tag, message, AutoComplete, Spin, theme } from 'antd'
-import { ColumnWidthOutlined, CloseOutlined, SendOutlined, RobotOutlined, FolderOutlined, FileTextOutlined, CloseCircleOutlined } from '@ant-design/icons'
+import { Button, Input, List, Typography, Tag, message, AutoComplete, Spin, Select, Tooltip, Checkbox, Dropdown, theme } from 'antd'
+import { ColumnWidthOutlined, CloseOutlined, SendOutlined, StopOutlined, RobotOutlined, FolderOutlined, FileTextOutlined, CloseCircleOutlined, PictureOutlined, SaveOutlined } from '@ant-design/icons'
 import { renderMarkdown } from '../lib/markdown'
+import { streamChat } from '../lib/aiStream'
+import { loadLocalImage } from '../lib/imageUtils'
 const { TextArea } = Input
 
+// 读取图片文件为 data URL
+const readAsDataURL = (file) => new Promise((resolve, reject) => {
+  const reader = new FileReader()
+  reader.onload = () => resolve(reader.result)
+  reader.onerror = () => reject(reader.error)
+  reader.readAsDataURL(file)
+})
+
+// 消息中附带的图片缩略图，历史消息只有相对路径时按需加载
+function ChatImageThumb({ path, preview, onRemove }) {
+  const [src, setSrc] = useState(preview || '')
+  useEffect(() => {
+    if (!preview) loadLocalImage(path).then(setSrc)
+  }, [path, preview])
+  return (
+    <span style={{ position: 'relative', display: 'inline-block' }}>
+      <img src={src} alt="" style={{ width: 56, height: 56, objectFit: 'cover', borderRadius: 4, display: 'block' }} />
+      {onRemove && (
+        <CloseCircleOutlined
+          onClick={onRemove}
+          style={{ position: 'absolute', top: -6, right: -6, background: '#fff', borderRadius: '50%', cursor: 'pointer' }}
+        />
+      )}
+    </span>
+  )
+}
+
+// 按错误类型给出的处理建议
+const ERROR_HINTS = {
+  auth: '请在 AI 配置中检查 API Key',
+  quota: '请稍后再试或检查服务商账户额度',
+  context_too_long: '请减少关联内容，或开启检索笔记代替整篇关联',
+  network: '请检查网络连接和 AI 配置中的代理设置',
+}
+
 export default function AIChatTab({ 
   activeCategory, 
   categories = [], 
@@ -25,6 +62,16 @@ export default function AIChatTab({
   const inputRef = useRef(null)
   const messagesEndRef = useRef(null)
   const mentionMenuRef = useRef(null)
+  const requestIdRef = useRef(null) // 进行中的流式请求 ID
+  const [conversationId, setConversationId] = useState(null)
+  const [conversations, setConversations] = useState([])
+  const [providers, setProviders] = useState([])
+  const [promptTemplates, setPromptTemplates] = useState([])
+  const [provider, setProvider] = useState('') // 空表示使用对话的服务商或默认服务商
+  const [retrieval, setRetrieval] = useState(false) // 是否按提问自动检索相关笔记片段
+  const [tools, setTools] = useState(false) // 是否允许 AI 调用笔记库工具
+  const [images, setImages] = useState([]) // 随提问发送的图片 [{ path, preview }]
+  const imageInputRef = useRef(null)
 
   // 加载当前目录下的笔记
   useEffect(() => {
@@ -43,6 +90,87 @@ export default function AIChatTab({
     loadNotes()
   }, [activeCategory])
 
+  // 加载对话列表
+  async function loadConversations() {
+    try {
+      const list = await window.go.backend.App.ListConversations()
+      setConversations(list || [])
+    } catch (e) {
+      console.error('加载对话列表失败:', e)
+    }
+  }
+
+  useEffect(() => {
+    loadConversations()
+    window.go.backend.App.ListAIProviders()
+      .then(list => setProviders(list.providers || []))
+      .catch(e => console.error('加载 AI 服务商失败:', e))
+    window.go.backend.App.ListPromptTemplates()
+      .then(list => setPromptTemplates(list || []))
+      .catch(e => console.error('加载提示词模板失败:', e))
+  }, [])
+
+  // 插入提示词模板，{{selection}} 取当前选中的文字，{{note_title}} 取第一个关联的笔记
+  const insertTemplate = async (templateId) => {
+    try {
+      const selection = window.getSelection()?.toString() || ''
+      const note = selectedContexts.find(ctx => ctx.type === 'note')
+      const text = await window.go.backend.App.RenderPromptTemplate(templateId, note?.id || 0, activeCategory || 0, selection)
+      setInputValue(prev => prev ? prev + '\n' + text : text)
+      inputRef.current?.focus()
+    } catch (e) {
+      message.error('插入模板失败: ' + (e.message || e))
+    }
+  }
+
+  // 打开历史对话
+  const openConversation = async (id) => {
+    try {
+      const list = await window.go.backend.App.GetConversationMessages(id)
+      setConversationId(id)
+      setProvider(conversations.find(c => c.id === id)?.provider || '')
+      setMessages((list || []).map(m => ({
+        id: m.id,
+        role: m.role,
+        content: m.content,
+        contexts: m.contextRefs || [],
+        images: (m.images || []).map(path => ({ path })),
+        citations: m.citations || []
+      })))
+    } catch (e) {
+      message.error('加载对话失败: ' + (e.message || '未知错误'))
+    }
+  }
+
+  // 导出当前对话为 Markdown 文件
+  const handleExport = async () => {
+    if (!conversationId) return
+    try {
+      const content = await window.go.backend.App.ExportConversation(conversationId, 'markdown')
+      const title = conversations.find(c => c.id === conversationId)?.title || 'conversation'
+      const url = URL.createObjectURL(new Blob([content], { type: 'text/markdown' }))
+      const a = document.createElement('a')
+      a.href = url
+      a.download = `${title}.md`
+      a.click()
+      URL.revokeObjectURL(url)
+    } catch (e) {
+      message.error('导出对话失败: ' + (e.message || '未知错误'))
+    }
+  }
+
+  // 删除当前对话
+  const handleDeleteConversation = async () => {
+    if (!conversationId) return
+    try {
+      await window.go.backend.App.DeleteConversation(conversationId)
+      handleClear()
+      loadConversations()
+    } catch (e) {
+      message.error('删除对话失败: ' + (e.message || '未知错误'))
+    }
+  }
+
   // 滚动到底部
   useEffect(() => {
     messagesEndRef.current?.scrollIntoView({ behavior: 'smooth' })
@@ -368,15 +496,36 @@ export default function AIChatTab({
     setInputValue(prev => prev.replace(regex, ''))
   }
 
+  // 保存粘贴或选择的图片，作为本次提问附带的图片
+  const addImageFiles = async (files) => {
+    for (const file of files) {
+      if (!file.type.startsWith('image/')) continue
+      try {
+        const dataURL = await readAsDataURL(file)
+        const path = await window.go.backend.App.SaveImage(dataURL)
+        setImages(prev => [...prev, { path, preview: dataURL }])
+      } catch (e) {
+        message.error('添加图片失败: ' + (e.message || e))
+      }
+    }
+  }
+
+  const handlePaste = (e) => {
+    const files = Array.from(e.clipboardData?.files || []).filter(f => f.type.startsWith('image/'))
+    if (files.length === 0) return
+    e.preventDefault()
+    addImageFiles(files)
+  }
+
   // 发送消息
   const handleSend = async () => {
-    if (!inputValue.trim() && selectedContexts.length === 0) {
+    if (!inputValue.trim() && selectedContexts.length === 0 && images.length === 0) {
       message.warning('请输入消息或选择关联内容')
       return
     }
 
     const userMessage = inputValue.trim()
-    if (!userMessage && selectedContexts.length === 0) {
+    if (!userMessage && selectedContexts.length === 0 && images.length === 0) {
       return
     }
 
@@ -399,98 +548,105 @@ export default function AIChatTab({
     // 添加用户消息
     const newUserMessage = {
       role: 'user',
-      content: userMessage || '（仅关联内容）',
-      contexts: [...selectedContexts]
+      content: userMessage || (selectedContexts.length > 0 ? '（仅关联内容）' : '（仅图片）'),
+      contexts: [...selectedContexts],
+      images: [...images]
     }
     setMessages(prev => [...prev, newUserMessage])
     setInputValue('')
     setSelectedContexts([])
+    setImages([])
     setLoading(true)
 
     try {
-      // 收集上下文内容
-      const contextTexts = []
-      console.log('[AIChatTab] 开始收集上下文，selectedContexts:', selectedContexts)
-      
-      for (const ctx of selectedContexts) {
-        try {
-          console.log(`[AIChatTab] 处理上下文: type=${ctx.type}, id=${ctx.id}, name=${ctx.name}`)
-          
-          if (ctx.type === 'category') {
-            console.log(`[AIChatTab] 调用 GetCategoryContent: categoryId=${ctx.id}`)
-            const content = await window.go.backend.App.GetCategoryContent(ctx.id)
-            console.log(`[AIChatTab] GetCategoryContent 返回: length=${content?.length || 0}, content=${content?.substring(0, 100) || 'empty'}...`)
-            
-            if (content && content.trim()) {
-              console.log(`[AIChatTab] 处理后内容长度: ${content.length}`)
-              contextTexts.push(`[目录: ${ctx.name}]\n${content}`)
-              console.log(`[AIChatTab] 已添加目录上下文: ${ctx.name}`)
-            } else {
-              console.warn(`[AIChatTab] 目录内容为空: ${ctx.name} (id=${ctx.id})`)
-            }
-          } else if (ctx.type === 'note') {
-            console.log(`[AIChatTab] 调用 GetNoteContent: noteId=${ctx.id}`)
-            const content = await window.go.backend.App.GetNoteContent(ctx.id)
-            console.log(`[AIChatTab] GetNoteContent 返回: length=${content?.length || 0}`)
-            
-            if (content && content.trim()) {
-              contextTexts.push(`[笔记: ${ctx.name}]\n${content}`)
-              console.log(`[AIChatTab] 已添加笔记上下文: ${ctx.name}`)
-            } else {
-              console.warn(`[AIChatTab] 笔记内容为空: ${ctx.name} (id=${ctx.id})`)
-            }
-          }
-        } catch (e) {
-          console.error(`[AIChatTab] 获取${ctx.type}内容失败:`, e)
-        }
+      // 上下文和历史消息由后端按对话组装，回复以流式事件逐段追加到最后一条助手消息
+      setMessages(prev => [...prev, { role: 'assistant', content: '' }])
+      const updateReply = (content) => {
+        setMessages(prev => [...prev.slice(0, -1), { ...prev[prev.length - 1], content }])
       }
-      
-      console.log(`[AIChatTab] 上下文收集完成，contextTexts.length=${contextTexts.length}`)
-
-      // 加入历史对话（只带最近若干条，避免上下文过长）
-      if (messages.length > 0) {
-        const historyLimit = 10 // 只保留最近 10 条
-        const recent = messages.slice(-historyLimit)
-        const historyText = recent
-          .map(msg => {
-            const roleLabel = msg.role === 'user' ? '用户' : '助手'
-            return `${roleLabel}: ${msg.content}`
+      const done = await streamChat(
+        async () => {
+          const result = await window.go.backend.App.SendMessage(conversationId || 0, userMessage, newUserMessage.contexts, {
+            provider,
+            retrieval,
+            tools,
+            categoryId: activeCategory || 0,
+            images: newUserMessage.images.map(img => img.path)
           })
-          .join('\n\n')
-
-        if (historyText.trim()) {
-          contextTexts.unshift(`[对话历史]\n${historyText}`)
+          setConversationId(result.conversationId)
+          // 记录实际放入的上下文，超出模型上下文窗口时提示被略过的部分
+          const report = result.context
+          setMessages(prev => prev.map(m => m === newUserMessage ? { ...m, contextReport: report, promptTemplate: result.promptTemplate } : m))
+          return result.requestId
+        },
+        {
+          onStart: (id) => { requestIdRef.current = id },
+          onDelta: (_, content) => updateReply(content)
         }
-      }
-
-      // 调用 AI API
-      const response = await window.go.backend.App.ChatWithAI(
-        userMessage || '请分析关联的内容',
-        contextTexts
       )
-      
-      // 添加 AI 回复
-      setMessages(prev => [...prev, {
-        role: 'assistant',
-        content: response
-      }])
+      loadConversations()
+      if (done.error) {
+        const err = new Error(done.error)
+        err.kind = done.errorKind
+        throw err
+      }
+      updateReply(done.canceled ? done.content + '\n\n（已停止）' : done.content)
+      if (done.budgetWarning) {
+        message.warning(done.budgetWarning)
+      }
+      if (done.messageId || done.citations?.length || done.toolCalls?.length) {
+        setMessages(prev => [...prev.slice(0, -1), { ...prev[prev.length - 1], id: done.messageId, citations: done.citations, toolCalls: done.toolCalls }])
+      }
     } catch (e) {
       console.error('AI 对话失败:', e)
-      message.error('AI 对话失败: ' + (e.message || '未知错误'))
-      setMessages(prev => [...prev, {
-        role: 'assistant',
-        content: '抱歉，发生了错误：' + (e.message || '未知错误')
-      }])
+      message.error('AI 对话失败: ' + (e.message || '未知错误') + (ERROR_HINTS[e.kind] ? '。' + ERROR_HINTS[e.kind] : ''))
+      setMessages(prev => {
+        // 去掉未收到内容的回复占位
+        const last = prev[prev.length - 1]
+        const rest = last?.role === 'assistant' && !last.content ? prev.slice(0, -1) : prev
+        return [...rest, {
+          role: 'assistant',
+          content: '抱歉，发生了错误：' + (e.message || '未知错误')
+        }]
+      })
     } finally {
+      requestIdRef.current = null
       setLoading(false)
     }
   }
 
-  // 清空对话
+  // 将回答保存为当前目录中的笔记，笔记中的来源会链接到作为上下文的笔记
+  const handleSaveAsNote = async (msg) => {
+    if (!activeCategory) {
+      message.warning('请先选择目录')
+      return
+    }
+    try {
+      const note = await window.go.backend.App.SaveChatAnswerAsNote(0, msg.id, activeCategory)
+      message.success(`已保存为笔记「${note.title}」`)
+    } catch (e) {
+      message.error('保存为笔记失败: ' + (e.message || e))
+    }
+  }
+
+  // 停止生成
+  const handleStop = async () => {
+    if (!requestIdRef.current) return
+    try {
+      await window.go.backend.App.CancelChat(requestIdRef.current)
+    } catch (e) {
+      console.error('停止对话失败:', e)
+    }
+  }
+
+  // 开始新对话
   const handleClear = () => {
+    setConversationId(null)
+    setProvider('')
     setMessages([])
     setInputValue('')
     setSelectedContexts([])
+    setImages([])
   }
 
   // 处理键盘事件
@@ -675,8 +831,45 @@ export default function AIChatTab({
                               {ctx.type === 'category' ? '目录' : '笔记'}: {ctx.name}
                             </Tag>
                           ))}
+                          {msg.contextReport && (msg.contextReport.omitted.length > 0 || msg.contextReport.included.some(e => e.truncated)) && (
+                            <Tooltip
+                              title={
+                                <div style={{ fontSize: 12 }}>
+                                  <div>已放入：</div>
+                                  {msg.contextReport.included.map((e, i) => (
+                                    <div key={i}>· {e.title}{e.heading ? ` / ${e.heading}` : ''}（{e.tokens} tokens{e.truncated ? '，已截断' : ''}）</div>
+                                  ))}
+                                  <div style={{ marginTop: 4 }}>已略过：</div>
+                                  {msg.contextReport.omitted.map((e, i) => (
+                                    <div key={i}>· {e.title}{e.heading ? ` / ${e.heading}` : ''}（{e.tokens} tokens）</div>
+                                  ))}
+                                </div>
+                              }
+                            >
+                              <Tag color="warning" style={{ marginLeft: 4 }}>
+                                内容过长，已按{msg.contextReport.strategy === 'recency' ? '更新时间' : '相关度'}选取 {msg.contextReport.used}/{msg.contextReport.budget} tokens
+                              </Tag>
+                            </Tooltip>
+                          )}
+                        </div>
+                      )}
+                      {msg.images?.length > 0 && (
+                        <div style={{ display: 'flex', gap: 6, flexWrap: 'wrap', marginBottom: 8 }}>
+                          {msg.images.map((img, i) => <ChatImageThumb key={i} path={img.path} preview={img.preview} />)}
                         </div>
                       )}
+                      {msg.contextReport?.omittedImages?.length > 0 && (
+                        <Tooltip title={msg.contextReport.omittedImages.join('\n')}>
+                          <Tag color="warning" style={{ marginBottom: 8 }}>
+                            {msg.contextReport.omittedImages.length} 张图片未发送（超出数量或上下文限制）
+                          </Tag>
+                        </Tooltip>
+                      )}
+                      {msg.promptTemplate && (
+                        <Tooltip title="当前目录设置的系统提示词模板">
+                          <Tag color="purple" style={{ marginBottom: 8 }}>人设：{msg.promptTemplate}</Tag>
+                        </Tooltip>
+                      )}
                       {msg.role === 'assistant' ? (
                         <div 
                           style={{ 
@@ -695,6 +888,52 @@ export default function AIChatTab({
                           {msg.content}
                         </Typography.Text>
                       )}
+                      {msg.role === 'assistant' && msg.toolCalls?.length > 0 && (
+                        <div style={{ marginTop: 8 }}>
+                          <Typography.Text type="secondary" style={{ fontSize: 12 }}>
+                            工具调用：
+                          </Typography.Text>
+                          {msg.toolCalls.map((call, i) => (
+                            <Tooltip key={i} title={<pre style={{ margin: 0, whiteSpace: 'pre-wrap', fontSize: 12 }}>{call.arguments}{'\n'}{call.error || call.result || ''}</pre>}>
+                              <Tag color={call.error ? 'error' : call.denied ? 'default' : 'processing'} style={{ marginLeft: 4 }}>
+                                {call.name}{call.denied ? '（已拒绝）' : ''}
+                              </Tag>
+                            </Tooltip>
+                          ))}
+                        </div>
+                      )}
+                      {msg.role === 'assistant' && msg.citations?.length > 0 && (
+                        <div style={{
+                          marginTop: 8,
+                          paddingTop: 8,
+                          borderTop: `1px solid ${token.colorBorderSecondary}`
+                        }}>
+                          <Typography.Text type="secondary" style={{ fontSize: 12 }}>
+                            引用：
+                          </Typography.Text>
+                          {msg.citations.map((c, i) => (
+                            <Tooltip key={i} title={`笔记 #${c.noteId}，相似度 ${c.score.toFixed(2)}`}>
+                              <Tag icon={<FileTextOutlined />} style={{ marginLeft: 4 }}>
+                                {c.title}{c.heading ? ` / ${c.heading}` : ''}
+                              </Tag>
+                            </Tooltip>
+                          ))}
+                        </div>
+                      )}
+                      {msg.role === 'assistant' && msg.id && (
+                        <div style={{ marginTop: 8 }}>
+                          <Button
+                            size="small"
+                            type="text"
+                            icon={<SaveOutlined />}
+                            onClick={() => handleSaveAsNote(msg)}
+                            disabled={!activeCategory}
+                            title={activeCategory ? '保存到当前目录' : '请先选择目录'}
+                          >
+                            保存为笔记
+                          </Button>
+                        </div>
+                      )}
                     </div>
                   </div>
                 </List.Item>
@@ -711,13 +950,26 @@ export default function AIChatTab({
 
         {/* 输入区域 */}
         <div style={{ padding: '12px', borderTop: `1px solid ${token.colorBorderSecondary}` }}>
+          {images.length > 0 && (
+            <div style={{ display: 'flex', gap: 8, flexWrap: 'wrap', marginBottom: 8 }}>
+              {images.map((img, i) => (
+                <ChatImageThumb
+                  key={img.path}
+                  path={img.path}
+                  preview={img.preview}
+                  onRemove={() => setImages(prev => prev.filter((_, j) => j !== i))}
+                />
+              ))}
+            </div>
+          )}
           <div style={{ position: 'relative' }}>
             <TextArea
               ref={inputRef}
               value={inputValue}
               onChange={handleInputChange}
               onKeyDown={handleKeyDown}
-              placeholder="输入消息... 使用 @ 符号关联目录或笔记"
+              onPaste={handlePaste}
+              placeholder="输入消息... 使用 @ 符号关联目录或笔记，可粘贴图片"
               autoSize={{ minRows: 2, maxRows: 6 }}
               style={{ marginBottom: 8 }}
             />
@@ -767,18 +1019,74 @@ export default function AIChatTab({
             )}
           </div>
           <div style={{ display: 'flex', justifyContent: 'space-between', alignItems: 'center' }}>
-            <Button onClick={handleClear} disabled={messages.length === 0}>
-              清空对话
-            </Button>
-            <Button
-              type="primary"
-              icon={<SendOutlined />}
-              onClick={handleSend}
-              loading={loading}
-              disabled={!inputValue.trim() && selectedContexts.length === 0}
-            >
-              发送
-            </Button>
+            <div style={{ display: 'flex', gap: 8 }}>
+              <Button onClick={handleClear} disabled={messages.length === 0 || loading}>
+                新对话
+              </Button>
+              <Select
+                style={{ width: 200 }}
+                placeholder="历史对话"
+                value={conversationId || undefined}
+                onChange={openConversation}
+                disabled={loading}
+                options={conversations.map(c => ({ value: c.id, label: c.title }))}
+              />
+              <Select
+                style={{ width: 140 }}
+                value={provider}
+                onChange={setProvider}
+                disabled={loading}
+                options={[
+                  { value: '', label: '默认服务商' },
+                  ...providers.map(p => ({ value: p.name, label: p.name }))
+                ]}
+              />
+              <Tooltip title="按提问自动检索最相关的笔记片段作为上下文，需先在 AI 配置中开启向量索引">
+                <Checkbox checked={retrieval} onChange={e => setRetrieval(e.target.checked)} disabled={loading} style={{ alignSelf: 'center' }}>
+                  检索笔记
+                </Checkbox>
+              </Tooltip>
+              <Tooltip title="允许 AI 搜索、读取笔记和创建笔记草稿，创建前会请求确认；加密目录对 AI 不可见">
+                <Checkbox checked={tools} onChange={e => setTools(e.target.checked)} disabled={loading} style={{ alignSelf: 'center' }}>
+                  工具
+                </Checkbox>
+              </Tooltip>
+              <Tooltip title="随提问发送图片，需要模型支持图片输入">
+                <Button icon={<PictureOutlined />} onClick={() => imageInputRef.current?.click()} disabled={loading} />
+              </Tooltip>
+              <input
+                ref={imageInputRef}
+                type="file"
+                accept="image/*"
+                multiple
+                style={{ display: 'none' }}
+                onChange={e => { addImageFiles(Array.from(e.target.files || [])); e.target.value = '' }}
+              />
+              {promptTemplates.length > 0 && (
+                <Dropdown
+                  menu={{ items: promptTemplates.map(t => ({ key: String(t.id), label: t.name })), onClick: ({ key }) => insertTemplate(Number(key)) }}
+                  disabled={loading}
+                >
+                  <Button>插入模板</Button>
+                </Dropdown>
+              )}
+              <Button onClick={handleExport} disabled={!conversationId}>导出</Button>
+              <Button danger onClick={handleDeleteConversation} disabled={!conversationId || loading}>删除</Button>
+            </div>
+            {loading ? (
+              <Button danger icon={<StopOutlined />} onClick={handleStop}>
+                停止
+              </Button>
+            ) : (
+              <Button
+                type="primary"
+                icon={<SendOutlined />}
+                onClick={handleSend}
+                disabled={!inputValue.trim() && selectedContexts.length === 0 && images.length === 0}
+              >
+                发送
+              </Button>
+            )}
           </div>
         </div>
       </div>
@@ -0,0 +1,66 @@
+import React, { useEffect, useState } from 'react'
+import { Button, Form, Input, InputNumber, Typography, message } from 'antd'
+import { SaveOutlined } from '@ant-design/icons'
+
+// AI 请求的网络设置：超时、代理和失败重试，对所有服务商生效
+export default function AIClientSettings() {
+  const [form] = Form.useForm()
+  const [saving, setSaving] = useState(false)
+
+  async function load() {
+    try {
+      form.setFieldsValue(await window.go.backend.App.GetAIClientConfig())
+    } catch (e) {
+      message.error('加载网络设置失败: ' + (e.message || '未知错误'))
+    }
+  }
+
+  useEffect(() => { load() }, [])
+
+  async function save() {
+    try {
+      setSaving(true)
+      const values = await form.validateFields()
+      await window.go.backend.App.UpdateAIClientConfig({
+        connectTimeout: Number(values.connectTimeout) || 0,
+        responseTimeout: Number(values.responseTimeout) || 0,
+        proxy: values.proxy || '',
+        maxRetries: Number(values.maxRetries) || 0,
+        retryBaseDelay: Number(values.retryBaseDelay) || 0,
+      })
+      message.success('网络设置已保存')
+      load()
+    } catch (e) {
+      if (e.errorFields) return
+      message.error('保存网络设置失败: ' + (e.message || e))
+    } finally {
+      setSaving(false)
+    }
+  }
+
+  return (
+    <div style={{ marginTop: 24, paddingTop: 24, borderTop: '1px solid #f0f0f0' }}>
+      <Typography.Text strong style={{ display: 'block', marginBottom: 12 }}>网络设置</Typography.Text>
+      <Form form={form} layout="vertical">
+        <Form.Item label="代理" name="proxy" tooltip="支持 http、https、socks5；留空使用系统环境变量中的代理，填 direct 表示不使用代理">
+          <Input placeholder="http://127.0.0.1:7890" />
+        </Form.Item>
+        <Form.Item label="连接超时（秒）" name="connectTimeout">
+          <InputNumber min={1} style={{ width: '100%' }} />
+        </Form.Item>
+        <Form.Item label="响应超时（秒）" name="responseTimeout" tooltip="等待服务商开始响应的最长时间，0 表示不限制；流式回复开始后不受限制。一次对话（含重试）最长 5 分钟">
+          <InputNumber min={0} style={{ width: '100%' }} />
+        </Form.Item>
+        <Form.Item label="最大重试次数" name="maxRetries" tooltip="遇到 429 限流和 5xx 错误时重试，优先按服务商返回的 Retry-After 等待">
+          <InputNumber min={0} max={10} style={{ width: '100%' }} />
+        </Form.Item>
+        <Form.Item label="首次重试等待（毫秒）" name="retryBaseDelay" tooltip="之后每次重试等待时间翻倍">
+          <InputNumber min={100} step={500} style={{ width: '100%' }} />
+        </Form.Item>
+        <Button type="primary" icon={<SaveOutlined />} onClick={save} loading={saving}>
+          保存
+        </Button>
+      </Form>
+    </div>
+  )
+}
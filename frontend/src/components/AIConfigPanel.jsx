@@ -1,6 +1,12 @@
 import React, { useEffect, useState } from 'react'
-import { Button, Input, Form, Card, message, Typography, Space, Alert, Modal, Progress, List } from 'antd'
+import { Button, Input, InputNumber, Form, Card, message, Typography, Space, Alert, Modal, Progress, List, Select, Switch } from 'antd'
 import { SettingOutlined, SaveOutlined, ReloadOutlined, PictureOutlined } from '@ant-design/icons'
+import AIProviderSettings, { VISION_OPTIONS } from './AIProviderSettings'
+import SemanticIndexSettings from './SemanticIndexSettings'
+import AIActionPromptSettings from './AIActionPromptSettings'
+import PromptTemplateSettings from './PromptTemplateSettings'
+import AIClientSettings from './AIClientSettings'
+import AIUsageSettings from './AIUsageSettings'
 
 const { TextArea } = Input
 
@@ -8,6 +14,7 @@ export default function AIConfigPanel({ onClose }) {
   const [form] = Form.useForm()
   const [loading, setLoading] = useState(false)
   const [configPath, setConfigPath] = useState('')
+  const [keyError, setKeyError] = useState('')
   const [migrateModalVisible, setMigrateModalVisible] = useState(false)
   const [migrating, setMigrating] = useState(false)
   const [migrateResult, setMigrateResult] = useState(null)
@@ -18,12 +25,18 @@ export default function AIConfigPanel({ onClose }) {
       form.setFieldsValue({
         apiKey: config.apiKey || '',
         apiURL: config.apiURL || '',
-        model: config.model || ''
+        model: config.model || '',
+        maxTokens: config.maxTokens || 0,
+        contextTokens: config.contextTokens || 0,
+        contextStrategy: config.contextStrategy || 'relevance',
+        vision: config.vision || '',
+        privacyMode: config.privacyMode !== false
       })
       
       // 获取配置文件路径
       const path = await window.go.backend.App.GetConfigFilePath()
       setConfigPath(path)
+      setKeyError(await window.go.backend.App.GetConfigKeyError())
     } catch (e) {
       console.error('加载配置失败:', e)
       message.error('加载配置失败: ' + (e.message || '未知错误'))
@@ -37,7 +50,12 @@ export default function AIConfigPanel({ onClose }) {
       await window.go.backend.App.UpdateAIConfig({
         apiKey: values.apiKey || '',
         apiURL: values.apiURL || '',
-        model: values.model || ''
+        model: values.model || '',
+        maxTokens: Number(values.maxTokens) || 0,
+        contextTokens: Number(values.contextTokens) || 0,
+        contextStrategy: values.contextStrategy || 'relevance',
+        vision: values.vision || '',
+        privacyMode: !!values.privacyMode
       })
       message.success('配置已保存')
       if (onClose) {
@@ -107,6 +125,20 @@ export default function AIConfigPanel({ onClose }) {
           </Space>
         }
       >
+        {keyError && (
+          <Alert
+            message="API Key 无法解密，配置暂不能保存"
+            description={
+              <div>
+                <p>{keyError}</p>
+                <p>为避免覆盖配置文件中已加密的 API Key，修复系统钥匙串或主密钥后重新启动应用再修改配置。</p>
+              </div>
+            }
+            type="error"
+            showIcon
+            style={{ marginBottom: 16 }}
+          />
+        )}
         <Alert
           message="配置说明"
           description={
@@ -131,7 +163,7 @@ export default function AIConfigPanel({ onClose }) {
             rules={[
               { required: true, message: '请输入 API Key' }
             ]}
-            tooltip="OpenAI API Key 或兼容的 API Key"
+            tooltip="OpenAI API Key 或兼容的 API Key，加密保存，这里只显示脱敏后的值，不修改时保持原样即可"
           >
             <Input.Password
               placeholder="sk-..."
@@ -166,6 +198,52 @@ export default function AIConfigPanel({ onClose }) {
             />
           </Form.Item>
 
+          <Form.Item
+            label="Max Tokens"
+            name="maxTokens"
+            tooltip="单次回复的最大 token 数，0 表示不限制，由服务端决定"
+          >
+            <InputNumber min={0} step={256} style={{ width: '100%' }} />
+          </Form.Item>
+
+          <Form.Item
+            label="上下文窗口"
+            name="contextTokens"
+            tooltip="模型的上下文窗口（token），关联内容超出时自动裁剪；0 表示默认 16384"
+          >
+            <InputNumber min={0} step={4096} style={{ width: '100%' }} />
+          </Form.Item>
+
+          <Form.Item
+            label="裁剪策略"
+            name="contextStrategy"
+            tooltip="关联内容超出上下文窗口时优先保留的内容，对所有服务商生效"
+          >
+            <Select
+              options={[
+                { value: 'relevance', label: '与提问最相关的内容' },
+                { value: 'recency', label: '最近更新的笔记' }
+              ]}
+            />
+          </Form.Item>
+
+          <Form.Item
+            label="图片输入"
+            name="vision"
+            tooltip="支持时，提问和关联笔记中引用的图片会缩小后随请求发送"
+          >
+            <Select options={VISION_OPTIONS} />
+          </Form.Item>
+
+          <Form.Item
+            label="隐私模式"
+            name="privacyMode"
+            valuePropName="checked"
+            tooltip="开启后日志只记录提示词和回复的长度及耗时，不记录具体内容"
+          >
+            <Switch />
+          </Form.Item>
+
           <Form.Item>
             <Space>
               <Button
@@ -188,6 +266,18 @@ export default function AIConfigPanel({ onClose }) {
           </Form.Item>
         </Form>
 
+        <AIProviderSettings />
+
+        <AIClientSettings />
+
+        <AIUsageSettings />
+
+        <SemanticIndexSettings />
+
+        <AIActionPromptSettings />
+
+        <PromptTemplateSettings />
+
         <div style={{ marginTop: 24, paddingTop: 24, borderTop: '1px solid #f0f0f0' }}>
           <Alert
             message="图片迁移工具"
@@ -0,0 +1,158 @@
+import React, { useEffect, useState } from 'react'
+import { Button, Form, Input, InputNumber, List, Modal, Select, Space, Tag, Typography, message } from 'antd'
+import { PlusOutlined } from '@ant-design/icons'
+
+const KIND_OPTIONS = [
+  { value: 'openai', label: 'OpenAI 兼容（含 llama.cpp / vLLM）' },
+  { value: 'anthropic', label: 'Anthropic Messages API' },
+  { value: 'ollama', label: 'Ollama' },
+]
+
+// 模型是否支持图片输入，为空时按模型名称判断
+export const VISION_OPTIONS = [
+  { value: '', label: '自动（按模型名称判断）' },
+  { value: 'on', label: '支持' },
+  { value: 'off', label: '不支持' },
+]
+
+const URL_PLACEHOLDER = {
+  openai: 'https://api.openai.com/v1/chat/completions',
+  anthropic: 'https://api.anthropic.com/v1/messages',
+  ollama: 'http://localhost:11434/api/chat',
+}
+
+// AI 服务商管理：default 对应上方的 AI 配置，其余为额外的服务商
+export default function AIProviderSettings() {
+  const [form] = Form.useForm()
+  const [providers, setProviders] = useState([])
+  const [defaultName, setDefaultName] = useState('default')
+  const [editing, setEditing] = useState(null) // null: 关闭, {}: 新建, provider: 编辑
+  const kind = Form.useWatch('kind', form)
+
+  async function load() {
+    try {
+      const list = await window.go.backend.App.ListAIProviders()
+      setProviders(list.providers || [])
+      setDefaultName(list.default)
+    } catch (e) {
+      message.error('加载 AI 服务商失败: ' + (e.message || '未知错误'))
+    }
+  }
+
+  useEffect(() => { load() }, [])
+
+  function openEditor(provider) {
+    setEditing(provider || {})
+    form.setFieldsValue({
+      name: provider?.name || '',
+      kind: provider?.kind || 'openai',
+      apiURL: provider?.apiURL || '',
+      apiKey: '',
+      model: provider?.model || '',
+      maxTokens: provider?.maxTokens || 0,
+      contextTokens: provider?.contextTokens || 0,
+      vision: provider?.vision || '',
+    })
+  }
+
+  async function save() {
+    try {
+      const values = await form.validateFields()
+      await window.go.backend.App.SaveAIProvider({
+        ...values,
+        maxTokens: Number(values.maxTokens) || 0,
+        contextTokens: Number(values.contextTokens) || 0,
+        vision: values.vision || '',
+      })
+      message.success('服务商已保存')
+      setEditing(null)
+      load()
+    } catch (e) {
+      if (e.errorFields) return
+      message.error('保存服务商失败: ' + (e.message || e))
+    }
+  }
+
+  async function remove(name) {
+    try {
+      await window.go.backend.App.DeleteAIProvider(name)
+      load()
+    } catch (e) {
+      message.error('删除服务商失败: ' + (e.message || e))
+    }
+  }
+
+  async function setDefault(name) {
+    try {
+      await window.go.backend.App.SetDefaultAIProvider(name)
+      load()
+    } catch (e) {
+      message.error('设置默认服务商失败: ' + (e.message || e))
+    }
+  }
+
+  return (
+    <div style={{ marginTop: 24, paddingTop: 24, borderTop: '1px solid #f0f0f0' }}>
+      <div style={{ display: 'flex', justifyContent: 'space-between', marginBottom: 12 }}>
+        <Typography.Text strong>AI 服务商</Typography.Text>
+        <Button size="small" icon={<PlusOutlined />} onClick={() => openEditor(null)}>添加服务商</Button>
+      </div>
+      <List
+        size="small"
+        bordered
+        dataSource={providers}
+        renderItem={(p) => (
+          <List.Item
+            actions={[
+              p.name !== defaultName && <a key="default" onClick={() => setDefault(p.name)}>设为默认</a>,
+              p.name !== 'default' && <a key="edit" onClick={() => openEditor(p)}>编辑</a>,
+              p.name !== 'default' && <a key="delete" onClick={() => remove(p.name)}>删除</a>,
+            ].filter(Boolean)}
+          >
+            <Space>
+              <span>{p.name}</span>
+              <Tag>{p.kind}</Tag>
+              <Typography.Text type="secondary">{p.model}</Typography.Text>
+              {p.name === defaultName && <Tag color="blue">默认</Tag>}
+            </Space>
+          </List.Item>
+        )}
+      />
+
+      <Modal
+        title={editing?.name ? `编辑服务商：${editing.name}` : '添加服务商'}
+        open={editing !== null}
+        onOk={save}
+        onCancel={() => setEditing(null)}
+        destroyOnClose
+      >
+        <Form form={form} layout="vertical">
+          <Form.Item label="名称" name="name" rules={[{ required: true, message: '请输入名称' }]}>
+            <Input disabled={!!editing?.name} />
+          </Form.Item>
+          <Form.Item label="类型" name="kind" rules={[{ required: true }]}>
+            <Select options={KIND_OPTIONS} />
+          </Form.Item>
+          <Form.Item label="接口地址" name="apiURL" rules={[{ required: true, message: '请输入接口地址' }]}>
+            <Input placeholder={URL_PLACEHOLDER[kind] || URL_PLACEHOLDER.openai} />
+          </Form.Item>
+          <Form.Item label="API Key" name="apiKey" tooltip={editing?.name ? '留空则保留原值' : '本地服务可留空'}>
+            <Input.Password autoComplete="off" />
+          </Form.Item>
+          <Form.Item label="模型" name="model" rules={[{ required: true, message: '请输入模型名称' }]}>
+            <Input />
+          </Form.Item>
+          <Form.Item label="Max Tokens" name="maxTokens" tooltip="0 表示使用服务商默认值">
+            <InputNumber min={0} step={256} style={{ width: '100%' }} />
+          </Form.Item>
+          <Form.Item label="上下文窗口" name="contextTokens" tooltip="模型的上下文窗口（token），关联内容超出时自动裁剪；0 表示默认 16384">
+            <InputNumber min={0} step={4096} style={{ width: '100%' }} />
+          </Form.Item>
+          <Form.Item label="图片输入" name="vision" tooltip="支持时，提问和关联笔记中引用的图片会缩小后随请求发送">
+            <Select options={VISION_OPTIONS} />
+          </Form.Item>
+        </Form>
+      </Modal>
+    </div>
+  )
+}
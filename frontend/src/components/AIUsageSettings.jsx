@@ -0,0 +1,164 @@
+import React, { useEffect, useState } from 'react'
+import { Button, DatePicker, Form, Input, InputNumber, Progress, Select, Space, Table, Typography, message } from 'antd'
+import { DeleteOutlined, PlusOutlined, ReloadOutlined, SaveOutlined } from '@ant-design/icons'
+import dayjs from 'dayjs'
+
+const GROUP_OPTIONS = [
+  { value: 'model', label: '按模型' },
+  { value: 'provider', label: '按服务商' },
+  { value: 'day', label: '按天' },
+  { value: 'month', label: '按月' },
+  { value: 'source', label: '按来源' },
+  { value: 'status', label: '按状态' },
+]
+
+// AI 用量统计、模型价格和月度预算
+export default function AIUsageSettings() {
+  const [form] = Form.useForm()
+  const [range, setRange] = useState([dayjs().startOf('month'), dayjs()])
+  const [groupBy, setGroupBy] = useState('model')
+  const [report, setReport] = useState(null)
+  const [saving, setSaving] = useState(false)
+
+  async function loadReport() {
+    try {
+      const from = range?.[0] ? range[0].format('YYYY-MM-DD') : ''
+      const to = range?.[1] ? range[1].format('YYYY-MM-DD') : ''
+      setReport(await window.go.backend.App.GetAIUsage(from, to, groupBy))
+    } catch (e) {
+      message.error('加载用量统计失败: ' + (e.message || e))
+    }
+  }
+
+  async function loadConfig() {
+    try {
+      const config = await window.go.backend.App.GetUsageConfig()
+      form.setFieldsValue({ ...config, prices: config.prices || [] })
+    } catch (e) {
+      message.error('加载用量配置失败: ' + (e.message || '未知错误'))
+    }
+  }
+
+  useEffect(() => { loadConfig() }, [])
+  useEffect(() => { loadReport() }, [range, groupBy])
+
+  async function save() {
+    try {
+      setSaving(true)
+      const values = await form.validateFields()
+      await window.go.backend.App.UpdateUsageConfig({
+        currency: values.currency || '',
+        monthlyBudget: Number(values.monthlyBudget) || 0,
+        budgetAction: values.budgetAction || 'warn',
+        prices: (values.prices || []).map(p => ({ model: p.model || '', input: Number(p.input) || 0, output: Number(p.output) || 0 })),
+      })
+      message.success('用量配置已保存')
+      loadConfig()
+      loadReport()
+    } catch (e) {
+      if (e.errorFields) return
+      message.error('保存用量配置失败: ' + (e.message || e))
+    } finally {
+      setSaving(false)
+    }
+  }
+
+  const currency = report?.currency || ''
+  const money = (v) => `${(v || 0).toFixed(4)} ${currency}`
+  const columns = [
+    { title: '分组', dataIndex: 'key', render: (v) => v || '（空）' },
+    { title: '请求', dataIndex: 'requests', align: 'right' },
+    { title: '失败', dataIndex: 'errors', align: 'right' },
+    { title: '输入 token', dataIndex: 'promptTokens', align: 'right' },
+    { title: '输出 token', dataIndex: 'completionTokens', align: 'right' },
+    { title: '平均耗时', dataIndex: 'avgLatencyMs', align: 'right', render: (v) => `${v} ms` },
+    { title: '费用', dataIndex: 'cost', align: 'right', render: (v, row) => money(v) + (row.estimated ? ' *' : '') },
+  ]
+
+  return (
+    <div style={{ marginTop: 24, paddingTop: 24, borderTop: '1px solid #f0f0f0' }}>
+      <Typography.Text strong style={{ display: 'block', marginBottom: 12 }}>用量与费用</Typography.Text>
+      <Space style={{ marginBottom: 12 }} wrap>
+        <DatePicker.RangePicker value={range} onChange={setRange} allowEmpty={[true, true]} />
+        <Select value={groupBy} onChange={setGroupBy} options={GROUP_OPTIONS} style={{ width: 120 }} />
+        <Button icon={<ReloadOutlined />} onClick={loadReport} />
+      </Space>
+      {report && report.monthlyBudget > 0 && (
+        <div style={{ marginBottom: 12 }}>
+          <Typography.Text type="secondary">本月费用 {money(report.monthSpent)} / 预算 {money(report.monthlyBudget)}</Typography.Text>
+          <Progress
+            percent={Math.min(100, Math.round(report.monthSpent * 100 / report.monthlyBudget))}
+            status={report.monthSpent >= report.monthlyBudget ? 'exception' : 'normal'}
+          />
+        </div>
+      )}
+      <Table
+        size="small"
+        rowKey="key"
+        columns={columns}
+        dataSource={report?.groups || []}
+        pagination={false}
+        summary={() => report?.total?.requests > 0 && (
+          <Table.Summary.Row>
+            {columns.map((c, i) => (
+              <Table.Summary.Cell key={i} index={i} align={c.align}>
+                {i === 0 ? '合计' : c.render ? c.render(report.total[c.dataIndex], report.total) : report.total[c.dataIndex]}
+              </Table.Summary.Cell>
+            ))}
+          </Table.Summary.Row>
+        )}
+      />
+      <Typography.Paragraph type="secondary" style={{ fontSize: 12, marginTop: 8 }}>
+        * 表示部分请求的服务商未返回用量，token 数为本地估算。费用按记录时的模型价格计算。
+      </Typography.Paragraph>
+
+      <Form form={form} layout="vertical" style={{ marginTop: 16 }}>
+        <Space align="start" wrap>
+          <Form.Item label="货币" name="currency">
+            <Input style={{ width: 100 }} placeholder="USD" />
+          </Form.Item>
+          <Form.Item label="月度预算" name="monthlyBudget" tooltip="0 表示不限制">
+            <InputNumber min={0} step={10} style={{ width: 140 }} />
+          </Form.Item>
+          <Form.Item label="超出预算后" name="budgetAction">
+            <Select
+              style={{ width: 160 }}
+              options={[
+                { value: 'warn', label: '提示但继续请求' },
+                { value: 'block', label: '拒绝新的请求' },
+              ]}
+            />
+          </Form.Item>
+        </Space>
+        <Form.Item label="模型价格（每百万 token）" tooltip="模型名以 * 结尾时按前缀匹配，如 gpt-4o*">
+          <Form.List name="prices">
+            {(fields, { add, remove }) => (
+              <>
+                {fields.map(({ key, name }) => (
+                  <Space key={key} align="baseline">
+                    <Form.Item name={[name, 'model']} rules={[{ required: true, message: '请输入模型' }]}>
+                      <Input placeholder="模型" style={{ width: 200 }} />
+                    </Form.Item>
+                    <Form.Item name={[name, 'input']}>
+                      <InputNumber min={0} placeholder="输入价格" style={{ width: 120 }} />
+                    </Form.Item>
+                    <Form.Item name={[name, 'output']}>
+                      <InputNumber min={0} placeholder="输出价格" style={{ width: 120 }} />
+                    </Form.Item>
+                    <DeleteOutlined onClick={() => remove(name)} />
+                  </Space>
+                ))}
+                <Button type="dashed" icon={<PlusOutlined />} onClick={() => add({ model: '', input: 0, output: 0 })}>
+                  添加模型价格
+                </Button>
+              </>
+            )}
+          </Form.List>
+        </Form.Item>
+        <Button type="primary" icon={<SaveOutlined />} onClick={save} loading={saving}>
+          保存
+        </Button>
+      </Form>
+    </div>
+  )
+}
@@ -1,6 +1,6 @@
 import React, { useEffect, useState } from 'react'
-import { Button, Input, List, Popover, ColorPicker, Typography, Modal, Tree, message, Tooltip } from 'antd'
-import { PlusOutlined, BgColorsOutlined } from '@ant-design/icons'
+import { Button, Input, List, Popover, ColorPicker, Typography, Modal, Tree, message, Tooltip, Select } from 'antd'
+import { PlusOutlined, BgColorsOutlined, RobotOutlined } from '@ant-design/icons'
 import ColorPresetManager from './ColorPresetManager.jsx'
 
 export default function CategorySidebar({ categories, activeCategory, onSelect, onSelectItem, onChanged }) {
@@ -18,6 +18,10 @@ export default function CategorySidebar({ categories, activeCategory, onSelect,
   const [filesByCategory, setFilesByCategory] = useState({})
   const [editingId, setEditingId] = useState(null)
   const [editingName, setEditingName] = useState('')
+  const [promptCat, setPromptCat] = useState(null) // 正在设置系统提示词的目录
+  const [promptTemplates, setPromptTemplates] = useState([])
+  const [promptTemplateId, setPromptTemplateId] = useState(null)
+  const [promptInfo, setPromptInfo] = useState(null)
 
   async function loadPalette() {
     const list = await window.go.backend.App.ListColorPresets()
@@ -55,6 +59,32 @@ export default function CategorySidebar({ categories, activeCategory, onSelect,
 
   useEffect(() => { loadPalette() }, [])
 
+  async function openPromptSetting(cat) {
+    try {
+      const [list, info] = await Promise.all([
+        window.go.backend.App.ListPromptTemplates(),
+        window.go.backend.App.GetCategoryPrompt(cat.id),
+      ])
+      setPromptTemplates(list || [])
+      setPromptInfo(info)
+      setPromptTemplateId(cat.promptTemplateId || null)
+      setPromptCat(cat)
+    } catch (e) {
+      message.error('加载提示词模板失败: ' + (e.message || e))
+    }
+  }
+
+  async function savePromptSetting() {
+    try {
+      await window.go.backend.App.SetCategoryPromptTemplate(promptCat.id, promptTemplateId ?? null)
+      message.success('目录提示词已保存')
+      setPromptCat(null)
+      onChanged()
+    } catch (e) {
+      message.error('保存目录提示词失败: ' + (e.message || e))
+    }
+  }
+
   function truncate(s) {
     if (!s) return ''
     const t = s.trim()
@@ -124,6 +154,9 @@ export default function CategorySidebar({ categories, activeCategory, onSelect,
                 {c.name}
               </div>
             )}
+            <Tooltip title="AI 系统提示词">
+              <Button type="text" icon={<RobotOutlined style={{ opacity: c.promptTemplateId ? 1 : 0.35 }} />} onClick={(e) => { e.stopPropagation(); openPromptSetting(c) }} />
+            </Tooltip>
             <Button type="text" icon={<PlusOutlined />} onClick={(e) => { e.stopPropagation(); setAddingParentId(c.id); setCreateChildModalOpen(true) }} />
           </div>
         ),
@@ -313,6 +346,28 @@ export default function CategorySidebar({ categories, activeCategory, onSelect,
           </div>
         </div>
       </Modal>
+      <Modal
+        open={!!promptCat}
+        onCancel={() => setPromptCat(null)}
+        onOk={savePromptSetting}
+        title={`AI 系统提示词 - ${promptCat?.name || ''}`}
+      >
+        <div style={{ display: 'grid', gap: 12, paddingTop: 16 }}>
+          <Select
+            value={promptTemplateId}
+            onChange={setPromptTemplateId}
+            allowClear
+            placeholder="继承上级目录"
+            options={promptTemplates.map(t => ({ value: t.id, label: t.name }))}
+          />
+          <Typography.Text type="secondary" style={{ fontSize: 12 }}>
+            {promptInfo?.inherited
+              ? `当前继承上级目录「${categories?.find(c => c.id === promptInfo.fromCategory)?.name || ''}」的模板「${promptInfo.templateName}」。`
+              : '未设置时继承上级目录的模板，都没有设置时使用默认提示词。'}
+            在此目录及其子目录中对话时，模板作为系统提示词。模板可在 AI 配置中管理。
+          </Typography.Text>
+        </div>
+      </Modal>
       <Modal open={managerOpen} onCancel={() => setManagerOpen(false)} footer={null} title="颜色预设管理">
         <ColorPresetManager onChanged={() => { loadPalette(); onChanged() }} />
       </Modal>
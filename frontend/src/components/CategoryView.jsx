@@ -1,6 +1,6 @@
 import React, { useEffect, useState, useMemo, useRef } from 'react'
 import { Button, Card, List, Typography, Empty, Input, Alert, message, Modal, Select } from 'antd'
-import { FileTextOutlined, SearchOutlined, AppstoreOutlined, UnorderedListOutlined, FilePdfOutlined, FolderOutlined, EditOutlined, DeleteOutlined, CodeOutlined, PlayCircleOutlined, RobotOutlined } from '@ant-design/icons'
+import { FileTextOutlined, SearchOutlined, AppstoreOutlined, UnorderedListOutlined, FilePdfOutlined, FolderOutlined, EditOutlined, DeleteOutlined, CodeOutlined, PlayCircleOutlined, RobotOutlined, ExperimentOutlined } from '@ant-design/icons'
 import dayjs from 'dayjs'
 import extractFirstImageUrl from '../lib/extractFirstImageurl'
 import { renderMarkdown } from '../lib/markdown'
@@ -324,6 +324,20 @@ export default function CategoryView({ activeCategory, onNavigate, reloadToken,
         >
           AI 助手
         </Button>
+        <Button
+          icon={<ExperimentOutlined />}
+          onClick={() => {
+            onNavigate('quiz', {
+              type: 'quiz',
+              categoryId: activeCategory
+            })
+          }}
+          size="middle"
+          disabled={!activeCategory}
+          title={activeCategory ? '根据目录中的笔记生成测验' : '请先选择目录'}
+        >
+          测验
+        </Button>
         <Button
           icon={<CodeOutlined />}
           onClick={() => {
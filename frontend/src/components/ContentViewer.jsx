@@ -1,18 +1,20 @@
 import React, { useEffect, useState, useMemo, useRef, useCallback } from 'react'
-import { Button, Typography, Image, Select, Modal, message, Card, Alert, Collapse, Spin } from 'antd'
+import { Button, Typography, Image, Select, Modal, message, Card, Alert, Collapse, Spin, List, Tag } from 'antd'
 import { EditOutlined, ColumnWidthOutlined, CloseOutlined, UnorderedListOutlined, FolderOutlined, PlayCircleOutlined, CodeOutlined } from '@ant-design/icons'
 import { renderMarkdown } from '../lib/markdown'
 import { extractHeadings } from '../lib/extractHeadings'
 import { processMarkdownHtml } from '../lib/imageUtils'
+import { executeScriptWithTrust, scriptErrorMessage } from '../lib/scriptPolicy'
 import PDFViewer from './PDFViewer'
 import TOCViewer from './TOCViewer'
+import AIActionMenu from './AIActionMenu'
 import ErrorBoundary from './ErrorBoundary'
 import 'highlight.js/styles/github.css'
 import hljs from 'highlight.js'
 
 const { Panel } = Collapse
 
-export default function ContentViewer({ item, onEdit, onSplitPane, onClosePane, canClose = true, onOpenTOC, categories = [], isDarkMode = false }) {
+export default function ContentViewer({ item, onEdit, onSplitPane, onClosePane, canClose = true, onOpenTOC, onOpenNote, categories = [], isDarkMode = false }) {
   const [data, setData] = useState(null)
   const [pdfPath, setPdfPath] = useState(null)
   const contentRef = useRef(null)
@@ -25,6 +27,7 @@ export default function ContentViewer({ item, onEdit, onSplitPane, onClosePane,
   const [tocVisible, setTocVisible] = useState(true) // 目录是否可见
   const [tocWidth, setTocWidth] = useState(250) // 目录宽度（像素）
   const [isResizingTOC, setIsResizingTOC] = useState(false) // 是否正在调整目录宽度
+  const [backlinks, setBacklinks] = useState([]) // 链接到当前笔记的其他笔记
 
   async function load() {
     if (!item) return
@@ -45,6 +48,37 @@ export default function ContentViewer({ item, onEdit, onSplitPane, onClosePane,
 
   useEffect(() => { load() }, [item])
 
+  // 加载反向链接
+  useEffect(() => {
+    if (!data?.id || item?.type !== 'note') {
+      setBacklinks([])
+      return
+    }
+    window.go.backend.App.GetNoteBacklinks(data.id)
+      .then(list => setBacklinks(list || []))
+      .catch(e => console.error('加载反向链接失败:', e))
+  }, [data?.id, item?.type])
+
+  // 打开笔记链接指向的笔记
+  const openNote = async (noteId) => {
+    if (!onOpenNote) return
+    const list = await window.go.backend.App.ListNotes(null)
+    const found = (list || []).find(n => n.id === noteId)
+    if (!found) {
+      message.warning('笔记不存在或已删除')
+      return
+    }
+    onOpenNote(found)
+  }
+
+  // 拦截正文中 note://<id> 链接的点击
+  const handleContentClick = (e) => {
+    const link = e.target.closest?.('a[href^="#note-"]')
+    if (!link) return
+    e.preventDefault()
+    openNote(Number(link.getAttribute('href').slice('#note-'.length)))
+  }
+
   // 当 data 加载后，设置当前目录 ID
   useEffect(() => {
     if (data) {
@@ -61,7 +95,7 @@ export default function ContentViewer({ item, onEdit, onSplitPane, onClosePane,
         setExecResult(null)
         
         try {
-          const result = await window.go.backend.App.ExecuteScript(data.id)
+          const result = await executeScriptWithTrust(data.id)
           if (result) {
             setExecResult({
               stdout: result.stdout || '',
@@ -83,7 +117,7 @@ export default function ContentViewer({ item, onEdit, onSplitPane, onClosePane,
           }
         } catch (e) {
           console.error('执行脚本失败:', e)
-          const errorMsg = e?.message || '未知错误'
+          const errorMsg = scriptErrorMessage(e)
           setExecResult({ 
             stdout: '', 
             stderr: errorMsg, 
@@ -209,7 +243,7 @@ export default function ContentViewer({ item, onEdit, onSplitPane, onClosePane,
     setExecResult(null)
     
     try {
-      const result = await window.go.backend.App.ExecuteScript(data.id)
+      const result = await executeScriptWithTrust(data.id)
       // ExecuteScript 返回 ScriptResult 对象
       if (result) {
         setExecResult({
@@ -232,7 +266,7 @@ export default function ContentViewer({ item, onEdit, onSplitPane, onClosePane,
       }
     } catch (e) {
       console.error('执行脚本失败:', e)
-      const errorMsg = e?.message || '未知错误'
+      const errorMsg = scriptErrorMessage(e)
       setExecResult({ 
         stdout: '', 
         stderr: errorMsg, 
@@ -691,6 +725,9 @@ export default function ContentViewer({ item, onEdit, onSplitPane, onClosePane,
       <ErrorBoundary>
         <div className="pane-viewer">
         <div className="pane-actions-inline">
+          {data.id && (
+            <AIActionMenu note={data} onApplied={load} />
+          )}
           {headings.length > 0 && (
             <Button
               type="text"
@@ -804,8 +841,30 @@ export default function ContentViewer({ item, onEdit, onSplitPane, onClosePane,
                 <div 
                   ref={contentRef}
                   className="viewer-content" 
+                  onClick={handleContentClick}
                   dangerouslySetInnerHTML={{ __html: processedHtml || html }} 
                 />
+                {backlinks.length > 0 && (
+                  <Card size="small" title={`反向链接（${backlinks.length}）`}>
+                    <List
+                      size="small"
+                      dataSource={backlinks}
+                      renderItem={(link) => (
+                        <List.Item style={{ display: 'block' }}>
+                          <div>
+                            {link.fromAi && <Tag color="purple">AI 回答</Tag>}
+                            <a onClick={() => openNote(link.noteId)}>{link.title}</a>
+                          </div>
+                          {link.excerpt && (
+                            <Typography.Text type="secondary" style={{ fontSize: 12 }}>
+                              {link.excerpt}
+                            </Typography.Text>
+                          )}
+                        </List.Item>
+                      )}
+                    />
+                  </Card>
+                )}
               </div>
             </div>
           </div>
@@ -0,0 +1,106 @@
+import React, { useEffect, useState } from 'react'
+import { Button, Form, Input, List, Modal, Popconfirm, Typography, message } from 'antd'
+import { PlusOutlined } from '@ant-design/icons'
+
+// 提示词模板库：模板可以设为目录的默认系统提示词，也可以在 AI 对话中插入
+export default function PromptTemplateSettings() {
+  const [form] = Form.useForm()
+  const [templates, setTemplates] = useState([])
+  const [editing, setEditing] = useState(null) // {} 表示新建
+
+  async function load() {
+    try {
+      setTemplates(await window.go.backend.App.ListPromptTemplates() || [])
+    } catch (e) {
+      message.error('加载提示词模板失败: ' + (e.message || '未知错误'))
+    }
+  }
+
+  useEffect(() => { load() }, [])
+
+  function openEditor(tpl) {
+    setEditing(tpl || {})
+    form.setFieldsValue({
+      name: tpl?.name || '',
+      description: tpl?.description || '',
+      content: tpl?.content || '',
+    })
+  }
+
+  async function save() {
+    try {
+      const values = await form.validateFields()
+      if (editing.id) {
+        await window.go.backend.App.UpdatePromptTemplate(editing.id, values.name, values.description || '', values.content)
+      } else {
+        await window.go.backend.App.CreatePromptTemplate(values.name, values.description || '', values.content)
+      }
+      message.success('提示词模板已保存')
+      setEditing(null)
+      load()
+    } catch (e) {
+      if (e.errorFields) return
+      message.error('保存提示词模板失败: ' + (e.message || e))
+    }
+  }
+
+  async function remove(tpl) {
+    try {
+      await window.go.backend.App.DeletePromptTemplate(tpl.id)
+      load()
+    } catch (e) {
+      message.error('删除提示词模板失败: ' + (e.message || e))
+    }
+  }
+
+  return (
+    <div style={{ marginTop: 24, paddingTop: 24, borderTop: '1px solid #f0f0f0' }}>
+      <div style={{ display: 'flex', justifyContent: 'space-between', marginBottom: 12 }}>
+        <Typography.Text strong>提示词模板</Typography.Text>
+        <Button size="small" icon={<PlusOutlined />} onClick={() => openEditor(null)}>新建模板</Button>
+      </div>
+      <List
+        size="small"
+        bordered
+        dataSource={templates}
+        locale={{ emptyText: '暂无模板，可在目录上设置模板作为默认系统提示词' }}
+        renderItem={(tpl) => (
+          <List.Item
+            actions={[
+              <a key="edit" onClick={() => openEditor(tpl)}>编辑</a>,
+              <Popconfirm key="delete" title="删除后使用该模板的目录将继承上级目录的提示词" onConfirm={() => remove(tpl)}>
+                <a>删除</a>
+              </Popconfirm>,
+            ]}
+          >
+            <List.Item.Meta title={tpl.name} description={tpl.description} />
+          </List.Item>
+        )}
+      />
+      <Modal
+        title={editing?.id ? `编辑模板：${editing.name}` : '新建模板'}
+        open={!!editing}
+        width={640}
+        onCancel={() => setEditing(null)}
+        onOk={save}
+      >
+        <Form form={form} layout="vertical">
+          <Form.Item label="名称" name="name" rules={[{ required: true, message: '请输入模板名称' }]}>
+            <Input placeholder="如 Blender 专家" />
+          </Form.Item>
+          <Form.Item label="说明" name="description">
+            <Input />
+          </Form.Item>
+          <Form.Item
+            label="内容"
+            name="content"
+            rules={[{ required: true, message: '请输入模板内容' }]}
+            extra={<>可用变量：<code>{'{{selection}}'}</code> 选中的内容，<code>{'{{note_title}}'}</code> 笔记标题，<code>{'{{category}}'}</code> 目录名称</>}
+          >
+            <Input.TextArea rows={8} placeholder="你是一名资深的 Blender 技术美术，熟悉 Python 脚本和几何节点……" />
+          </Form.Item>
+        </Form>
+      </Modal>
+    </div>
+  )
+}
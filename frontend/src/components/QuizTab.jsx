@@ -0,0 +1,288 @@
+import React, { useEffect, useState } from 'react'
+import { Alert, Button, Empty, Input, InputNumber, List, Popconfirm, Radio, Select, Space, Spin, Table, Tag, Typography, message, theme } from 'antd'
+import { ColumnWidthOutlined, CloseOutlined, ExperimentOutlined, ArrowLeftOutlined, CheckCircleOutlined, CloseCircleOutlined } from '@ant-design/icons'
+import dayjs from 'dayjs'
+
+const STYLE_OPTIONS = [
+  { value: 'mixed', label: '单选 + 简答' },
+  { value: 'choice', label: '单选题' },
+  { value: 'short', label: '简答题' },
+]
+
+// 测验：由目录或笔记生成题目，作答后记录得分，并统计经常答错的笔记
+export default function QuizTab({
+  activeCategory,
+  categories = [],
+  onSplitPane,
+  onClosePane,
+  canClose = true,
+  ensureUnlocked
+}) {
+  const { token } = theme.useToken()
+  const [notes, setNotes] = useState([])
+  const [providers, setProviders] = useState([])
+  const [quizzes, setQuizzes] = useState([])
+  const [stats, setStats] = useState([])
+  const [noteId, setNoteId] = useState(0) // 0 表示按当前目录出题
+  const [count, setCount] = useState(5)
+  const [style, setStyle] = useState('mixed')
+  const [provider, setProvider] = useState('')
+  const [generating, setGenerating] = useState(false)
+  const [quiz, setQuiz] = useState(null) // 正在作答的测验
+  const [responses, setResponses] = useState({}) // { questionId: { choice, text } }
+  const [submitting, setSubmitting] = useState(false)
+  const [result, setResult] = useState(null) // 提交后的作答记录
+
+  async function loadLists() {
+    try {
+      const [list, noteStats] = await Promise.all([
+        window.go.backend.App.ListQuizzes(),
+        window.go.backend.App.GetQuizNoteStats(activeCategory || 0),
+      ])
+      setQuizzes(list || [])
+      setStats((noteStats || []).filter(s => s.wrong > 0))
+    } catch (e) {
+      message.error('加载测验失败: ' + (e.message || '未知错误'))
+    }
+  }
+
+  useEffect(() => {
+    loadLists()
+    setNoteId(0)
+    if (activeCategory) {
+      window.go.backend.App.ListNotes(activeCategory).then(list => setNotes(list || [])).catch(() => setNotes([]))
+    } else {
+      setNotes([])
+    }
+  }, [activeCategory])
+
+  useEffect(() => {
+    window.go.backend.App.ListAIProviders().then(list => setProviders(list.providers || [])).catch(() => {})
+  }, [])
+
+  const categoryName = categories.find(c => c.id === activeCategory)?.name
+
+  async function generate() {
+    if (!noteId && !activeCategory) {
+      message.warning('请先选择目录')
+      return
+    }
+    if (ensureUnlocked && !(await ensureUnlocked(activeCategory, '目录'))) return
+    setGenerating(true)
+    try {
+      const created = await window.go.backend.App.GenerateQuiz(activeCategory || 0, noteId, count || 0, style, provider)
+      message.success(`已生成 ${created.questions.length} 道题`)
+      openQuiz(created)
+      loadLists()
+    } catch (e) {
+      message.error('生成测验失败: ' + (e.message || e))
+    } finally {
+      setGenerating(false)
+    }
+  }
+
+  function openQuiz(q) {
+    setQuiz(q)
+    setResponses({})
+    setResult(null)
+  }
+
+  async function openQuizById(id) {
+    try {
+      openQuiz(await window.go.backend.App.GetQuiz(id))
+    } catch (e) {
+      message.error('加载测验失败: ' + (e.message || e))
+    }
+  }
+
+  async function removeQuiz(id) {
+    try {
+      await window.go.backend.App.DeleteQuiz(id)
+      loadLists()
+    } catch (e) {
+      message.error('删除测验失败: ' + (e.message || e))
+    }
+  }
+
+  function setResponse(questionId, patch) {
+    setResponses(prev => ({ ...prev, [questionId]: { choice: -1, text: '', ...prev[questionId], ...patch } }))
+  }
+
+  async function submit() {
+    setSubmitting(true)
+    try {
+      const list = quiz.questions.map(q => ({
+        questionId: q.id,
+        choice: responses[q.id]?.choice ?? -1,
+        text: responses[q.id]?.text || '',
+      }))
+      setResult(await window.go.backend.App.SubmitQuizAttempt(quiz.id, list))
+      loadLists()
+    } catch (e) {
+      message.error('提交失败: ' + (e.message || e))
+    } finally {
+      setSubmitting(false)
+    }
+  }
+
+  const noteTitle = (id) => notes.find(n => n.id === id)?.title || `笔记 #${id}`
+
+  function renderQuestion(q, idx) {
+    const answer = result?.answers?.find(a => a.questionId === q.id)
+    return (
+      <List.Item key={q.id} style={{ display: 'block' }}>
+        <Typography.Paragraph strong style={{ marginBottom: 8 }}>
+          {idx + 1}. {q.question}
+          <Tag style={{ marginLeft: 8 }}>{q.type === 'choice' ? '单选' : '简答'}</Tag>
+        </Typography.Paragraph>
+        {q.type === 'choice' ? (
+          <Radio.Group
+            value={responses[q.id]?.choice ?? -1}
+            onChange={e => setResponse(q.id, { choice: e.target.value })}
+            disabled={!!result}
+          >
+            <Space direction="vertical">
+              {q.options.map((opt, i) => (
+                <Radio key={i} value={i}>
+                  <span style={result && i === q.correct ? { color: token.colorSuccess } : undefined}>
+                    {String.fromCharCode(65 + i)}. {opt}
+                  </span>
+                </Radio>
+              ))}
+            </Space>
+          </Radio.Group>
+        ) : (
+          <Input.TextArea
+            autoSize={{ minRows: 2, maxRows: 6 }}
+            value={responses[q.id]?.text || ''}
+            onChange={e => setResponse(q.id, { text: e.target.value })}
+            disabled={!!result}
+          />
+        )}
+        {answer && (
+          <Alert
+            style={{ marginTop: 8 }}
+            type={answer.isCorrect ? 'success' : 'error'}
+            icon={answer.isCorrect ? <CheckCircleOutlined /> : <CloseCircleOutlined />}
+            showIcon
+            message={`${answer.isCorrect ? '正确' : '错误'}（得分 ${Math.round(answer.score * 100)}%）${q.type === 'short' ? '，参考答案：' + q.answer : ''}`}
+            description={
+              <div style={{ fontSize: 12 }}>
+                {answer.feedback && <div>批改：{answer.feedback}</div>}
+                {q.explanation && <div>解析：{q.explanation}</div>}
+                <div>出处：{noteTitle(q.noteId)}</div>
+              </div>
+            }
+          />
+        )}
+      </List.Item>
+    )
+  }
+
+  return (
+    <div style={{ display: 'flex', flexDirection: 'column', height: '100%', minHeight: '60vh' }}>
+      <div className="pane-actions-inline">
+        <Button type="text" size="small" icon={<ColumnWidthOutlined />} onClick={onSplitPane} title="分屏" />
+        <Button type="text" size="small" icon={<CloseOutlined />} onClick={onClosePane} disabled={!canClose} title="关闭面板" />
+      </div>
+
+      <div style={{ flex: 1, overflowY: 'auto', padding: 16 }}>
+        {quiz ? (
+          <>
+            <Space style={{ marginBottom: 12 }}>
+              <Button icon={<ArrowLeftOutlined />} onClick={() => setQuiz(null)}>返回</Button>
+              <Typography.Text strong>{quiz.title}</Typography.Text>
+              {result && <Tag color={result.score >= 60 ? 'success' : 'error'}>得分 {result.score}（{result.correct}/{result.total}）</Tag>}
+            </Space>
+            <List dataSource={quiz.questions} renderItem={renderQuestion} />
+            <Space style={{ marginTop: 12 }}>
+              {result ? (
+                <Button type="primary" onClick={() => openQuiz(quiz)}>重新作答</Button>
+              ) : (
+                <Button type="primary" onClick={submit} loading={submitting}>提交</Button>
+              )}
+            </Space>
+          </>
+        ) : (
+          <>
+            <Typography.Text strong style={{ display: 'block', marginBottom: 12 }}>
+              <ExperimentOutlined /> 生成测验{categoryName ? `：${categoryName}` : ''}
+            </Typography.Text>
+            <Space wrap style={{ marginBottom: 16 }}>
+              <Select
+                style={{ width: 220 }}
+                value={noteId}
+                onChange={setNoteId}
+                showSearch
+                optionFilterProp="label"
+                options={[
+                  { value: 0, label: '当前目录的全部笔记' },
+                  ...notes.filter(n => n.type !== 1).map(n => ({ value: n.id, label: n.title })),
+                ]}
+              />
+              <InputNumber min={1} max={20} value={count} onChange={setCount} addonAfter="题" style={{ width: 110 }} />
+              <Select style={{ width: 130 }} value={style} onChange={setStyle} options={STYLE_OPTIONS} />
+              <Select
+                style={{ width: 140 }}
+                value={provider}
+                onChange={setProvider}
+                options={[{ value: '', label: '默认服务商' }, ...providers.map(p => ({ value: p.name, label: p.name }))]}
+              />
+              <Button type="primary" onClick={generate} loading={generating} disabled={!activeCategory}>
+                生成
+              </Button>
+            </Space>
+            {generating && <div style={{ textAlign: 'center', padding: 16 }}><Spin tip="正在出题..." /></div>}
+
+            <Typography.Text strong style={{ display: 'block', margin: '8px 0' }}>历史测验</Typography.Text>
+            {quizzes.length === 0 ? (
+              <Empty description="还没有测验" />
+            ) : (
+              <List
+                size="small"
+                bordered
+                dataSource={quizzes}
+                renderItem={(q) => (
+                  <List.Item
+                    actions={[
+                      <a key="take" onClick={() => openQuizById(q.id)}>作答</a>,
+                      <Popconfirm key="delete" title="删除测验及作答记录？" onConfirm={() => removeQuiz(q.id)}>
+                        <a>删除</a>
+                      </Popconfirm>,
+                    ]}
+                  >
+                    <List.Item.Meta
+                      title={q.title}
+                      description={
+                        <span style={{ fontSize: 12 }}>
+                          {q.questionCount} 题 · {dayjs(q.createdAt).format('YYYY-MM-DD HH:mm')}
+                          {q.attemptCount > 0 && ` · 作答 ${q.attemptCount} 次，最近 ${q.lastScore} 分，最高 ${q.bestScore} 分`}
+                        </span>
+                      }
+                    />
+                  </List.Item>
+                )}
+              />
+            )}
+
+            <Typography.Text strong style={{ display: 'block', margin: '16px 0 8px' }}>经常答错的笔记</Typography.Text>
+            <Table
+              size="small"
+              rowKey="noteId"
+              dataSource={stats}
+              pagination={{ pageSize: 10, hideOnSinglePage: true }}
+              locale={{ emptyText: '暂无答错记录' }}
+              columns={[
+                { title: '笔记', dataIndex: 'title' },
+                { title: '答错', dataIndex: 'wrong', width: 70 },
+                { title: '作答', dataIndex: 'answered', width: 70 },
+                { title: '错误率', dataIndex: 'wrongRate', width: 80, render: v => `${Math.round(v * 100)}%` },
+                { title: '最近答错', dataIndex: 'lastWrongAt', width: 150, render: v => v ? dayjs(v).format('YYYY-MM-DD HH:mm') : '' },
+              ]}
+            />
+          </>
+        )}
+      </div>
+    </div>
+  )
+}
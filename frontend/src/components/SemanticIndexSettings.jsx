@@ -0,0 +1,118 @@
+import React, { useEffect, useState } from 'react'
+import { Button, Form, Input, InputNumber, Progress, Select, Space, Switch, Typography, message } from 'antd'
+import { SaveOutlined, SyncOutlined } from '@ant-design/icons'
+
+// 语义检索：笔记按标题切分后通过服务商的向量接口建立索引，保存笔记时增量更新
+export default function SemanticIndexSettings() {
+  const [form] = Form.useForm()
+  const [providers, setProviders] = useState([])
+  const [status, setStatus] = useState(null)
+  const [saving, setSaving] = useState(false)
+  const [progress, setProgress] = useState(null) // { done, total }
+
+  async function load() {
+    try {
+      const [config, list, st] = await Promise.all([
+        window.go.backend.App.GetEmbeddingConfig(),
+        window.go.backend.App.ListAIProviders(),
+        window.go.backend.App.GetSemanticIndexStatus(),
+      ])
+      form.setFieldsValue({
+        enabled: config.enabled,
+        provider: config.provider || '',
+        model: config.model || '',
+        apiURL: config.apiURL || '',
+        topK: config.topK || 5,
+      })
+      setProviders(list.providers || [])
+      setStatus(st)
+    } catch (e) {
+      message.error('加载向量索引配置失败: ' + (e.message || '未知错误'))
+    }
+  }
+
+  useEffect(() => {
+    load()
+    const handler = (p) => setProgress({ done: p.done, total: p.total })
+    window.runtime.EventsOn('semantic-index-progress', handler)
+    return () => window.runtime.EventsOff('semantic-index-progress')
+  }, [])
+
+  async function save() {
+    try {
+      setSaving(true)
+      const values = await form.validateFields()
+      await window.go.backend.App.UpdateEmbeddingConfig({ ...values, topK: Number(values.topK) || 5 })
+      message.success('向量索引配置已保存')
+      load()
+    } catch (e) {
+      if (e.errorFields) return
+      message.error('保存向量索引配置失败: ' + (e.message || e))
+    } finally {
+      setSaving(false)
+    }
+  }
+
+  async function rebuild() {
+    try {
+      setProgress({ done: 0, total: 0 })
+      const failed = await window.go.backend.App.RebuildSemanticIndex()
+      if (failed > 0) {
+        message.warning(`索引重建完成，${failed} 篇笔记失败`)
+      } else {
+        message.success('索引重建完成')
+      }
+      load()
+    } catch (e) {
+      message.error('重建索引失败: ' + (e.message || e))
+    } finally {
+      setProgress(null)
+    }
+  }
+
+  return (
+    <div style={{ marginTop: 24, paddingTop: 24, borderTop: '1px solid #f0f0f0' }}>
+      <div style={{ display: 'flex', justifyContent: 'space-between', marginBottom: 12 }}>
+        <Typography.Text strong>语义检索</Typography.Text>
+        {status && (
+          <Typography.Text type="secondary">
+            已索引 {status.notes} 篇笔记，{status.chunks} 个片段
+          </Typography.Text>
+        )}
+      </div>
+      <Form form={form} layout="vertical">
+        <Form.Item label="启用向量索引" name="enabled" valuePropName="checked" tooltip="开启后保存笔记时在后台更新索引，加密目录中的笔记不会发送给向量接口">
+          <Switch />
+        </Form.Item>
+        <Form.Item label="服务商" name="provider">
+          <Select
+            options={[
+              { value: '', label: '默认服务商' },
+              ...providers.filter(p => p.kind !== 'anthropic').map(p => ({ value: p.name, label: p.name })),
+            ]}
+          />
+        </Form.Item>
+        <Form.Item label="向量模型" name="model" tooltip="如 text-embedding-3-small、nomic-embed-text">
+          <Input />
+        </Form.Item>
+        <Form.Item label="向量接口地址" name="apiURL" tooltip="留空时由服务商的对话接口地址推导">
+          <Input placeholder="https://api.openai.com/v1/embeddings" />
+        </Form.Item>
+        <Form.Item label="检索片段数" name="topK">
+          <InputNumber min={1} max={50} style={{ width: '100%' }} />
+        </Form.Item>
+        <Space>
+          <Button type="primary" icon={<SaveOutlined />} onClick={save} loading={saving}>
+            保存
+          </Button>
+          <Button icon={<SyncOutlined />} onClick={rebuild} loading={!!progress}>
+            重建索引
+          </Button>
+        </Space>
+        {progress && progress.total > 0 && (
+          <Progress percent={Math.round(progress.done * 100 / progress.total)} style={{ marginTop: 12 }} />
+        )}
+      </Form>
+    </div>
+  )
+}
@@ -0,0 +1,48 @@
+/**
+ * 订阅后端流式对话事件（ai-chat-delta / ai-chat-done）
+ * start 返回 requestID 之前收到的事件会先缓存，拿到 ID 后再按顺序回放
+ * @param {() => Promise<string>} start - 发起请求，返回 requestID
+ * @param {object} handlers - { onStart(requestId), onDelta(delta, content) }
+ * @returns {Promise<object>} ai-chat-done 事件数据
+ */
+export function streamChat(start, { onStart, onDelta } = {}) {
+  return new Promise((resolve, reject) => {
+    let requestId = null
+    let content = ''
+    const pending = []
+
+    const cleanup = () => {
+      window.runtime.EventsOff('ai-chat-delta')
+      window.runtime.EventsOff('ai-chat-done')
+    }
+
+    const handle = (name, data) => {
+      if (data.requestId !== requestId) return
+      if (name === 'ai-chat-delta') {
+        content += data.delta
+        onDelta?.(data.delta, content)
+        return
+      }
+      cleanup()
+      resolve(data)
+    }
+
+    window.runtime.EventsOn('ai-chat-delta', (data) => {
+      if (requestId === null) pending.push(['ai-chat-delta', data])
+      else handle('ai-chat-delta', data)
+    })
+    window.runtime.EventsOn('ai-chat-done', (data) => {
+      if (requestId === null) pending.push(['ai-chat-done', data])
+      else handle('ai-chat-done', data)
+    })
+
+    start().then((id) => {
+      requestId = id
+      onStart?.(id)
+      pending.splice(0).forEach(([name, data]) => handle(name, data))
+    }).catch((e) => {
+      cleanup()
+      reject(e)
+    })
+  })
+}
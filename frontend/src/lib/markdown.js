@@ -51,6 +51,11 @@ export function renderMarkdown(md, options = {}) {
     processedMd = processedMd.replace(/<img[^>]*>/gi, '')
   }
   
+  // 附件引用 local://attachments/<id> 由后端 AssetHandler 提供
+  processedMd = processedMd.replace(/local:\/\/attachments\/(\d+)/g, '/local/attachments/$1')
+  // 笔记链接 note://<id> 转换为页内锚点，由查看器拦截点击后打开对应笔记
+  processedMd = processedMd.replace(/note:\/\/(\d+)/g, '#note-$1')
+  
   const html = marked.parse(processedMd)
   const sanitized = DOMPurify.sanitize(html, { USE_PROFILES: { html: true } })
   
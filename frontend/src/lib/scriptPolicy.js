@@ -0,0 +1,67 @@
+import React from 'react'
+import { Modal } from 'antd'
+
+/**
+ * 解析后端返回的脚本策略错误（ScriptPolicyError 以 JSON 作为错误文本）
+ * @param {any} err - 调用后端抛出的错误
+ * @returns {object|null} { code, message, violations }，不是策略错误时返回 null
+ */
+export function parsePolicyError(err) {
+  const text = typeof err === 'string' ? err : err?.message
+  if (!text || text[0] !== '{') return null
+  try {
+    const obj = JSON.parse(text)
+    return obj && obj.code ? obj : null
+  } catch {
+    return null
+  }
+}
+
+function confirmTrust(policyError) {
+  const violations = policyError.violations || []
+  return new Promise((resolve) => {
+    Modal.confirm({
+      title: '确认执行脚本',
+      content: React.createElement('div', null,
+        React.createElement('p', null, '该脚本内容尚未确认（新建、导入或修改后需要重新确认），请检查后再执行。'),
+        violations.length > 0 && React.createElement('p', { style: { color: '#cf1322' } }, '检测到以下危险命令：'),
+        violations.length > 0 && React.createElement('ul', null,
+          violations.map((v, i) => React.createElement('li', { key: i }, `第 ${v.line} 行：${v.message}（${v.snippet}）`))
+        )
+      ),
+      okText: '确认并执行',
+      okButtonProps: { danger: violations.length > 0 },
+      cancelText: '取消',
+      onOk: () => resolve(true),
+      onCancel: () => resolve(false),
+    })
+  })
+}
+
+/**
+ * 执行命令行工具笔记，脚本未确认时弹窗请求确认，确认后重新执行
+ * @param {number} noteId - 笔记 ID
+ * @param {object|null} opts - 运行参数
+ */
+export async function executeScriptWithTrust(noteId, opts = null) {
+  try {
+    return await window.go.backend.App.ExecuteScript(noteId, opts)
+  } catch (e) {
+    const policyError = parsePolicyError(e)
+    if (!policyError || policyError.code !== 'untrusted') throw e
+    if (!(await confirmTrust(policyError))) {
+      throw new Error('已取消执行')
+    }
+    await window.go.backend.App.TrustScript(noteId)
+    return await window.go.backend.App.ExecuteScript(noteId, opts)
+  }
+}
+
+/**
+ * 提取错误文本，策略错误返回其中的 message
+ */
+export function scriptErrorMessage(e) {
+  const policyError = parsePolicyError(e)
+  if (policyError) return policyError.message
+  return (typeof e === 'string' ? e : e?.message) || '未知错误'
+}
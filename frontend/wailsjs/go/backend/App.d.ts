@@ -2,73 +2,250 @@
 // This file is automatically generated. DO NOT EDIT
 import {backend} from '../models';
 import {context} from '../models';
+import {time} from '../models';
 
-export function ChatWithAI(arg1:string,arg2:Array<string>):Promise<string>;
+export function AddAttachment(arg1:number,arg2:string,arg3:string):Promise<backend.Attachment>;
+
+export function AppendScriptRunToNote(arg1:number,arg2:number):Promise<void>;
+
+export function ApplyAIAction(arg1:number,arg2:string,arg3:string,arg4:string):Promise<void>;
+
+export function CancelChat(arg1:string):Promise<void>;
+
+export function CancelScript(arg1:string):Promise<void>;
+
+export function ChatWithAI(arg1:string,arg2:Array<string>,arg3:backend.ChatOptions):Promise<backend.AIChatResult>;
+
+export function CheckScriptPolicy(arg1:number):Promise<backend.ScriptPolicyReport>;
+
+export function CloseTerminal(arg1:string):Promise<void>;
+
+export function ConfirmAIToolCall(arg1:string,arg2:boolean):Promise<void>;
 
 export function CreateCategory(arg1:string,arg2:any,arg3:any):Promise<backend.Category>;
 
 export function CreateColorPreset(arg1:string,arg2:string,arg3:boolean):Promise<backend.ColorPreset>;
 
+export function CreateEnvProfile(arg1:string,arg2:Record<string, string>,arg3:string):Promise<backend.EnvProfile>;
+
 export function CreateNote(arg1:string,arg2:string,arg3:string,arg4:string,arg5:number):Promise<backend.Note>;
 
 export function CreateNoteMD(arg1:string,arg2:string,arg3:string,arg4:number):Promise<backend.Note>;
 
 export function CreateNoteMDWithType(arg1:string,arg2:string,arg3:string,arg4:number,arg5:number):Promise<backend.Note>;
 
+export function CreatePromptTemplate(arg1:string,arg2:string,arg3:string):Promise<backend.PromptTemplate>;
+
+export function CreateScriptSchedule(arg1:number,arg2:string,arg3:number,arg4:string):Promise<backend.ScriptSchedule>;
+
+export function CreateSecret(arg1:string,arg2:string,arg3:string):Promise<backend.Secret>;
+
+export function DeleteAIProvider(arg1:string):Promise<void>;
+
+export function DeleteAttachment(arg1:number):Promise<void>;
+
 export function DeleteCategory(arg1:number):Promise<void>;
 
 export function DeleteColorPreset(arg1:number):Promise<void>;
 
+export function DeleteConversation(arg1:number):Promise<void>;
+
+export function DeleteEnvProfile(arg1:number):Promise<void>;
+
 export function DeleteNote(arg1:number):Promise<void>;
 
-export function ExecuteScript(arg1:number):Promise<backend.ScriptResult>;
+export function DeletePromptTemplate(arg1:number):Promise<void>;
+
+export function DeleteQuiz(arg1:number):Promise<void>;
+
+export function DeleteScriptRun(arg1:number):Promise<void>;
+
+export function DeleteScriptSchedule(arg1:number):Promise<void>;
+
+export function DeleteSecret(arg1:number):Promise<void>;
+
+export function ExecuteCodeBlock(arg1:number,arg2:number):Promise<backend.ScriptResult>;
+
+export function ExecuteScript(arg1:number,arg2:backend.ScriptRunOptions):Promise<backend.ScriptResult>;
+
+export function ExportConversation(arg1:number,arg2:string):Promise<string>;
+
+export function GenerateMissingSnippetAnalyses(arg1:string):Promise<number>;
+
+export function GenerateQuiz(arg1:number,arg2:number,arg3:number,arg4:string,arg5:string):Promise<backend.Quiz>;
+
+export function GenerateSnippetAnalysis(arg1:number,arg2:string):Promise<string>;
+
+export function GetAIClientConfig():Promise<backend.AIClientConfig>;
 
 export function GetAIConfig():Promise<backend.AIConfig>;
 
+export function GetAIUsage(arg1:string,arg2:string,arg3:string):Promise<backend.AIUsageReport>;
+
 export function GetCategoryContent(arg1:number):Promise<string>;
 
+export function GetCategoryPrompt(arg1:number):Promise<backend.CategoryPrompt>;
+
 export function GetConfigFilePath():Promise<string>;
 
+export function GetConfigKeyError():Promise<string>;
+
 export function GetContext():Promise<context.Context>;
 
+export function GetConversationMessages(arg1:number):Promise<Array<backend.ChatMessage>>;
+
+export function GetEPUBChapter(arg1:number,arg2:number):Promise<backend.BookChapter>;
+
+export function GetEmbeddingConfig():Promise<backend.EmbeddingConfig>;
+
 export function GetImageContent(arg1:string):Promise<string>;
 
+export function GetInterpreters():Promise<Record<string, backend.Interpreter>>;
+
 export function GetLogFilePath():Promise<string>;
 
+export function GetNoteBacklinks(arg1:number):Promise<Array<backend.NoteBacklink>>;
+
 export function GetNoteContent(arg1:number):Promise<string>;
 
 export function GetPDFContent(arg1:number):Promise<string>;
 
 export function GetPDFPath(arg1:number):Promise<string>;
 
+export function GetQuiz(arg1:number):Promise<backend.Quiz>;
+
+export function GetQuizNoteStats(arg1:number):Promise<Array<backend.QuizNoteStat>>;
+
+export function GetScriptPolicy():Promise<backend.ScriptPolicy>;
+
+export function GetScriptRun(arg1:number):Promise<backend.ScriptRun>;
+
+export function GetScriptVariables(arg1:number):Promise<Array<backend.ScriptVariable>>;
+
+export function GetSemanticIndexStatus():Promise<backend.SemanticIndexStatus>;
+
+export function GetUsageConfig():Promise<backend.UsageConfig>;
+
+export function ImportEPUB(arg1:string,arg2:string,arg3:number):Promise<backend.Note>;
+
 export function ImportPDF(arg1:string,arg2:string,arg3:number):Promise<backend.Note>;
 
+export function ListAIActionPrompts():Promise<Array<backend.AIActionPrompt>>;
+
+export function ListAIProviders():Promise<backend.AIProviderList>;
+
+export function ListAttachments(arg1:number):Promise<Array<backend.Attachment>>;
+
 export function ListCategories():Promise<Array<backend.Category>>;
 
+export function ListCodeBlocks(arg1:number):Promise<Array<backend.CodeBlock>>;
+
 export function ListColorPresets():Promise<Array<backend.ColorPreset>>;
 
+export function ListConversations():Promise<Array<backend.Conversation>>;
+
+export function ListEPUBChapters(arg1:number):Promise<Array<backend.BookChapter>>;
+
+export function ListEnvProfiles():Promise<Array<backend.EnvProfile>>;
+
 export function ListNotes(arg1:any):Promise<Array<backend.Note>>;
 
+export function ListPromptTemplates():Promise<Array<backend.PromptTemplate>>;
+
+export function ListQuizAttempts(arg1:number):Promise<Array<backend.QuizAttempt>>;
+
+export function ListQuizzes():Promise<Array<backend.QuizSummary>>;
+
+export function ListScriptRuns(arg1:number,arg2:number):Promise<Array<backend.ScriptRun>>;
+
+export function ListScriptSchedules(arg1:number):Promise<Array<backend.ScriptSchedule>>;
+
+export function ListSecrets():Promise<Array<backend.Secret>>;
+
 export function LogFrontend(arg1:string):Promise<void>;
 
 export function MigrateBase64ImagesToLocal():Promise<Record<string, any>>;
 
+export function OpenAttachment(arg1:number):Promise<void>;
+
+export function PreviewCronSchedule(arg1:string,arg2:number):Promise<Array<time.Time>>;
+
 export function ReadLogFile():Promise<string>;
 
+export function RebuildSemanticIndex():Promise<number>;
+
+export function RenameConversation(arg1:number,arg2:string):Promise<void>;
+
+export function RenderPromptTemplate(arg1:number,arg2:number,arg3:number,arg4:string):Promise<string>;
+
 export function RequireBiometric(arg1:string):Promise<void>;
 
+export function ResizeTerminal(arg1:string,arg2:number,arg3:number):Promise<void>;
+
+export function RunAIAction(arg1:number,arg2:string,arg3:string,arg4:string,arg5:string):Promise<backend.AIActionResult>;
+
+export function SaveAIProvider(arg1:backend.AIProvider):Promise<void>;
+
+export function SaveChatAnswerAsNote(arg1:number,arg2:number,arg3:number):Promise<backend.Note>;
+
 export function SaveImage(arg1:string):Promise<string>;
 
+export function SearchNotes(arg1:string):Promise<Array<backend.Note>>;
+
+export function SendMessage(arg1:number,arg2:string,arg3:Array<backend.ContextRef>,arg4:backend.ChatOptions):Promise<backend.SendMessageResult>;
+
+export function SetCategoryPromptTemplate(arg1:number,arg2:any):Promise<void>;
+
+export function SetDefaultAIProvider(arg1:string):Promise<void>;
+
 export function SetTheme(arg1:boolean):Promise<void>;
 
+export function StartChatStream(arg1:string,arg2:Array<string>,arg3:string):Promise<string>;
+
+export function StartScript(arg1:number,arg2:backend.ScriptRunOptions):Promise<string>;
+
+export function StartTerminal(arg1:number,arg2:backend.ScriptRunOptions,arg3:number,arg4:number):Promise<string>;
+
+export function SubmitQuizAttempt(arg1:number,arg2:Array<backend.QuizResponse>):Promise<backend.QuizAttempt>;
+
+export function TrustScript(arg1:number):Promise<void>;
+
+export function UntrustScript(arg1:number):Promise<void>;
+
+export function UpdateAIActionPrompt(arg1:string,arg2:string):Promise<void>;
+
+export function UpdateAIClientConfig(arg1:backend.AIClientConfig):Promise<void>;
+
 export function UpdateAIConfig(arg1:backend.AIConfig):Promise<void>;
 
 export function UpdateCategory(arg1:number,arg2:string,arg3:any,arg4:any):Promise<void>;
 
 export function UpdateColorPreset(arg1:number,arg2:string,arg3:string,arg4:boolean):Promise<void>;
 
+export function UpdateEPUBChapter(arg1:number,arg2:number):Promise<void>;
+
+export function UpdateEmbeddingConfig(arg1:backend.EmbeddingConfig):Promise<void>;
+
+export function UpdateEnvProfile(arg1:number,arg2:string,arg3:Record<string, string>,arg4:string):Promise<void>;
+
+export function UpdateInterpreters(arg1:Record<string, backend.Interpreter>):Promise<void>;
+
 export function UpdateNote(arg1:number,arg2:string,arg3:string,arg4:string,arg5:string,arg6:number):Promise<void>;
 
 export function UpdateNoteMD(arg1:number,arg2:string,arg3:string,arg4:string,arg5:number):Promise<void>;
 
 export function UpdatePDFPage(arg1:number,arg2:number):Promise<void>;
+
+export function UpdatePromptTemplate(arg1:number,arg2:string,arg3:string,arg4:string):Promise<void>;
+
+export function UpdateScriptPolicy(arg1:backend.ScriptPolicy):Promise<void>;
+
+export function UpdateScriptSchedule(arg1:number,arg2:string,arg3:number,arg4:string,arg5:boolean):Promise<void>;
+
+export function UpdateScriptTimeout(arg1:number,arg2:number):Promise<void>;
+
+export function UpdateSecret(arg1:number,arg2:string,arg3:string,arg4:string):Promise<void>;
+
+export function UpdateUsageConfig(arg1:backend.UsageConfig):Promise<void>;
+
+export function WriteTerminal(arg1:string,arg2:string):Promise<void>;
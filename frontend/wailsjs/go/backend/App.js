@@ -2,8 +2,40 @@
 // Cynhyrchwyd y ffeil hon yn awtomatig. PEIDIWCH Â MODIWL
 // This file is automatically generated. DO NOT EDIT
 
-export function ChatWithAI(arg1, arg2) {
-  return window['go']['backend']['App']['ChatWithAI'](arg1, arg2);
+export function AddAttachment(arg1, arg2, arg3) {
+  return window['go']['backend']['App']['AddAttachment'](arg1, arg2, arg3);
+}
+
+export function AppendScriptRunToNote(arg1, arg2) {
+  return window['go']['backend']['App']['AppendScriptRunToNote'](arg1, arg2);
+}
+
+export function ApplyAIAction(arg1, arg2, arg3, arg4) {
+  return window['go']['backend']['App']['ApplyAIAction'](arg1, arg2, arg3, arg4);
+}
+
+export function CancelChat(arg1) {
+  return window['go']['backend']['App']['CancelChat'](arg1);
+}
+
+export function CancelScript(arg1) {
+  return window['go']['backend']['App']['CancelScript'](arg1);
+}
+
+export function ChatWithAI(arg1, arg2, arg3) {
+  return window['go']['backend']['App']['ChatWithAI'](arg1, arg2, arg3);
+}
+
+export function CheckScriptPolicy(arg1) {
+  return window['go']['backend']['App']['CheckScriptPolicy'](arg1);
+}
+
+export function CloseTerminal(arg1) {
+  return window['go']['backend']['App']['CloseTerminal'](arg1);
+}
+
+export function ConfirmAIToolCall(arg1, arg2) {
+  return window['go']['backend']['App']['ConfirmAIToolCall'](arg1, arg2);
 }
 
 export function CreateCategory(arg1, arg2, arg3) {
@@ -14,6 +46,10 @@ export function CreateColorPreset(arg1, arg2, arg3) {
   return window['go']['backend']['App']['CreateColorPreset'](arg1, arg2, arg3);
 }
 
+export function CreateEnvProfile(arg1, arg2, arg3) {
+  return window['go']['backend']['App']['CreateEnvProfile'](arg1, arg2, arg3);
+}
+
 export function CreateNote(arg1, arg2, arg3, arg4, arg5) {
   return window['go']['backend']['App']['CreateNote'](arg1, arg2, arg3, arg4, arg5);
 }
@@ -26,6 +62,26 @@ export function CreateNoteMDWithType(arg1, arg2, arg3, arg4, arg5) {
   return window['go']['backend']['App']['CreateNoteMDWithType'](arg1, arg2, arg3, arg4, arg5);
 }
 
+export function CreatePromptTemplate(arg1, arg2, arg3) {
+  return window['go']['backend']['App']['CreatePromptTemplate'](arg1, arg2, arg3);
+}
+
+export function CreateScriptSchedule(arg1, arg2, arg3, arg4) {
+  return window['go']['backend']['App']['CreateScriptSchedule'](arg1, arg2, arg3, arg4);
+}
+
+export function CreateSecret(arg1, arg2, arg3) {
+  return window['go']['backend']['App']['CreateSecret'](arg1, arg2, arg3);
+}
+
+export function DeleteAIProvider(arg1) {
+  return window['go']['backend']['App']['DeleteAIProvider'](arg1);
+}
+
+export function DeleteAttachment(arg1) {
+  return window['go']['backend']['App']['DeleteAttachment'](arg1);
+}
+
 export function DeleteCategory(arg1) {
   return window['go']['backend']['App']['DeleteCategory'](arg1);
 }
@@ -34,38 +90,122 @@ export function DeleteColorPreset(arg1) {
   return window['go']['backend']['App']['DeleteColorPreset'](arg1);
 }
 
+export function DeleteConversation(arg1) {
+  return window['go']['backend']['App']['DeleteConversation'](arg1);
+}
+
+export function DeleteEnvProfile(arg1) {
+  return window['go']['backend']['App']['DeleteEnvProfile'](arg1);
+}
+
 export function DeleteNote(arg1) {
   return window['go']['backend']['App']['DeleteNote'](arg1);
 }
 
-export function ExecuteScript(arg1) {
-  return window['go']['backend']['App']['ExecuteScript'](arg1);
+export function DeletePromptTemplate(arg1) {
+  return window['go']['backend']['App']['DeletePromptTemplate'](arg1);
+}
+
+export function DeleteQuiz(arg1) {
+  return window['go']['backend']['App']['DeleteQuiz'](arg1);
+}
+
+export function DeleteScriptRun(arg1) {
+  return window['go']['backend']['App']['DeleteScriptRun'](arg1);
+}
+
+export function DeleteScriptSchedule(arg1) {
+  return window['go']['backend']['App']['DeleteScriptSchedule'](arg1);
+}
+
+export function DeleteSecret(arg1) {
+  return window['go']['backend']['App']['DeleteSecret'](arg1);
+}
+
+export function ExecuteCodeBlock(arg1, arg2) {
+  return window['go']['backend']['App']['ExecuteCodeBlock'](arg1, arg2);
+}
+
+export function ExecuteScript(arg1, arg2) {
+  return window['go']['backend']['App']['ExecuteScript'](arg1, arg2);
+}
+
+export function ExportConversation(arg1, arg2) {
+  return window['go']['backend']['App']['ExportConversation'](arg1, arg2);
+}
+
+export function GenerateMissingSnippetAnalyses(arg1) {
+  return window['go']['backend']['App']['GenerateMissingSnippetAnalyses'](arg1);
+}
+
+export function GenerateQuiz(arg1, arg2, arg3, arg4, arg5) {
+  return window['go']['backend']['App']['GenerateQuiz'](arg1, arg2, arg3, arg4, arg5);
+}
+
+export function GenerateSnippetAnalysis(arg1, arg2) {
+  return window['go']['backend']['App']['GenerateSnippetAnalysis'](arg1, arg2);
+}
+
+export function GetAIClientConfig() {
+  return window['go']['backend']['App']['GetAIClientConfig']();
 }
 
 export function GetAIConfig() {
   return window['go']['backend']['App']['GetAIConfig']();
 }
 
+export function GetAIUsage(arg1, arg2, arg3) {
+  return window['go']['backend']['App']['GetAIUsage'](arg1, arg2, arg3);
+}
+
 export function GetCategoryContent(arg1) {
   return window['go']['backend']['App']['GetCategoryContent'](arg1);
 }
 
+export function GetCategoryPrompt(arg1) {
+  return window['go']['backend']['App']['GetCategoryPrompt'](arg1);
+}
+
 export function GetConfigFilePath() {
   return window['go']['backend']['App']['GetConfigFilePath']();
 }
 
+export function GetConfigKeyError() {
+  return window['go']['backend']['App']['GetConfigKeyError']();
+}
+
 export function GetContext() {
   return window['go']['backend']['App']['GetContext']();
 }
 
+export function GetConversationMessages(arg1) {
+  return window['go']['backend']['App']['GetConversationMessages'](arg1);
+}
+
+export function GetEPUBChapter(arg1, arg2) {
+  return window['go']['backend']['App']['GetEPUBChapter'](arg1, arg2);
+}
+
+export function GetEmbeddingConfig() {
+  return window['go']['backend']['App']['GetEmbeddingConfig']();
+}
+
 export function GetImageContent(arg1) {
   return window['go']['backend']['App']['GetImageContent'](arg1);
 }
 
+export function GetInterpreters() {
+  return window['go']['backend']['App']['GetInterpreters']();
+}
+
 export function GetLogFilePath() {
   return window['go']['backend']['App']['GetLogFilePath']();
 }
 
+export function GetNoteBacklinks(arg1) {
+  return window['go']['backend']['App']['GetNoteBacklinks'](arg1);
+}
+
 export function GetNoteContent(arg1) {
   return window['go']['backend']['App']['GetNoteContent'](arg1);
 }
@@ -78,22 +218,106 @@ export function GetPDFPath(arg1) {
   return window['go']['backend']['App']['GetPDFPath'](arg1);
 }
 
+export function GetQuiz(arg1) {
+  return window['go']['backend']['App']['GetQuiz'](arg1);
+}
+
+export function GetQuizNoteStats(arg1) {
+  return window['go']['backend']['App']['GetQuizNoteStats'](arg1);
+}
+
+export function GetScriptPolicy() {
+  return window['go']['backend']['App']['GetScriptPolicy']();
+}
+
+export function GetScriptRun(arg1) {
+  return window['go']['backend']['App']['GetScriptRun'](arg1);
+}
+
+export function GetScriptVariables(arg1) {
+  return window['go']['backend']['App']['GetScriptVariables'](arg1);
+}
+
+export function GetSemanticIndexStatus() {
+  return window['go']['backend']['App']['GetSemanticIndexStatus']();
+}
+
+export function GetUsageConfig() {
+  return window['go']['backend']['App']['GetUsageConfig']();
+}
+
+export function ImportEPUB(arg1, arg2, arg3) {
+  return window['go']['backend']['App']['ImportEPUB'](arg1, arg2, arg3);
+}
+
 export function ImportPDF(arg1, arg2, arg3) {
   return window['go']['backend']['App']['ImportPDF'](arg1, arg2, arg3);
 }
 
+export function ListAIActionPrompts() {
+  return window['go']['backend']['App']['ListAIActionPrompts']();
+}
+
+export function ListAIProviders() {
+  return window['go']['backend']['App']['ListAIProviders']();
+}
+
+export function ListAttachments(arg1) {
+  return window['go']['backend']['App']['ListAttachments'](arg1);
+}
+
 export function ListCategories() {
   return window['go']['backend']['App']['ListCategories']();
 }
 
+export function ListCodeBlocks(arg1) {
+  return window['go']['backend']['App']['ListCodeBlocks'](arg1);
+}
+
 export function ListColorPresets() {
   return window['go']['backend']['App']['ListColorPresets']();
 }
 
+export function ListConversations() {
+  return window['go']['backend']['App']['ListConversations']();
+}
+
+export function ListEPUBChapters(arg1) {
+  return window['go']['backend']['App']['ListEPUBChapters'](arg1);
+}
+
+export function ListEnvProfiles() {
+  return window['go']['backend']['App']['ListEnvProfiles']();
+}
+
 export function ListNotes(arg1) {
   return window['go']['backend']['App']['ListNotes'](arg1);
 }
 
+export function ListPromptTemplates() {
+  return window['go']['backend']['App']['ListPromptTemplates']();
+}
+
+export function ListQuizAttempts(arg1) {
+  return window['go']['backend']['App']['ListQuizAttempts'](arg1);
+}
+
+export function ListQuizzes() {
+  return window['go']['backend']['App']['ListQuizzes']();
+}
+
+export function ListScriptRuns(arg1, arg2) {
+  return window['go']['backend']['App']['ListScriptRuns'](arg1, arg2);
+}
+
+export function ListScriptSchedules(arg1) {
+  return window['go']['backend']['App']['ListScriptSchedules'](arg1);
+}
+
+export function ListSecrets() {
+  return window['go']['backend']['App']['ListSecrets']();
+}
+
 export function LogFrontend(arg1) {
   return window['go']['backend']['App']['LogFrontend'](arg1);
 }
@@ -102,22 +326,106 @@ export function MigrateBase64ImagesToLocal() {
   return window['go']['backend']['App']['MigrateBase64ImagesToLocal']();
 }
 
+export function OpenAttachment(arg1) {
+  return window['go']['backend']['App']['OpenAttachment'](arg1);
+}
+
+export function PreviewCronSchedule(arg1, arg2) {
+  return window['go']['backend']['App']['PreviewCronSchedule'](arg1, arg2);
+}
+
 export function ReadLogFile() {
   return window['go']['backend']['App']['ReadLogFile']();
 }
 
+export function RebuildSemanticIndex() {
+  return window['go']['backend']['App']['RebuildSemanticIndex']();
+}
+
+export function RenameConversation(arg1, arg2) {
+  return window['go']['backend']['App']['RenameConversation'](arg1, arg2);
+}
+
+export function RenderPromptTemplate(arg1, arg2, arg3, arg4) {
+  return window['go']['backend']['App']['RenderPromptTemplate'](arg1, arg2, arg3, arg4);
+}
+
 export function RequireBiometric(arg1) {
   return window['go']['backend']['App']['RequireBiometric'](arg1);
 }
 
+export function ResizeTerminal(arg1, arg2, arg3) {
+  return window['go']['backend']['App']['ResizeTerminal'](arg1, arg2, arg3);
+}
+
+export function RunAIAction(arg1, arg2, arg3, arg4, arg5) {
+  return window['go']['backend']['App']['RunAIAction'](arg1, arg2, arg3, arg4, arg5);
+}
+
+export function SaveAIProvider(arg1) {
+  return window['go']['backend']['App']['SaveAIProvider'](arg1);
+}
+
+export function SaveChatAnswerAsNote(arg1, arg2, arg3) {
+  return window['go']['backend']['App']['SaveChatAnswerAsNote'](arg1, arg2, arg3);
+}
+
 export function SaveImage(arg1) {
   return window['go']['backend']['App']['SaveImage'](arg1);
 }
 
+export function SearchNotes(arg1) {
+  return window['go']['backend']['App']['SearchNotes'](arg1);
+}
+
+export function SendMessage(arg1, arg2, arg3, arg4) {
+  return window['go']['backend']['App']['SendMessage'](arg1, arg2, arg3, arg4);
+}
+
+export function SetCategoryPromptTemplate(arg1, arg2) {
+  return window['go']['backend']['App']['SetCategoryPromptTemplate'](arg1, arg2);
+}
+
+export function SetDefaultAIProvider(arg1) {
+  return window['go']['backend']['App']['SetDefaultAIProvider'](arg1);
+}
+
 export function SetTheme(arg1) {
   return window['go']['backend']['App']['SetTheme'](arg1);
 }
 
+export function StartChatStream(arg1, arg2, arg3) {
+  return window['go']['backend']['App']['StartChatStream'](arg1, arg2, arg3);
+}
+
+export function StartScript(arg1, arg2) {
+  return window['go']['backend']['App']['StartScript'](arg1, arg2);
+}
+
+export function StartTerminal(arg1, arg2, arg3, arg4) {
+  return window['go']['backend']['App']['StartTerminal'](arg1, arg2, arg3, arg4);
+}
+
+export function SubmitQuizAttempt(arg1, arg2) {
+  return window['go']['backend']['App']['SubmitQuizAttempt'](arg1, arg2);
+}
+
+export function TrustScript(arg1) {
+  return window['go']['backend']['App']['TrustScript'](arg1);
+}
+
+export function UntrustScript(arg1) {
+  return window['go']['backend']['App']['UntrustScript'](arg1);
+}
+
+export function UpdateAIActionPrompt(arg1, arg2) {
+  return window['go']['backend']['App']['UpdateAIActionPrompt'](arg1, arg2);
+}
+
+export function UpdateAIClientConfig(arg1) {
+  return window['go']['backend']['App']['UpdateAIClientConfig'](arg1);
+}
+
 export function UpdateAIConfig(arg1) {
   return window['go']['backend']['App']['UpdateAIConfig'](arg1);
 }
@@ -130,6 +438,22 @@ export function UpdateColorPreset(arg1, arg2, arg3, arg4) {
   return window['go']['backend']['App']['UpdateColorPreset'](arg1, arg2, arg3, arg4);
 }
 
+export function UpdateEPUBChapter(arg1, arg2) {
+  return window['go']['backend']['App']['UpdateEPUBChapter'](arg1, arg2);
+}
+
+export function UpdateEmbeddingConfig(arg1) {
+  return window['go']['backend']['App']['UpdateEmbeddingConfig'](arg1);
+}
+
+export function UpdateEnvProfile(arg1, arg2, arg3, arg4) {
+  return window['go']['backend']['App']['UpdateEnvProfile'](arg1, arg2, arg3, arg4);
+}
+
+export function UpdateInterpreters(arg1) {
+  return window['go']['backend']['App']['UpdateInterpreters'](arg1);
+}
+
 export function UpdateNote(arg1, arg2, arg3, arg4, arg5, arg6) {
   return window['go']['backend']['App']['UpdateNote'](arg1, arg2, arg3, arg4, arg5, arg6);
 }
@@ -141,3 +465,31 @@ export function UpdateNoteMD(arg1, arg2, arg3, arg4, arg5) {
 export function UpdatePDFPage(arg1, arg2) {
   return window['go']['backend']['App']['UpdatePDFPage'](arg1, arg2);
 }
+
+export function UpdatePromptTemplate(arg1, arg2, arg3, arg4) {
+  return window['go']['backend']['App']['UpdatePromptTemplate'](arg1, arg2, arg3, arg4);
+}
+
+export function UpdateScriptPolicy(arg1) {
+  return window['go']['backend']['App']['UpdateScriptPolicy'](arg1);
+}
+
+export function UpdateScriptSchedule(arg1, arg2, arg3, arg4, arg5) {
+  return window['go']['backend']['App']['UpdateScriptSchedule'](arg1, arg2, arg3, arg4, arg5);
+}
+
+export function UpdateScriptTimeout(arg1, arg2) {
+  return window['go']['backend']['App']['UpdateScriptTimeout'](arg1, arg2);
+}
+
+export function UpdateSecret(arg1, arg2, arg3, arg4) {
+  return window['go']['backend']['App']['UpdateSecret'](arg1, arg2, arg3, arg4);
+}
+
+export function UpdateUsageConfig(arg1) {
+  return window['go']['backend']['App']['UpdateUsageConfig'](arg1);
+}
+
+export function WriteTerminal(arg1, arg2) {
+  return window['go']['backend']['App']['WriteTerminal'](arg1, arg2);
+}
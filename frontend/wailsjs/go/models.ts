@@ -1,9 +1,216 @@
 export namespace backend {
 	
+	export class AIActionPrompt {
+	    action: string;
+	    name: string;
+	    template: string;
+	    isDefault: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new AIActionPrompt(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.action = source["action"];
+	        this.name = source["name"];
+	        this.template = source["template"];
+	        this.isDefault = source["isDefault"];
+	    }
+	}
+	export class AIActionResult {
+	    action: string;
+	    noteId: number;
+	    title: string;
+	    contentMd: string;
+	    output: string;
+	    diff: string;
+	    baseHash: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new AIActionResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.action = source["action"];
+	        this.noteId = source["noteId"];
+	        this.title = source["title"];
+	        this.contentMd = source["contentMd"];
+	        this.output = source["output"];
+	        this.diff = source["diff"];
+	        this.baseHash = source["baseHash"];
+	    }
+	}
+	export class ToolCallRecord {
+	    name: string;
+	    arguments: string;
+	    result?: string;
+	    error?: string;
+	    denied?: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new ToolCallRecord(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.arguments = source["arguments"];
+	        this.result = source["result"];
+	        this.error = source["error"];
+	        this.denied = source["denied"];
+	    }
+	}
+	export class Citation {
+	    noteId: number;
+	    title: string;
+	    heading?: string;
+	    score: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new Citation(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.noteId = source["noteId"];
+	        this.title = source["title"];
+	        this.heading = source["heading"];
+	        this.score = source["score"];
+	    }
+	}
+	export class ContextEntry {
+	    noteId?: number;
+	    title: string;
+	    heading?: string;
+	    tokens: number;
+	    truncated?: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new ContextEntry(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.noteId = source["noteId"];
+	        this.title = source["title"];
+	        this.heading = source["heading"];
+	        this.tokens = source["tokens"];
+	        this.truncated = source["truncated"];
+	    }
+	}
+	export class ContextReport {
+	    strategy: string;
+	    budget: number;
+	    used: number;
+	    included: ContextEntry[];
+	    omitted: ContextEntry[];
+	    images?: string[];
+	    omittedImages?: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new ContextReport(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.strategy = source["strategy"];
+	        this.budget = source["budget"];
+	        this.used = source["used"];
+	        this.included = this.convertValues(source["included"], ContextEntry);
+	        this.omitted = this.convertValues(source["omitted"], ContextEntry);
+	        this.images = source["images"];
+	        this.omittedImages = source["omittedImages"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class AIChatResult {
+	    content: string;
+	    context?: ContextReport;
+	    citations: Citation[];
+	    toolCalls: ToolCallRecord[];
+	    budgetWarning?: string;
+	    promptTemplate?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new AIChatResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.content = source["content"];
+	        this.context = this.convertValues(source["context"], ContextReport);
+	        this.citations = this.convertValues(source["citations"], Citation);
+	        this.toolCalls = this.convertValues(source["toolCalls"], ToolCallRecord);
+	        this.budgetWarning = source["budgetWarning"];
+	        this.promptTemplate = source["promptTemplate"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class AIClientConfig {
+	    connectTimeout: number;
+	    responseTimeout: number;
+	    proxy: string;
+	    maxRetries: number;
+	    retryBaseDelay: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new AIClientConfig(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.connectTimeout = source["connectTimeout"];
+	        this.responseTimeout = source["responseTimeout"];
+	        this.proxy = source["proxy"];
+	        this.maxRetries = source["maxRetries"];
+	        this.retryBaseDelay = source["retryBaseDelay"];
+	    }
+	}
 	export class AIConfig {
 	    apiKey: string;
 	    apiURL: string;
 	    model: string;
+	    maxTokens?: number;
+	    contextTokens?: number;
+	    contextStrategy?: string;
+	    vision?: string;
+	    privacyMode?: boolean;
 	
 	    static createFrom(source: any = {}) {
 	        return new AIConfig(source);
@@ -14,30 +221,51 @@ export namespace backend {
 	        this.apiKey = source["apiKey"];
 	        this.apiURL = source["apiURL"];
 	        this.model = source["model"];
+	        this.maxTokens = source["maxTokens"];
+	        this.contextTokens = source["contextTokens"];
+	        this.contextStrategy = source["contextStrategy"];
+	        this.vision = source["vision"];
+	        this.privacyMode = source["privacyMode"];
 	    }
 	}
-	export class ColorPreset {
-	    id: number;
+	export class AIProvider {
 	    name: string;
-	    hex: string;
-	    encrypted: boolean;
-	    // Go type: time
-	    createdAt: any;
-	    // Go type: time
-	    updatedAt: any;
+	    kind: string;
+	    apiURL: string;
+	    apiKey: string;
+	    model: string;
+	    maxTokens?: number;
+	    contextTokens?: number;
+	    vision?: string;
 	
 	    static createFrom(source: any = {}) {
-	        return new ColorPreset(source);
+	        return new AIProvider(source);
 	    }
 	
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
-	        this.id = source["id"];
 	        this.name = source["name"];
-	        this.hex = source["hex"];
-	        this.encrypted = source["encrypted"];
-	        this.createdAt = this.convertValues(source["createdAt"], null);
-	        this.updatedAt = this.convertValues(source["updatedAt"], null);
+	        this.kind = source["kind"];
+	        this.apiURL = source["apiURL"];
+	        this.apiKey = source["apiKey"];
+	        this.model = source["model"];
+	        this.maxTokens = source["maxTokens"];
+	        this.contextTokens = source["contextTokens"];
+	        this.vision = source["vision"];
+	    }
+	}
+	export class AIProviderList {
+	    providers: AIProvider[];
+	    default: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new AIProviderList(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.providers = this.convertValues(source["providers"], AIProvider);
+	        this.default = source["default"];
 	    }
 	
 		convertValues(a: any, classs: any, asMap: boolean = false): any {
@@ -58,30 +286,100 @@ export namespace backend {
 		    return a;
 		}
 	}
-	export class Category {
+	export class AIUsageGroup {
+	    key: string;
+	    requests: number;
+	    errors: number;
+	    promptTokens: number;
+	    completionTokens: number;
+	    cost: number;
+	    avgLatencyMs: number;
+	    estimated: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new AIUsageGroup(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.key = source["key"];
+	        this.requests = source["requests"];
+	        this.errors = source["errors"];
+	        this.promptTokens = source["promptTokens"];
+	        this.completionTokens = source["completionTokens"];
+	        this.cost = source["cost"];
+	        this.avgLatencyMs = source["avgLatencyMs"];
+	        this.estimated = source["estimated"];
+	    }
+	}
+	export class AIUsageReport {
+	    from: string;
+	    to: string;
+	    groupBy: string;
+	    currency: string;
+	    groups: AIUsageGroup[];
+	    total: AIUsageGroup;
+	    monthSpent: number;
+	    monthlyBudget: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new AIUsageReport(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.from = source["from"];
+	        this.to = source["to"];
+	        this.groupBy = source["groupBy"];
+	        this.currency = source["currency"];
+	        this.groups = this.convertValues(source["groups"], AIUsageGroup);
+	        this.total = this.convertValues(source["total"], AIUsageGroup);
+	        this.monthSpent = source["monthSpent"];
+	        this.monthlyBudget = source["monthlyBudget"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class Attachment {
 	    id: number;
-	    name: string;
-	    colorPresetId?: number;
-	    colorPreset?: ColorPreset;
-	    parentId?: number;
-	    // Go type: time
-	    createdAt: any;
-	    // Go type: time
-	    updatedAt: any;
+	    noteId: number;
+	    originalName: string;
+	    mimeType: string;
+	    size: number;
+	    hash: string;
+	    storagePath: string;
+	    createdAt: time.Time;
 	
 	    static createFrom(source: any = {}) {
-	        return new Category(source);
+	        return new Attachment(source);
 	    }
 	
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
 	        this.id = source["id"];
-	        this.name = source["name"];
-	        this.colorPresetId = source["colorPresetId"];
-	        this.colorPreset = this.convertValues(source["colorPreset"], ColorPreset);
-	        this.parentId = source["parentId"];
-	        this.createdAt = this.convertValues(source["createdAt"], null);
-	        this.updatedAt = this.convertValues(source["updatedAt"], null);
+	        this.noteId = source["noteId"];
+	        this.originalName = source["originalName"];
+	        this.mimeType = source["mimeType"];
+	        this.size = source["size"];
+	        this.hash = source["hash"];
+	        this.storagePath = source["storagePath"];
+	        this.createdAt = this.convertValues(source["createdAt"], time.Time);
 	    }
 	
 		convertValues(a: any, classs: any, asMap: boolean = false): any {
@@ -102,41 +400,30 @@ export namespace backend {
 		    return a;
 		}
 	}
-	
-	export class Note {
+	export class BookChapter {
 	    id: number;
+	    noteId: number;
+	    seq: number;
 	    title: string;
-	    language: string;
-	    snippet: string;
-	    analysis: string;
+	    href: string;
+	    contentHtml: string;
 	    contentMd: string;
-	    type: number;
-	    filePath: string;
-	    pdfPage: number;
-	    categoryId: number;
-	    // Go type: time
-	    createdAt: any;
-	    // Go type: time
-	    updatedAt: any;
+	    createdAt: time.Time;
 	
 	    static createFrom(source: any = {}) {
-	        return new Note(source);
+	        return new BookChapter(source);
 	    }
 	
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
 	        this.id = source["id"];
+	        this.noteId = source["noteId"];
+	        this.seq = source["seq"];
 	        this.title = source["title"];
-	        this.language = source["language"];
-	        this.snippet = source["snippet"];
-	        this.analysis = source["analysis"];
+	        this.href = source["href"];
+	        this.contentHtml = source["contentHtml"];
 	        this.contentMd = source["contentMd"];
-	        this.type = source["type"];
-	        this.filePath = source["filePath"];
-	        this.pdfPage = source["pdfPage"];
-	        this.categoryId = source["categoryId"];
-	        this.createdAt = this.convertValues(source["createdAt"], null);
-	        this.updatedAt = this.convertValues(source["updatedAt"], null);
+	        this.createdAt = this.convertValues(source["createdAt"], time.Time);
 	    }
 	
 		convertValues(a: any, classs: any, asMap: boolean = false): any {
@@ -157,22 +444,1120 @@ export namespace backend {
 		    return a;
 		}
 	}
-	export class ScriptResult {
-	    stdout: string;
-	    stderr: string;
-	    success: boolean;
-	    error?: string;
+	export class ColorPreset {
+	    id: number;
+	    name: string;
+	    hex: string;
+	    encrypted: boolean;
+	    createdAt: time.Time;
+	    updatedAt: time.Time;
 	
 	    static createFrom(source: any = {}) {
-	        return new ScriptResult(source);
+	        return new ColorPreset(source);
 	    }
 	
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
-	        this.stdout = source["stdout"];
-	        this.stderr = source["stderr"];
-	        this.success = source["success"];
-	        this.error = source["error"];
+	        this.id = source["id"];
+	        this.name = source["name"];
+	        this.hex = source["hex"];
+	        this.encrypted = source["encrypted"];
+	        this.createdAt = this.convertValues(source["createdAt"], time.Time);
+	        this.updatedAt = this.convertValues(source["updatedAt"], time.Time);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class Category {
+	    id: number;
+	    name: string;
+	    colorPresetId?: number;
+	    colorPreset?: ColorPreset;
+	    parentId?: number;
+	    promptTemplateId?: number;
+	    createdAt: time.Time;
+	    updatedAt: time.Time;
+	
+	    static createFrom(source: any = {}) {
+	        return new Category(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.name = source["name"];
+	        this.colorPresetId = source["colorPresetId"];
+	        this.colorPreset = this.convertValues(source["colorPreset"], ColorPreset);
+	        this.parentId = source["parentId"];
+	        this.promptTemplateId = source["promptTemplateId"];
+	        this.createdAt = this.convertValues(source["createdAt"], time.Time);
+	        this.updatedAt = this.convertValues(source["updatedAt"], time.Time);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class CategoryPrompt {
+	    templateId: number;
+	    templateName: string;
+	    fromCategory: number;
+	    inherited: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new CategoryPrompt(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.templateId = source["templateId"];
+	        this.templateName = source["templateName"];
+	        this.fromCategory = source["fromCategory"];
+	        this.inherited = source["inherited"];
+	    }
+	}
+	export class ContextRef {
+	    type: string;
+	    id: number;
+	    name: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ContextRef(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.type = source["type"];
+	        this.id = source["id"];
+	        this.name = source["name"];
+	    }
+	}
+	export class ChatMessage {
+	    id: number;
+	    conversationId: number;
+	    role: string;
+	    content: string;
+	    contextRefs: ContextRef[];
+	    images: string[];
+	    citations: Citation[];
+	    contextNotes: number[];
+	    model: string;
+	    createdAt: time.Time;
+	
+	    static createFrom(source: any = {}) {
+	        return new ChatMessage(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.conversationId = source["conversationId"];
+	        this.role = source["role"];
+	        this.content = source["content"];
+	        this.contextRefs = this.convertValues(source["contextRefs"], ContextRef);
+	        this.images = source["images"];
+	        this.citations = this.convertValues(source["citations"], Citation);
+	        this.contextNotes = source["contextNotes"];
+	        this.model = source["model"];
+	        this.createdAt = this.convertValues(source["createdAt"], time.Time);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class ChatOptions {
+	    provider: string;
+	    retrieval: boolean;
+	    tools: boolean;
+	    categoryId: number;
+	    images: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new ChatOptions(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.provider = source["provider"];
+	        this.retrieval = source["retrieval"];
+	        this.tools = source["tools"];
+	        this.categoryId = source["categoryId"];
+	        this.images = source["images"];
+	    }
+	}
+	
+	export class CodeBlock {
+	    index: number;
+	    language: string;
+	    code: string;
+	    line: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new CodeBlock(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.index = source["index"];
+	        this.language = source["language"];
+	        this.code = source["code"];
+	        this.line = source["line"];
+	    }
+	}
+	
+	
+	
+	
+	export class Conversation {
+	    id: number;
+	    title: string;
+	    provider: string;
+	    createdAt: time.Time;
+	    updatedAt: time.Time;
+	
+	    static createFrom(source: any = {}) {
+	        return new Conversation(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.title = source["title"];
+	        this.provider = source["provider"];
+	        this.createdAt = this.convertValues(source["createdAt"], time.Time);
+	        this.updatedAt = this.convertValues(source["updatedAt"], time.Time);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class EmbeddingConfig {
+	    enabled: boolean;
+	    provider: string;
+	    model: string;
+	    apiURL?: string;
+	    topK: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new EmbeddingConfig(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.enabled = source["enabled"];
+	        this.provider = source["provider"];
+	        this.model = source["model"];
+	        this.apiURL = source["apiURL"];
+	        this.topK = source["topK"];
+	    }
+	}
+	export class EnvProfile {
+	    id: number;
+	    name: string;
+	    variables: Record<string, string>;
+	    workDir: string;
+	    createdAt: time.Time;
+	    updatedAt: time.Time;
+	
+	    static createFrom(source: any = {}) {
+	        return new EnvProfile(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.name = source["name"];
+	        this.variables = source["variables"];
+	        this.workDir = source["workDir"];
+	        this.createdAt = this.convertValues(source["createdAt"], time.Time);
+	        this.updatedAt = this.convertValues(source["updatedAt"], time.Time);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class ModelPrice {
+	    model: string;
+	    input: number;
+	    output: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new ModelPrice(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.model = source["model"];
+	        this.input = source["input"];
+	        this.output = source["output"];
+	    }
+	}
+	export class Note {
+	    id: number;
+	    title: string;
+	    language: string;
+	    snippet: string;
+	    analysis: string;
+	    contentMd: string;
+	    type: number;
+	    filePath: string;
+	    pdfPage: number;
+	    epubChapter: number;
+	    scriptTimeout: number;
+	    trustedHash: string;
+	    sourceMessage: number;
+	    categoryId: number;
+	    createdAt: time.Time;
+	    updatedAt: time.Time;
+	
+	    static createFrom(source: any = {}) {
+	        return new Note(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.title = source["title"];
+	        this.language = source["language"];
+	        this.snippet = source["snippet"];
+	        this.analysis = source["analysis"];
+	        this.contentMd = source["contentMd"];
+	        this.type = source["type"];
+	        this.filePath = source["filePath"];
+	        this.pdfPage = source["pdfPage"];
+	        this.epubChapter = source["epubChapter"];
+	        this.scriptTimeout = source["scriptTimeout"];
+	        this.trustedHash = source["trustedHash"];
+	        this.sourceMessage = source["sourceMessage"];
+	        this.categoryId = source["categoryId"];
+	        this.createdAt = this.convertValues(source["createdAt"], time.Time);
+	        this.updatedAt = this.convertValues(source["updatedAt"], time.Time);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class NoteBacklink {
+	    noteId: number;
+	    title: string;
+	    categoryId: number;
+	    fromAi: boolean;
+	    excerpt: string;
+	    updatedAt: time.Time;
+	
+	    static createFrom(source: any = {}) {
+	        return new NoteBacklink(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.noteId = source["noteId"];
+	        this.title = source["title"];
+	        this.categoryId = source["categoryId"];
+	        this.fromAi = source["fromAi"];
+	        this.excerpt = source["excerpt"];
+	        this.updatedAt = this.convertValues(source["updatedAt"], time.Time);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class PolicyViolation {
+	    rule: string;
+	    message: string;
+	    line: number;
+	    snippet?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new PolicyViolation(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.rule = source["rule"];
+	        this.message = source["message"];
+	        this.line = source["line"];
+	        this.snippet = source["snippet"];
+	    }
+	}
+	export class PromptTemplate {
+	    id: number;
+	    name: string;
+	    description: string;
+	    content: string;
+	    createdAt: time.Time;
+	    updatedAt: time.Time;
+	
+	    static createFrom(source: any = {}) {
+	        return new PromptTemplate(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.name = source["name"];
+	        this.description = source["description"];
+	        this.content = source["content"];
+	        this.createdAt = this.convertValues(source["createdAt"], time.Time);
+	        this.updatedAt = this.convertValues(source["updatedAt"], time.Time);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class QuizQuestion {
+	    id: number;
+	    quizId: number;
+	    seq: number;
+	    type: string;
+	    question: string;
+	    options: string[];
+	    correct: number;
+	    answer: string;
+	    explanation: string;
+	    noteId: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new QuizQuestion(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.quizId = source["quizId"];
+	        this.seq = source["seq"];
+	        this.type = source["type"];
+	        this.question = source["question"];
+	        this.options = source["options"];
+	        this.correct = source["correct"];
+	        this.answer = source["answer"];
+	        this.explanation = source["explanation"];
+	        this.noteId = source["noteId"];
+	    }
+	}
+	export class Quiz {
+	    id: number;
+	    title: string;
+	    style: string;
+	    categoryId: number;
+	    noteId: number;
+	    provider: string;
+	    model: string;
+	    questions: QuizQuestion[];
+	    createdAt: time.Time;
+	
+	    static createFrom(source: any = {}) {
+	        return new Quiz(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.title = source["title"];
+	        this.style = source["style"];
+	        this.categoryId = source["categoryId"];
+	        this.noteId = source["noteId"];
+	        this.provider = source["provider"];
+	        this.model = source["model"];
+	        this.questions = this.convertValues(source["questions"], QuizQuestion);
+	        this.createdAt = this.convertValues(source["createdAt"], time.Time);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class QuizAnswer {
+	    id: number;
+	    attemptId: number;
+	    quizId: number;
+	    questionId: number;
+	    noteId: number;
+	    response: string;
+	    score: number;
+	    isCorrect: boolean;
+	    feedback: string;
+	    createdAt: time.Time;
+	
+	    static createFrom(source: any = {}) {
+	        return new QuizAnswer(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.attemptId = source["attemptId"];
+	        this.quizId = source["quizId"];
+	        this.questionId = source["questionId"];
+	        this.noteId = source["noteId"];
+	        this.response = source["response"];
+	        this.score = source["score"];
+	        this.isCorrect = source["isCorrect"];
+	        this.feedback = source["feedback"];
+	        this.createdAt = this.convertValues(source["createdAt"], time.Time);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class QuizAttempt {
+	    id: number;
+	    quizId: number;
+	    score: number;
+	    correct: number;
+	    total: number;
+	    answers: QuizAnswer[];
+	    createdAt: time.Time;
+	
+	    static createFrom(source: any = {}) {
+	        return new QuizAttempt(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.quizId = source["quizId"];
+	        this.score = source["score"];
+	        this.correct = source["correct"];
+	        this.total = source["total"];
+	        this.answers = this.convertValues(source["answers"], QuizAnswer);
+	        this.createdAt = this.convertValues(source["createdAt"], time.Time);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class QuizNoteStat {
+	    noteId: number;
+	    title: string;
+	    categoryId: number;
+	    answered: number;
+	    wrong: number;
+	    wrongRate: number;
+	    lastWrongAt?: time.Time;
+	
+	    static createFrom(source: any = {}) {
+	        return new QuizNoteStat(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.noteId = source["noteId"];
+	        this.title = source["title"];
+	        this.categoryId = source["categoryId"];
+	        this.answered = source["answered"];
+	        this.wrong = source["wrong"];
+	        this.wrongRate = source["wrongRate"];
+	        this.lastWrongAt = this.convertValues(source["lastWrongAt"], time.Time);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	
+	export class QuizResponse {
+	    questionId: number;
+	    choice: number;
+	    text: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new QuizResponse(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.questionId = source["questionId"];
+	        this.choice = source["choice"];
+	        this.text = source["text"];
+	    }
+	}
+	export class QuizSummary {
+	    id: number;
+	    title: string;
+	    style: string;
+	    categoryId: number;
+	    noteId: number;
+	    questionCount: number;
+	    attemptCount: number;
+	    bestScore: number;
+	    lastScore: number;
+	    lastAttemptAt?: time.Time;
+	    createdAt: time.Time;
+	
+	    static createFrom(source: any = {}) {
+	        return new QuizSummary(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.title = source["title"];
+	        this.style = source["style"];
+	        this.categoryId = source["categoryId"];
+	        this.noteId = source["noteId"];
+	        this.questionCount = source["questionCount"];
+	        this.attemptCount = source["attemptCount"];
+	        this.bestScore = source["bestScore"];
+	        this.lastScore = source["lastScore"];
+	        this.lastAttemptAt = this.convertValues(source["lastAttemptAt"], time.Time);
+	        this.createdAt = this.convertValues(source["createdAt"], time.Time);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class ScriptPolicy {
+	    requireTrust: boolean;
+	    blockDangerous: boolean;
+	    scrubEnv: boolean;
+	    envAllowlist: string[];
+	    cpuSeconds: number;
+	    memoryMB: number;
+	    workDir: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ScriptPolicy(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.requireTrust = source["requireTrust"];
+	        this.blockDangerous = source["blockDangerous"];
+	        this.scrubEnv = source["scrubEnv"];
+	        this.envAllowlist = source["envAllowlist"];
+	        this.cpuSeconds = source["cpuSeconds"];
+	        this.memoryMB = source["memoryMB"];
+	        this.workDir = source["workDir"];
+	    }
+	}
+	export class ScriptPolicyReport {
+	    trusted: boolean;
+	    violations: PolicyViolation[];
+	
+	    static createFrom(source: any = {}) {
+	        return new ScriptPolicyReport(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.trusted = source["trusted"];
+	        this.violations = this.convertValues(source["violations"], PolicyViolation);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class ScriptResult {
+	    stdout: string;
+	    stderr: string;
+	    success: boolean;
+	    error?: string;
+	    exitCode: number;
+	    historyId?: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new ScriptResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.stdout = source["stdout"];
+	        this.stderr = source["stderr"];
+	        this.success = source["success"];
+	        this.error = source["error"];
+	        this.exitCode = source["exitCode"];
+	        this.historyId = source["historyId"];
+	    }
+	}
+	export class ScriptRun {
+	    id: number;
+	    noteId: number;
+	    trigger: string;
+	    startedAt: time.Time;
+	    durationMs: number;
+	    exitCode: number;
+	    success: boolean;
+	    error: string;
+	    stdout: string;
+	    stderr: string;
+	    createdAt: time.Time;
+	
+	    static createFrom(source: any = {}) {
+	        return new ScriptRun(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.noteId = source["noteId"];
+	        this.trigger = source["trigger"];
+	        this.startedAt = this.convertValues(source["startedAt"], time.Time);
+	        this.durationMs = source["durationMs"];
+	        this.exitCode = source["exitCode"];
+	        this.success = source["success"];
+	        this.error = source["error"];
+	        this.stdout = source["stdout"];
+	        this.stderr = source["stderr"];
+	        this.createdAt = this.convertValues(source["createdAt"], time.Time);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class ScriptRunOptions {
+	    variables: Record<string, string>;
+	    profileId: number;
+	    secrets: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new ScriptRunOptions(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.variables = source["variables"];
+	        this.profileId = source["profileId"];
+	        this.secrets = source["secrets"];
+	    }
+	}
+	export class ScriptSchedule {
+	    id: number;
+	    noteId: number;
+	    cron: string;
+	    enabled: boolean;
+	    profileId: number;
+	    catchUp: string;
+	    nextRunAt?: time.Time;
+	    lastRunAt?: time.Time;
+	    lastSuccess: boolean;
+	    lastError: string;
+	    lastRunId: number;
+	    createdAt: time.Time;
+	    updatedAt: time.Time;
+	
+	    static createFrom(source: any = {}) {
+	        return new ScriptSchedule(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.noteId = source["noteId"];
+	        this.cron = source["cron"];
+	        this.enabled = source["enabled"];
+	        this.profileId = source["profileId"];
+	        this.catchUp = source["catchUp"];
+	        this.nextRunAt = this.convertValues(source["nextRunAt"], time.Time);
+	        this.lastRunAt = this.convertValues(source["lastRunAt"], time.Time);
+	        this.lastSuccess = source["lastSuccess"];
+	        this.lastError = source["lastError"];
+	        this.lastRunId = source["lastRunId"];
+	        this.createdAt = this.convertValues(source["createdAt"], time.Time);
+	        this.updatedAt = this.convertValues(source["updatedAt"], time.Time);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class ScriptVariable {
+	    name: string;
+	    default: string;
+	    hasDefault: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new ScriptVariable(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.default = source["default"];
+	        this.hasDefault = source["hasDefault"];
+	    }
+	}
+	export class Secret {
+	    id: number;
+	    name: string;
+	    description: string;
+	    createdAt: time.Time;
+	    updatedAt: time.Time;
+	
+	    static createFrom(source: any = {}) {
+	        return new Secret(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.name = source["name"];
+	        this.description = source["description"];
+	        this.createdAt = this.convertValues(source["createdAt"], time.Time);
+	        this.updatedAt = this.convertValues(source["updatedAt"], time.Time);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class SemanticIndexStatus {
+	    enabled: boolean;
+	    notes: number;
+	    chunks: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new SemanticIndexStatus(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.enabled = source["enabled"];
+	        this.notes = source["notes"];
+	        this.chunks = source["chunks"];
+	    }
+	}
+	export class SendMessageResult {
+	    conversationId: number;
+	    requestId: string;
+	    userMessage?: ChatMessage;
+	    context?: ContextReport;
+	    promptTemplate?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new SendMessageResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.conversationId = source["conversationId"];
+	        this.requestId = source["requestId"];
+	        this.userMessage = this.convertValues(source["userMessage"], ChatMessage);
+	        this.context = this.convertValues(source["context"], ContextReport);
+	        this.promptTemplate = source["promptTemplate"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	
+	export class UsageConfig {
+	    currency: string;
+	    prices: ModelPrice[];
+	    monthlyBudget: number;
+	    budgetAction: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new UsageConfig(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.currency = source["currency"];
+	        this.prices = this.convertValues(source["prices"], ModelPrice);
+	        this.monthlyBudget = source["monthlyBudget"];
+	        this.budgetAction = source["budgetAction"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+
+}
+
+export namespace time {
+	
+	export class Time {
+	
+	
+	    static createFrom(source: any = {}) {
+	        return new Time(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	
 	    }
 	}
 
@@ -60,13 +60,14 @@ func main() {
 		Width:  1600,
 		Height: 900,
 		// 使用嵌入到二进制中的前端静态资源
-		AssetServer: &assetserver.Options{Assets: assets},
-		OnStartup:   app.Startup,
-		OnShutdown:  app.Shutdown,
-		Bind:        []interface{}{app},
-		Menu:        appMenu,
+		// 附件等本地文件通过 Handler 提供
+		AssetServer:     &assetserver.Options{Assets: assets, Handler: backend.NewAssetHandler()},
+		OnStartup:       app.Startup,
+		OnShutdown:      app.Shutdown,
+		Bind:            []interface{}{app},
+		Menu:            appMenu,
 		CSSDragProperty: "widows",
-        CSSDragValue:    "1",
+		CSSDragValue:    "1",
 		Mac: &mac.Options{
 			TitleBar: mac.TitleBarHiddenInset(),
 			About: &mac.AboutInfo{